@@ -14,6 +14,7 @@ func NewClusterCmd(appCtx *AppContext) *cobra.Command {
 		NewClusterCreateCmd(appCtx),
 		NewClusterDeleteCmd(appCtx),
 		NewClusterListCmd(appCtx),
+		NewClusterScaleCmd(appCtx),
 	)
 
 	return cmd
@@ -40,11 +40,16 @@ type CreateConfig struct {
 	persistenceType      string
 	storageClassName     string
 	storageRequestSize   string
+	datastoreSecretName  string
+	volumeNames          []string
+	volumeSelector       string
 	version              string
 	mode                 string
 	kubeconfigServerHost string
 	policy               string
 	mirrorHostNodes      bool
+	namingStrategy       string
+	namespaceMapping     string
 	customCertsPath      string
 }
 
@@ -191,17 +196,26 @@ func newCluster(name, namespace string, config *CreateConfig) *v1alpha1.Cluster
 			Version:     config.version,
 			Mode:        v1alpha1.ClusterMode(config.mode),
 			Persistence: v1alpha1.PersistenceConfig{
-				Type:               v1alpha1.PersistenceMode(config.persistenceType),
-				StorageClassName:   ptr.To(config.storageClassName),
-				StorageRequestSize: config.storageRequestSize,
+				Type:                v1alpha1.PersistenceMode(config.persistenceType),
+				StorageClassName:    ptr.To(config.storageClassName),
+				StorageRequestSize:  config.storageRequestSize,
+				DatastoreSecretName: config.datastoreSecretName,
+				VolumeNames:         config.volumeNames,
 			},
-			MirrorHostNodes: config.mirrorHostNodes,
+			MirrorHostNodes:  config.mirrorHostNodes,
+			NamingStrategy:   v1alpha1.NamingStrategy(config.namingStrategy),
+			NamespaceMapping: v1alpha1.NamespaceMapping(config.namespaceMapping),
 		},
 	}
 	if config.storageClassName == "" {
 		cluster.Spec.Persistence.StorageClassName = nil
 	}
 
+	// the selector is validated with the flags
+	if volumeSelector, err := metav1.ParseToLabelSelector(config.volumeSelector); config.volumeSelector != "" && err == nil {
+		cluster.Spec.Persistence.VolumeSelector = volumeSelector
+	}
+
 	if config.token != "" {
 		cluster.Spec.TokenSecretRef = &v1.SecretReference{
 			Name:      k3kcluster.TokenSecretName(name),
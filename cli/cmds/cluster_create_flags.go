@@ -2,10 +2,13 @@ package cmds
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/api/resource"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
 )
 
@@ -16,9 +19,14 @@ func createFlags(cmd *cobra.Command, cfg *CreateConfig) {
 	cmd.Flags().StringVar(&cfg.clusterCIDR, "cluster-cidr", "", "cluster CIDR")
 	cmd.Flags().StringVar(&cfg.serviceCIDR, "service-cidr", "", "service CIDR")
 	cmd.Flags().BoolVar(&cfg.mirrorHostNodes, "mirror-host-nodes", false, "Mirror Host Cluster Nodes")
-	cmd.Flags().StringVar(&cfg.persistenceType, "persistence-type", string(v1alpha1.DynamicPersistenceMode), "persistence mode for the nodes (dynamic, ephemeral, static)")
+	cmd.Flags().StringVar(&cfg.namingStrategy, "naming-strategy", string(v1alpha1.HashNamingStrategy), "naming strategy of the objects synced in the host cluster (Hash, ShortHash, Readable)")
+	cmd.Flags().StringVar(&cfg.namespaceMapping, "namespace-mapping", string(v1alpha1.SingleNamespaceMapping), "mapping of the virtual namespaces to the host namespaces (Single, PerNamespace)")
+	cmd.Flags().StringVar(&cfg.persistenceType, "persistence-type", string(v1alpha1.DynamicPersistenceMode), "persistence mode for the nodes (dynamic, ephemeral, external, static)")
 	cmd.Flags().StringVar(&cfg.storageClassName, "storage-class-name", "", "storage class name for dynamic persistence type")
 	cmd.Flags().StringVar(&cfg.storageRequestSize, "storage-request-size", "", "storage size for dynamic persistence type")
+	cmd.Flags().StringVar(&cfg.datastoreSecretName, "datastore-secret-name", "", "name of the Secret with the endpoint of the datastore for external persistence type (SQLite if not set)")
+	cmd.Flags().StringSliceVar(&cfg.volumeNames, "volume-names", []string{}, "names of the pre-provisioned PersistentVolumes of the servers for static persistence type, in the order of the servers")
+	cmd.Flags().StringVar(&cfg.volumeSelector, "volume-selector", "", "label selector of the pre-provisioned PersistentVolumes of the servers for static persistence type")
 	cmd.Flags().StringSliceVar(&cfg.serverArgs, "server-args", []string{}, "servers extra arguments")
 	cmd.Flags().StringSliceVar(&cfg.agentArgs, "agent-args", []string{}, "agents extra arguments")
 	cmd.Flags().StringSliceVar(&cfg.serverEnvs, "server-envs", []string{}, "servers extra Envs")
@@ -37,21 +45,41 @@ func validateCreateConfig(cfg *CreateConfig) error {
 
 	if cfg.persistenceType != "" {
 		switch v1alpha1.PersistenceMode(cfg.persistenceType) {
-		case v1alpha1.EphemeralPersistenceMode, v1alpha1.DynamicPersistenceMode:
-			return nil
+		case v1alpha1.EphemeralPersistenceMode, v1alpha1.DynamicPersistenceMode, v1alpha1.ExternalPersistenceMode:
+		case v1alpha1.StaticPersistenceMode:
+			if err := validateStaticPersistence(cfg); err != nil {
+				return err
+			}
+		default:
+			return errors.New(`persistence-type should be one of "dynamic", "ephemeral", "external" or "static"`)
+		}
+	}
+
+	if cfg.storageRequestSize != "" {
+		if _, err := resource.ParseQuantity(cfg.storageRequestSize); err != nil {
+			return errors.New(`invalid storage size, should be a valid resource quantity e.g "10Gi"`)
+		}
+	}
+
+	if cfg.namingStrategy != "" {
+		switch v1alpha1.NamingStrategy(cfg.namingStrategy) {
+		case v1alpha1.HashNamingStrategy, v1alpha1.ShortHashNamingStrategy, v1alpha1.ReadableNamingStrategy:
 		default:
-			return errors.New(`persistence-type should be one of "dynamic", "ephemeral" or "static"`)
+			return errors.New(`naming-strategy should be one of "Hash", "ShortHash" or "Readable"`)
 		}
 	}
 
-	if _, err := resource.ParseQuantity(cfg.storageRequestSize); err != nil {
-		return errors.New(`invalid storage size, should be a valid resource quantity e.g "10Gi"`)
+	if cfg.namespaceMapping != "" {
+		switch v1alpha1.NamespaceMapping(cfg.namespaceMapping) {
+		case v1alpha1.SingleNamespaceMapping, v1alpha1.PerNamespaceMapping:
+		default:
+			return errors.New(`namespace-mapping should be one of "Single" or "PerNamespace"`)
+		}
 	}
 
 	if cfg.mode != "" {
 		switch cfg.mode {
 		case string(v1alpha1.VirtualClusterMode), string(v1alpha1.SharedClusterMode):
-			return nil
 		default:
 			return errors.New(`mode should be one of "shared" or "virtual"`)
 		}
@@ -59,3 +87,19 @@ func validateCreateConfig(cfg *CreateConfig) error {
 
 	return nil
 }
+
+func validateStaticPersistence(cfg *CreateConfig) error {
+	if len(cfg.volumeNames) == 0 && cfg.volumeSelector == "" {
+		return errors.New(`static persistence-type requires "volume-names" or "volume-selector"`)
+	}
+
+	if len(cfg.volumeNames) > 0 && len(cfg.volumeNames) < cfg.servers {
+		return fmt.Errorf("%d volume-names for %d servers, a volume is needed for each server", len(cfg.volumeNames), cfg.servers)
+	}
+
+	if _, err := metav1.ParseToLabelSelector(cfg.volumeSelector); cfg.volumeSelector != "" && err != nil {
+		return fmt.Errorf("invalid volume-selector: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+type ScaleConfig struct {
+	servers int32
+	agents  int32
+}
+
+func NewClusterScaleCmd(appCtx *AppContext) *cobra.Command {
+	scaleConfig := &ScaleConfig{}
+
+	cmd := &cobra.Command{
+		Use:     "scale",
+		Short:   "Scale the servers or the agents of an existing cluster",
+		Example: "k3kcli cluster scale [command options] NAME",
+		RunE:    scale(appCtx, scaleConfig),
+		Args:    cobra.ExactArgs(1),
+	}
+
+	CobraFlagNamespace(appCtx, cmd.Flags())
+	cmd.Flags().Int32Var(&scaleConfig.servers, "servers", 1, "number of servers")
+	cmd.Flags().Int32Var(&scaleConfig.agents, "agents", 0, "number of agents, only allowed in virtual mode")
+
+	return cmd
+}
+
+func scale(appCtx *AppContext, config *ScaleConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		client := appCtx.Client
+		name := args[0]
+
+		scaleServers, scaleAgents := cmd.Flags().Changed("servers"), cmd.Flags().Changed("agents")
+		if !scaleServers && !scaleAgents {
+			return errors.New("invalid flags, --servers or --agents is required")
+		}
+
+		var cluster v1alpha1.Cluster
+
+		key := types.NamespacedName{Name: name, Namespace: appCtx.Namespace(name)}
+		if err := client.Get(ctx, key, &cluster); err != nil {
+			return err
+		}
+
+		if scaleAgents && cluster.Spec.Mode == v1alpha1.SharedClusterMode {
+			return errors.New("invalid flag, --agents flag is only allowed in virtual mode")
+		}
+
+		patch := ctrlclient.MergeFrom(cluster.DeepCopy())
+
+		if scaleServers {
+			cluster.Spec.Servers = ptr.To(config.servers)
+		}
+
+		if scaleAgents {
+			cluster.Spec.Agents = ptr.To(config.agents)
+		}
+
+		logrus.Infof("Scaling [%s] cluster in namespace [%s] to %d servers and %d agents", cluster.Name, cluster.Namespace, ptr.Deref(cluster.Spec.Servers, 1), ptr.Deref(cluster.Spec.Agents, 0))
+
+		return client.Patch(ctx, &cluster, patch)
+	}
+}
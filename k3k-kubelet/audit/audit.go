@@ -0,0 +1,307 @@
+// Package audit records the interactive accesses (exec, attach, port-forward and logs)
+// to the pods of a virtual cluster served by the k3k-kubelet.
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	k3klog "github.com/rancher/k3k/pkg/log"
+)
+
+type Action string
+
+const (
+	ActionExec        = Action("exec")
+	ActionAttach      = Action("attach")
+	ActionPortForward = Action("portforward")
+	ActionLogs        = Action("logs")
+)
+
+// Record is the audit record of an interactive access to a pod of the virtual cluster.
+// The requests to the kubelet API are sent by the virtual API server, that doesn't forward the identity
+// of the end user: the Caller of the records is the identity of the API server, and the end user is
+// recorded by the audit log of the virtual API server.
+type Record struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Caller is the user authenticated by the client certificate of the request to the kubelet API
+	Caller           string   `json:"caller,omitempty"`
+	Cluster          string   `json:"cluster"`
+	ClusterNamespace string   `json:"clusterNamespace"`
+	Node             string   `json:"node"`
+	Action           Action   `json:"action"`
+	Namespace        string   `json:"namespace"`
+	Pod              string   `json:"pod"`
+	Container        string   `json:"container,omitempty"`
+	Command          []string `json:"command,omitempty"`
+	TTY              bool     `json:"tty,omitempty"`
+	Port             int32    `json:"port,omitempty"`
+	DurationSeconds  float64  `json:"durationSeconds"`
+	// BytesIn is the number of bytes sent to the container (stdin)
+	BytesIn int64 `json:"bytesIn"`
+	// BytesOut is the number of bytes received from the container (stdout, stderr and logs)
+	BytesOut  int64  `json:"bytesOut"`
+	Recording string `json:"recording,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Config is the configuration of the audit of the interactive accesses
+type Config struct {
+	// LogPath is the path of the file where the records are appended as JSON lines, "-" for stdout
+	LogPath string
+	// WebhookURL is the URL of an HTTP endpoint receiving the records as JSON
+	WebhookURL string
+	// RecordingDir is the directory where the exec and attach sessions are recorded
+	RecordingDir string
+}
+
+// Auditor emits the audit records of the sessions to the sinks.
+// A nil Auditor is valid, and doesn't audit anything.
+type Auditor struct {
+	sinks            []Sink
+	recordingDir     string
+	cluster          string
+	clusterNamespace string
+	node             string
+	logger           *k3klog.Logger
+}
+
+// New returns an Auditor for the node of the cluster with the sinks of the configuration.
+// It returns nil if no sinks are configured.
+func New(config Config, cluster, clusterNamespace, node string, logger *k3klog.Logger) (*Auditor, error) {
+	var sinks []Sink
+
+	if config.LogPath != "" {
+		sink, err := NewFileSink(config.LogPath)
+		if err != nil {
+			return nil, err
+		}
+
+		sinks = append(sinks, sink)
+	}
+
+	if config.WebhookURL != "" {
+		sinks = append(sinks, NewWebhookSink(config.WebhookURL))
+	}
+
+	if len(sinks) == 0 {
+		if config.RecordingDir != "" {
+			return nil, errors.New("session recording requires an audit log path or webhook")
+		}
+
+		return nil, nil
+	}
+
+	return &Auditor{
+		sinks:            sinks,
+		recordingDir:     config.RecordingDir,
+		cluster:          cluster,
+		clusterNamespace: clusterNamespace,
+		node:             node,
+		logger:           logger,
+	}, nil
+}
+
+type callerKey struct{}
+
+// WithCaller returns a context with the user authenticated by the request to the kubelet API
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns the user authenticated by the request to the kubelet API of the context
+func CallerFrom(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// Start starts the audit of a session of a container of the pod, requested by the caller of the context.
+// The session must be ended with End.
+func (a *Auditor) Start(ctx context.Context, action Action, namespace, pod, container string) *Session {
+	if a == nil {
+		return nil
+	}
+
+	session := &Session{
+		auditor: a,
+		start:   time.Now(),
+		record: Record{
+			Caller:           CallerFrom(ctx),
+			Cluster:          a.cluster,
+			ClusterNamespace: a.clusterNamespace,
+			Node:             a.node,
+			Action:           action,
+			Namespace:        namespace,
+			Pod:              pod,
+			Container:        container,
+		},
+	}
+
+	// the interactive sessions are recorded, a failure is audited without blocking the session
+	if a.recordingDir != "" && (action == ActionExec || action == ActionAttach) {
+		name := fmt.Sprintf("%s_%s_%s_%s_%d.cast", a.cluster, namespace, pod, container, session.start.UnixNano())
+
+		recorder, err := newRecorder(filepath.Join(a.recordingDir, name), session.start)
+		if err != nil {
+			a.logger.Errorw("unable to start session recording", "error", err)
+		} else {
+			session.recorder = recorder
+			session.record.Recording = recorder.path
+		}
+	}
+
+	return session
+}
+
+// Session is an audited session. A nil Session is valid, and doesn't audit anything.
+type Session struct {
+	auditor  *Auditor
+	start    time.Time
+	record   Record
+	recorder *recorder
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+}
+
+// WithCommand sets the command executed in the container
+func (s *Session) WithCommand(command []string, tty bool) *Session {
+	if s != nil {
+		s.record.Command = command
+		s.record.TTY = tty
+	}
+
+	return s
+}
+
+// WithPort sets the forwarded port of the pod
+func (s *Session) WithPort(port int32) *Session {
+	if s != nil {
+		s.record.Port = port
+	}
+
+	return s
+}
+
+// Stdin returns a reader counting, and recording, the bytes sent to the container
+func (s *Session) Stdin(r io.Reader) io.Reader {
+	if s == nil || r == nil {
+		return r
+	}
+
+	return &countingReader{reader: r, count: &s.bytesIn, recorder: s.recorder, stream: inputStream}
+}
+
+// Stdout returns a writer counting, and recording, the bytes received from the container
+func (s *Session) Stdout(w io.Writer) io.Writer {
+	if s == nil || w == nil {
+		return w
+	}
+
+	return &countingWriter{writer: w, count: &s.bytesOut, recorder: s.recorder}
+}
+
+// Stderr returns a writer counting, and recording, the bytes received from the container
+func (s *Session) Stderr(w io.Writer) io.Writer {
+	return s.Stdout(w)
+}
+
+// Logs returns a reader counting the bytes of the logs, that ends the session when closed
+func (s *Session) Logs(rc io.ReadCloser) io.ReadCloser {
+	if s == nil || rc == nil {
+		return rc
+	}
+
+	return &logsReader{
+		Reader: &countingReader{reader: rc, count: &s.bytesOut},
+		closer: rc,
+		end:    s.End,
+	}
+}
+
+// End completes the session, and writes its audit record to the sinks
+func (s *Session) End(err error) {
+	if s == nil {
+		return
+	}
+
+	record := s.record
+	record.Timestamp = s.start.UTC()
+	record.DurationSeconds = time.Since(s.start).Seconds()
+	record.BytesIn = s.bytesIn.Load()
+	record.BytesOut = s.bytesOut.Load()
+
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if s.recorder != nil {
+		if err := s.recorder.Close(); err != nil {
+			s.auditor.logger.Errorw("unable to close session recording", "recording", s.recorder.path, "error", err)
+		}
+	}
+
+	// the session could be ended by a cancelled context, so the record is written with a new one
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	for _, sink := range s.auditor.sinks {
+		if err := sink.Write(ctx, &record); err != nil {
+			s.auditor.logger.Errorw("unable to write audit record", "action", record.Action, "pod", record.Pod, "error", err)
+		}
+	}
+}
+
+type countingReader struct {
+	reader   io.Reader
+	count    *atomic.Int64
+	recorder *recorder
+	stream   string
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.count.Add(int64(n))
+		r.recorder.record(r.stream, p[:n])
+	}
+
+	return n, err
+}
+
+type countingWriter struct {
+	writer   io.Writer
+	count    *atomic.Int64
+	recorder *recorder
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	if n > 0 {
+		w.count.Add(int64(n))
+		w.recorder.record(outputStream, p[:n])
+	}
+
+	return n, err
+}
+
+type logsReader struct {
+	io.Reader
+	closer io.Closer
+	end    func(error)
+	closed atomic.Bool
+}
+
+func (r *logsReader) Close() error {
+	err := r.closer.Close()
+
+	if r.closed.CompareAndSwap(false, true) {
+		r.end(nil)
+	}
+
+	return err
+}
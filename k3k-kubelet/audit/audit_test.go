@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	k3klog "github.com/rancher/k3k/pkg/log"
+)
+
+func newTestAuditor(t *testing.T, recordingDir string) (*Auditor, *bytes.Buffer) {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	return &Auditor{
+		sinks:            []Sink{NewWriterSink(&buf)},
+		recordingDir:     recordingDir,
+		cluster:          "mycluster",
+		clusterNamespace: "k3k-mycluster",
+		node:             "node-1",
+		logger:           k3klog.New(false),
+	}, &buf
+}
+
+func Test_New(t *testing.T) {
+	auditor, err := New(Config{}, "mycluster", "k3k-mycluster", "node-1", k3klog.New(false))
+	assert.NoError(t, err)
+	assert.Nil(t, auditor)
+
+	_, err = New(Config{RecordingDir: t.TempDir()}, "mycluster", "k3k-mycluster", "node-1", k3klog.New(false))
+	assert.Error(t, err)
+
+	auditor, err = New(Config{LogPath: "-", WebhookURL: "http://localhost:8080"}, "mycluster", "k3k-mycluster", "node-1", k3klog.New(false))
+	assert.NoError(t, err)
+	assert.Len(t, auditor.sinks, 2)
+}
+
+func Test_NilAuditor(t *testing.T) {
+	var auditor *Auditor
+
+	session := auditor.Start(context.Background(), ActionExec, "default", "app", "main").WithCommand([]string{"sh"}, true)
+	assert.Nil(t, session)
+
+	stdin := strings.NewReader("ls\n")
+	assert.Equal(t, io.Reader(stdin), session.Stdin(stdin))
+
+	var stdout bytes.Buffer
+	assert.Equal(t, io.Writer(&stdout), session.Stdout(&stdout))
+	assert.Nil(t, session.Stderr(nil))
+
+	session.End(nil)
+}
+
+func Test_ExecSession(t *testing.T) {
+	recordingDir := t.TempDir()
+	auditor, buf := newTestAuditor(t, recordingDir)
+
+	session := auditor.Start(WithCaller(context.Background(), "system:kube-apiserver"), ActionExec, "default", "app", "main").WithCommand([]string{"sh", "-c", "cat"}, false)
+
+	var stdout, stderr bytes.Buffer
+
+	_, err := io.Copy(session.Stdout(&stdout), session.Stdin(strings.NewReader("hello\n")))
+	assert.NoError(t, err)
+
+	_, err = session.Stderr(&stderr).Write([]byte("warning\n"))
+	assert.NoError(t, err)
+
+	assert.Nil(t, session.Stdin(nil), "a nil stream must stay nil")
+
+	session.End(errors.New("command terminated with exit code 1"))
+
+	var record Record
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+
+	assert.Equal(t, "system:kube-apiserver", record.Caller)
+	assert.Equal(t, "mycluster", record.Cluster)
+	assert.Equal(t, "k3k-mycluster", record.ClusterNamespace)
+	assert.Equal(t, "node-1", record.Node)
+	assert.Equal(t, ActionExec, record.Action)
+	assert.Equal(t, "default", record.Namespace)
+	assert.Equal(t, "app", record.Pod)
+	assert.Equal(t, "main", record.Container)
+	assert.Equal(t, []string{"sh", "-c", "cat"}, record.Command)
+	assert.Equal(t, int64(6), record.BytesIn)
+	assert.Equal(t, int64(14), record.BytesOut)
+	assert.Equal(t, "command terminated with exit code 1", record.Error)
+	assert.False(t, record.Timestamp.IsZero())
+
+	if !assert.NotEmpty(t, record.Recording) {
+		t.FailNow()
+	}
+
+	recording, err := os.ReadFile(record.Recording)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(recording)), "\n")
+	if !assert.Len(t, lines, 4) {
+		t.FailNow()
+	}
+
+	assert.Contains(t, lines[0], `"version":2`)
+	assert.Contains(t, lines[1], `"i","hello\n"`)
+	assert.Contains(t, lines[2], `"o","hello\n"`)
+	assert.Contains(t, lines[3], `"o","warning\n"`)
+}
+
+func Test_LogsSession(t *testing.T) {
+	auditor, buf := newTestAuditor(t, t.TempDir())
+
+	session := auditor.Start(context.Background(), ActionLogs, "default", "app", "main")
+	logs := session.Logs(io.NopCloser(strings.NewReader("line 1\nline 2\n")))
+
+	_, err := io.ReadAll(logs)
+	assert.NoError(t, err)
+	assert.Empty(t, buf.String(), "the record must be written when the logs are closed")
+
+	assert.NoError(t, logs.Close())
+	assert.NoError(t, logs.Close())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !assert.Len(t, lines, 1) {
+		t.FailNow()
+	}
+
+	var record Record
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+
+	assert.Equal(t, ActionLogs, record.Action)
+	assert.Equal(t, int64(14), record.BytesOut)
+	assert.Empty(t, record.Recording, "the logs are not recorded")
+}
+
+func Test_WebhookSink(t *testing.T) {
+	var received Record
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+
+		if received.Pod == "rejected" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL)
+
+	err := sink.Write(t.Context(), &Record{Action: ActionPortForward, Pod: "app", Port: 8080})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(8080), received.Port)
+
+	err = sink.Write(t.Context(), &Record{Action: ActionPortForward, Pod: "rejected"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	inputStream  = "i"
+	outputStream = "o"
+
+	// the size of the terminal is unknown, the default size is used
+	recordingWidth  = 80
+	recordingHeight = 24
+)
+
+// recorder records the streams of a session in the asciicast v2 format, that can be replayed with asciinema.
+// A nil recorder is valid, and doesn't record anything.
+type recorder struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	start time.Time
+}
+
+type recordingHeader struct {
+	Version   int   `json:"version"`
+	Width     int   `json:"width"`
+	Height    int   `json:"height"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+func newRecorder(path string, start time.Time) (*recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := json.Marshal(recordingHeader{
+		Version:   2,
+		Width:     recordingWidth,
+		Height:    recordingHeight,
+		Timestamp: start.Unix(),
+	})
+	if err != nil {
+		return nil, errors.Join(err, file.Close())
+	}
+
+	if _, err := file.Write(append(header, '\n')); err != nil {
+		return nil, errors.Join(err, file.Close())
+	}
+
+	return &recorder{path: path, file: file, start: start}, nil
+}
+
+// record writes an event with the data of the stream, errors are ignored to not interrupt the session
+func (r *recorder) record(stream string, data []byte) {
+	if r == nil {
+		return
+	}
+
+	event, err := json.Marshal([]any{time.Since(r.start).Seconds(), stream, string(data)})
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, _ = r.file.Write(append(event, '\n'))
+}
+
+func (r *recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.file.Close()
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"errors"
+	"reflect"
 )
 
-// config has all virtual-kubelet startup options
+// config has all virtual-kubelet startup options. The config file is watched, and the changes of Debug and Version
+// are applied without restarting the k3k-kubelet: the other fields are applied only on startup.
 type config struct {
 	ClusterName      string `mapstructure:"clusterName"`
 	ClusterNamespace string `mapstructure:"clusterNamespace"`
@@ -15,9 +17,15 @@ type config struct {
 	VirtKubeconfig   string `mapstructure:"virtKubeconfig"`
 	KubeletPort      int    `mapstructure:"kubeletPort"`
 	WebhookPort      int    `mapstructure:"webhookPort"`
+	MetricsPort      int    `mapstructure:"metricsPort"`
 	ServerIP         string `mapstructure:"serverIP"`
 	Version          string `mapstructure:"version"`
 	MirrorHostNodes  bool   `mapstructure:"mirrorHostNodes"`
+	Debug            bool   `mapstructure:"debug"`
+
+	AuditLogPath      string `mapstructure:"auditLogPath"`
+	AuditWebhookURL   string `mapstructure:"auditWebhookURL"`
+	AuditRecordingDir string `mapstructure:"auditRecordingDir"`
 }
 
 func (c *config) validate() error {
@@ -35,3 +43,24 @@ func (c *config) validate() error {
 
 	return nil
 }
+
+// changedStartupFields returns the keys of the fields changed in the new config that are applied only on startup
+func (c *config) changedStartupFields(newConfig *config) []string {
+	current, updated := *c, *newConfig
+
+	// the fields applied without a restart
+	current.Debug, updated.Debug = false, false
+	current.Version, updated.Version = "", ""
+
+	currentValue, updatedValue := reflect.ValueOf(current), reflect.ValueOf(updated)
+
+	var fields []string
+
+	for i := range currentValue.NumField() {
+		if !reflect.DeepEqual(currentValue.Field(i).Interface(), updatedValue.Field(i).Interface()) {
+			fields = append(fields, currentValue.Type().Field(i).Tag.Get("mapstructure"))
+		}
+	}
+
+	return fields
+}
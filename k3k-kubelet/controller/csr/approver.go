@@ -0,0 +1,166 @@
+// Package csr approves the CertificateSigningRequests of the serving certificate of the k3k-kubelet
+// in the virtual cluster.
+package csr
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	approverControllerName = "serving-csr-approver"
+
+	// NodeUserPrefix is the prefix of the common name of the certificates of the nodes
+	NodeUserPrefix = "system:node:"
+	// NodesGroup is the organization of the certificates of the nodes
+	NodesGroup = "system:nodes"
+
+	approvedReason = "K3kKubeletServingCertificate"
+)
+
+type approver struct {
+	client   ctrlruntimeclient.Client
+	nodeName string
+	username string
+}
+
+// AddServingCertificateApprover adds a controller to the virtual manager approving the CertificateSigningRequests
+// of the serving certificate of the node, requested by the user of the k3k-kubelet. The controller doesn't need the
+// leader election, since every replica approves the requests of its own node.
+func AddServingCertificateApprover(ctx context.Context, virtMgr manager.Manager, nodeName, username string) error {
+	reconciler := approver{
+		client:   virtMgr.GetClient(),
+		nodeName: nodeName,
+		username: username,
+	}
+
+	return ctrl.NewControllerManagedBy(virtMgr).
+		Named(approverControllerName).
+		For(&certificatesv1.CertificateSigningRequest{}).
+		WithEventFilter(predicate.NewPredicateFuncs(reconciler.filterResources)).
+		WithOptions(controller.Options{NeedLeaderElection: ptr.To(false)}).
+		Complete(&reconciler)
+}
+
+func (a *approver) filterResources(object ctrlruntimeclient.Object) bool {
+	csr, ok := object.(*certificatesv1.CertificateSigningRequest)
+	if !ok {
+		return false
+	}
+
+	return csr.Spec.SignerName == certificatesv1.KubeletServingSignerName && csr.Spec.Username == a.username
+}
+
+func (a *approver) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	var csr certificatesv1.CertificateSigningRequest
+	if err := a.client.Get(ctx, req.NamespacedName, &csr); err != nil {
+		return reconcile.Result{}, ctrlruntimeclient.IgnoreNotFound(err)
+	}
+
+	if isCompleted(&csr) {
+		return reconcile.Result{}, nil
+	}
+
+	request, err := parseRequest(csr.Spec.Request)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// the requests of the other nodes are approved by their own k3k-kubelet
+	if request.Subject.CommonName != NodeUserPrefix+a.nodeName {
+		return reconcile.Result{}, nil
+	}
+
+	if err := validateServingRequest(request, csr.Spec.Usages); err != nil {
+		log.Error(err, "serving certificate request not approved", "csr", csr.Name)
+		return reconcile.Result{}, nil
+	}
+
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:           certificatesv1.CertificateApproved,
+		Status:         v1.ConditionTrue,
+		Reason:         approvedReason,
+		Message:        "serving certificate of the k3k-kubelet approved",
+		LastUpdateTime: metav1.Now(),
+	})
+
+	log.Info("approving serving certificate request", "csr", csr.Name)
+
+	if err := a.client.SubResource("approval").Update(ctx, &csr); err != nil && !apierrors.IsConflict(err) {
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// isCompleted returns true if the request was already approved or denied
+func isCompleted(csr *certificatesv1.CertificateSigningRequest) bool {
+	for _, condition := range csr.Status.Conditions {
+		if condition.Type == certificatesv1.CertificateApproved || condition.Type == certificatesv1.CertificateDenied {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseRequest(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("PEM block type must be CERTIFICATE REQUEST")
+	}
+
+	return x509.ParseCertificateRequest(block.Bytes)
+}
+
+// validateServingRequest checks that the request is valid for the kubelet-serving signer
+func validateServingRequest(request *x509.CertificateRequest, usages []certificatesv1.KeyUsage) error {
+	allowedUsages := []certificatesv1.KeyUsage{
+		certificatesv1.UsageDigitalSignature,
+		certificatesv1.UsageKeyEncipherment,
+		certificatesv1.UsageServerAuth,
+	}
+
+	if !slices.Equal(request.Subject.Organization, []string{NodesGroup}) {
+		return fmt.Errorf("organization must be %q", NodesGroup)
+	}
+
+	if len(request.DNSNames) == 0 && len(request.IPAddresses) == 0 {
+		return errors.New("DNS or IP subjectAltName is required")
+	}
+
+	if len(request.EmailAddresses) > 0 || len(request.URIs) > 0 {
+		return errors.New("email and URI subjectAltNames are not allowed")
+	}
+
+	for _, usage := range usages {
+		if !slices.Contains(allowedUsages, usage) {
+			return fmt.Errorf("usage %q is not allowed", usage)
+		}
+	}
+
+	if !slices.Contains(usages, certificatesv1.UsageServerAuth) {
+		return fmt.Errorf("usage %q is required", certificatesv1.UsageServerAuth)
+	}
+
+	return nil
+}
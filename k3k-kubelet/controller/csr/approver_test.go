@@ -0,0 +1,109 @@
+package csr
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+)
+
+func Test_validateServingRequest(t *testing.T) {
+	subject := pkix.Name{
+		CommonName:   NodeUserPrefix + "node-1",
+		Organization: []string{NodesGroup},
+	}
+
+	servingUsages := []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageServerAuth}
+
+	tests := []struct {
+		name    string
+		request *x509.CertificateRequest
+		usages  []certificatesv1.KeyUsage
+		wantErr bool
+	}{
+		{
+			name: "valid request",
+			request: &x509.CertificateRequest{
+				Subject:     subject,
+				DNSNames:    []string{"node-1"},
+				IPAddresses: []net.IP{net.ParseIP("10.43.0.12")},
+			},
+			usages: servingUsages,
+		},
+		{
+			name: "wrong organization",
+			request: &x509.CertificateRequest{
+				Subject: pkix.Name{
+					CommonName:   NodeUserPrefix + "node-1",
+					Organization: []string{"system:masters"},
+				},
+				DNSNames: []string{"node-1"},
+			},
+			usages:  servingUsages,
+			wantErr: true,
+		},
+		{
+			name: "missing subjectAltNames",
+			request: &x509.CertificateRequest{
+				Subject: subject,
+			},
+			usages:  servingUsages,
+			wantErr: true,
+		},
+		{
+			name: "URI subjectAltName",
+			request: &x509.CertificateRequest{
+				Subject:  subject,
+				DNSNames: []string{"node-1"},
+				URIs:     []*url.URL{{Scheme: "spiffe", Host: "example.com"}},
+			},
+			usages:  servingUsages,
+			wantErr: true,
+		},
+		{
+			name: "client auth usage",
+			request: &x509.CertificateRequest{
+				Subject:  subject,
+				DNSNames: []string{"node-1"},
+			},
+			usages:  []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageServerAuth, certificatesv1.UsageClientAuth},
+			wantErr: true,
+		},
+		{
+			name: "missing server auth usage",
+			request: &x509.CertificateRequest{
+				Subject:  subject,
+				DNSNames: []string{"node-1"},
+			},
+			usages:  []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateServingRequest(tt.request, tt.usages)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_isCompleted(t *testing.T) {
+	csr := &certificatesv1.CertificateSigningRequest{}
+	assert.False(t, isCompleted(csr))
+
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type: certificatesv1.CertificateDenied,
+	})
+	assert.True(t, isCompleted(csr))
+}
@@ -15,7 +15,6 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 
-	"github.com/rancher/k3k/k3k-kubelet/translate"
 	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
 )
 
@@ -35,14 +34,16 @@ func (c *ConfigMapSyncer) Name() string {
 
 // AddConfigMapSyncer adds configmap syncer controller to the manager of the virtual cluster
 func AddConfigMapSyncer(ctx context.Context, virtMgr, hostMgr manager.Manager, clusterName, clusterNamespace string) error {
+	translator, err := newTranslator(ctx, hostMgr, clusterName, clusterNamespace)
+	if err != nil {
+		return err
+	}
+
 	reconciler := ConfigMapSyncer{
 		SyncerContext: &SyncerContext{
-			VirtualClient: virtMgr.GetClient(),
-			HostClient:    hostMgr.GetClient(),
-			Translator: translate.ToHostTranslator{
-				ClusterName:      clusterName,
-				ClusterNamespace: clusterNamespace,
-			},
+			VirtualClient:    virtMgr.GetClient(),
+			HostClient:       hostMgr.GetClient(),
+			Translator:       translator,
 			ClusterName:      clusterName,
 			ClusterNamespace: clusterNamespace,
 		},
@@ -53,6 +54,7 @@ func AddConfigMapSyncer(ctx context.Context, virtMgr, hostMgr manager.Manager, c
 	return ctrl.NewControllerManagedBy(virtMgr).
 		Named(name).
 		For(&corev1.ConfigMap{}).WithEventFilter(predicate.NewPredicateFuncs(reconciler.filterResources)).
+		WatchesRawSource(reconciler.syncConfigSource(hostMgr, &corev1.ConfigMapList{}, reconciler.filterResources)).
 		Complete(&reconciler)
 }
 
@@ -134,6 +136,11 @@ func (c *ConfigMapSyncer) Reconcile(ctx context.Context, req reconcile.Request)
 		return reconcile.Result{}, err
 	}
 
+	// the host object could belong to another virtual object with the same translated name
+	if err := c.Translator.CheckCollision(&hostConfigMap, req.Namespace, req.Name); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	// TODO: Add option to keep labels/annotation set by the host cluster
 	log.Info("updating ConfigMap on the host cluster")
 
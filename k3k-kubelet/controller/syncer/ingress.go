@@ -15,7 +15,6 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
-	"github.com/rancher/k3k/k3k-kubelet/translate"
 	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
 )
 
@@ -30,16 +29,18 @@ type IngressReconciler struct {
 
 // AddIngressSyncer adds ingress syncer controller to the manager of the virtual cluster
 func AddIngressSyncer(ctx context.Context, virtMgr, hostMgr manager.Manager, clusterName, clusterNamespace string) error {
+	translator, err := newTranslator(ctx, hostMgr, clusterName, clusterNamespace)
+	if err != nil {
+		return err
+	}
+
 	reconciler := IngressReconciler{
 		SyncerContext: &SyncerContext{
 			ClusterName:      clusterName,
 			ClusterNamespace: clusterNamespace,
 			VirtualClient:    virtMgr.GetClient(),
 			HostClient:       hostMgr.GetClient(),
-			Translator: translate.ToHostTranslator{
-				ClusterName:      clusterName,
-				ClusterNamespace: clusterNamespace,
-			},
+			Translator:       translator,
 		},
 	}
 
@@ -49,6 +50,7 @@ func AddIngressSyncer(ctx context.Context, virtMgr, hostMgr manager.Manager, clu
 		Named(name).
 		For(&networkingv1.Ingress{}).
 		WithEventFilter(predicate.NewPredicateFuncs(reconciler.filterResources)).
+		WatchesRawSource(reconciler.syncConfigSource(hostMgr, &networkingv1.IngressList{}, reconciler.filterResources)).
 		Complete(&reconciler)
 }
 
@@ -97,7 +99,7 @@ func (r *IngressReconciler) Reconcile(ctx context.Context, req reconcile.Request
 	}
 
 	syncedIngress := r.ingress(&virtIngress)
-	if err := controllerutil.SetControllerReference(&cluster, syncedIngress, r.HostClient.Scheme()); err != nil {
+	if err := r.Translator.SetControllerReference(&cluster, syncedIngress, r.HostClient.Scheme()); err != nil {
 		return reconcile.Result{}, err
 	}
 
@@ -128,7 +130,7 @@ func (r *IngressReconciler) Reconcile(ctx context.Context, req reconcile.Request
 
 	// create or update the ingress on host
 	var hostIngress networkingv1.Ingress
-	if err := r.HostClient.Get(ctx, types.NamespacedName{Name: syncedIngress.Name, Namespace: r.ClusterNamespace}, &hostIngress); err != nil {
+	if err := r.HostClient.Get(ctx, types.NamespacedName{Name: syncedIngress.Name, Namespace: syncedIngress.Namespace}, &hostIngress); err != nil {
 		if apierrors.IsNotFound(err) {
 			log.Info("creating the ingress for the first time on the host cluster")
 			return reconcile.Result{}, r.HostClient.Create(ctx, syncedIngress)
@@ -137,6 +139,11 @@ func (r *IngressReconciler) Reconcile(ctx context.Context, req reconcile.Request
 		return reconcile.Result{}, err
 	}
 
+	// the host object could belong to another virtual object with the same translated name
+	if err := r.Translator.CheckCollision(&hostIngress, req.Namespace, req.Name); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	log.Info("updating ingress on the host cluster")
 
 	return reconcile.Result{}, r.HostClient.Update(ctx, syncedIngress)
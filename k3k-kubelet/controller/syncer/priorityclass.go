@@ -17,7 +17,6 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
-	"github.com/rancher/k3k/k3k-kubelet/translate"
 	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
 )
 
@@ -34,6 +33,11 @@ type PriorityClassSyncer struct {
 
 // AddPriorityClassSyncer adds a PriorityClass reconciler to k3k-kubelet
 func AddPriorityClassSyncer(ctx context.Context, virtMgr, hostMgr manager.Manager, clusterName, clusterNamespace string) error {
+	translator, err := newTranslator(ctx, hostMgr, clusterName, clusterNamespace)
+	if err != nil {
+		return err
+	}
+
 	// initialize a new Reconciler
 	reconciler := PriorityClassSyncer{
 		SyncerContext: &SyncerContext{
@@ -41,10 +45,7 @@ func AddPriorityClassSyncer(ctx context.Context, virtMgr, hostMgr manager.Manage
 			ClusterNamespace: clusterNamespace,
 			VirtualClient:    virtMgr.GetClient(),
 			HostClient:       hostMgr.GetClient(),
-			Translator: translate.ToHostTranslator{
-				ClusterName:      clusterName,
-				ClusterNamespace: clusterNamespace,
-			},
+			Translator:       translator,
 		},
 	}
 
@@ -54,6 +55,9 @@ func AddPriorityClassSyncer(ctx context.Context, virtMgr, hostMgr manager.Manage
 		Named(name).
 		For(&schedulingv1.PriorityClass{}).WithEventFilter(ignoreSystemPrefixPredicate).
 		WithEventFilter(predicate.NewPredicateFuncs(reconciler.filterResources)).
+		WatchesRawSource(reconciler.syncConfigSource(hostMgr, &schedulingv1.PriorityClassList{}, func(object ctrlruntimeclient.Object) bool {
+			return !strings.HasPrefix(object.GetName(), "system-") && reconciler.filterResources(object)
+		})).
 		Complete(&reconciler)
 }
 
@@ -1,9 +1,23 @@
 package syncer
 
 import (
+	"context"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/rancher/k3k/k3k-kubelet/translate"
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
 )
 
 type SyncerContext struct {
@@ -13,3 +27,67 @@ type SyncerContext struct {
 	HostClient       client.Client
 	Translator       translate.ToHostTranslator
 }
+
+// newTranslator returns the translator of the resources of the cluster, with its naming strategy and namespace mapping.
+// The cluster is read with the API reader, since the cache of the host manager could be not started yet.
+func newTranslator(ctx context.Context, hostMgr manager.Manager, clusterName, clusterNamespace string) (translate.ToHostTranslator, error) {
+	var cluster v1alpha1.Cluster
+	if err := hostMgr.GetAPIReader().Get(ctx, types.NamespacedName{Name: clusterName, Namespace: clusterNamespace}, &cluster); err != nil {
+		return translate.ToHostTranslator{}, err
+	}
+
+	return translate.ToHostTranslator{
+		ClusterName:      clusterName,
+		ClusterNamespace: clusterNamespace,
+		NamingStrategy:   cluster.Spec.NamingStrategy,
+		NamespaceMapping: cluster.Spec.NamespaceMapping,
+	}, nil
+}
+
+// syncConfigSource returns a source watching the cluster in the host cluster. When the sync configuration of the
+// cluster changes, the objects of the list selected by the filter are enqueued, so that the objects enabled or
+// selected by the new configuration are synced without restarting the k3k-kubelet.
+func (s *SyncerContext) syncConfigSource(hostMgr manager.Manager, list client.ObjectList, filter func(client.Object) bool) source.Source {
+	isCluster := func(cluster *v1alpha1.Cluster) bool {
+		return cluster.Name == s.ClusterName && cluster.Namespace == s.ClusterNamespace
+	}
+
+	syncConfigPredicate := predicate.TypedFuncs[*v1alpha1.Cluster]{
+		CreateFunc:  func(event.TypedCreateEvent[*v1alpha1.Cluster]) bool { return false },
+		DeleteFunc:  func(event.TypedDeleteEvent[*v1alpha1.Cluster]) bool { return false },
+		GenericFunc: func(event.TypedGenericEvent[*v1alpha1.Cluster]) bool { return false },
+		UpdateFunc: func(e event.TypedUpdateEvent[*v1alpha1.Cluster]) bool {
+			return isCluster(e.ObjectNew) && !reflect.DeepEqual(e.ObjectOld.Spec.Sync, e.ObjectNew.Spec.Sync)
+		},
+	}
+
+	enqueueObjects := func(ctx context.Context, _ *v1alpha1.Cluster) []reconcile.Request {
+		objectList := list.DeepCopyObject().(client.ObjectList)
+
+		if err := s.VirtualClient.List(ctx, objectList); err != nil {
+			ctrl.LoggerFrom(ctx).Error(err, "unable to list the objects to resync")
+			return nil
+		}
+
+		objects, err := meta.ExtractList(objectList)
+		if err != nil {
+			ctrl.LoggerFrom(ctx).Error(err, "unable to list the objects to resync")
+			return nil
+		}
+
+		var requests []reconcile.Request
+
+		for _, obj := range objects {
+			object, ok := obj.(client.Object)
+			if !ok || !filter(object) {
+				continue
+			}
+
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(object)})
+		}
+
+		return requests
+	}
+
+	return source.Kind(hostMgr.GetCache(), &v1alpha1.Cluster{}, handler.TypedEnqueueRequestsFromMapFunc(enqueueObjects), syncConfigPredicate)
+}
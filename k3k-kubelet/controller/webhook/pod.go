@@ -4,21 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	v1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/rancher/k3k/k3k-kubelet/metrics"
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
 	"github.com/rancher/k3k/pkg/controller/cluster/agent"
 	"github.com/rancher/k3k/pkg/log"
 )
@@ -28,10 +31,13 @@ const (
 	webhookTimeout = int32(10)
 	webhookPath    = "/mutate--v1-pod"
 	FieldpathField = "k3k.io/fieldpath"
+	// FieldpathInitField is the prefix of the annotations with the fieldpath envs of the init containers
+	FieldpathInitField = "k3k.io/fieldpath-init"
 )
 
 type webhookHandler struct {
 	client           ctrlruntimeclient.Client
+	hostClient       ctrlruntimeclient.Client
 	scheme           *runtime.Scheme
 	serviceName      string
 	clusterName      string
@@ -46,6 +52,7 @@ type webhookHandler struct {
 func AddPodMutatorWebhook(ctx context.Context, mgr manager.Manager, hostClient ctrlruntimeclient.Client, clusterName, clusterNamespace, serviceName string, logger *log.Logger, webhookPort int) error {
 	handler := webhookHandler{
 		client:           mgr.GetClient(),
+		hostClient:       hostClient,
 		scheme:           mgr.GetScheme(),
 		logger:           logger,
 		serviceName:      serviceName,
@@ -54,21 +61,41 @@ func AddPodMutatorWebhook(ctx context.Context, mgr manager.Manager, hostClient c
 		webhookPort:      webhookPort,
 	}
 
-	// create mutator webhook configuration to the cluster
-	config, err := handler.configuration(ctx, hostClient)
+	// create or update the mutator webhook configuration of the cluster, e.g. with a renewed CA bundle
+	expectedConfig, err := handler.configuration(ctx, hostClient)
 	if err != nil {
 		return err
 	}
 
-	if err := handler.client.Create(ctx, config); err != nil {
-		if !apierrors.IsAlreadyExists(err) {
-			return err
-		}
+	config := expectedConfig.DeepCopy()
+
+	if err := ensureConfiguration(ctx, mgr, config, func() error {
+		config.Webhooks = expectedConfig.Webhooks
+		return nil
+	}); err != nil {
+		return err
 	}
+
 	// register webhook with the manager
 	return ctrl.NewWebhookManagedBy(mgr).For(&v1.Pod{}).WithDefaulter(&handler).Complete()
 }
 
+// ensureConfiguration creates or updates a webhook configuration of the virtual cluster. The configurations are
+// written before the manager is started, so they are read from the API server instead of the cache of the manager.
+func ensureConfiguration(ctx context.Context, mgr manager.Manager, config ctrlruntimeclient.Object, mutate controllerutil.MutateFn) error {
+	virtualClient, err := ctrlruntimeclient.New(mgr.GetConfig(), ctrlruntimeclient.Options{
+		Scheme: mgr.GetScheme(),
+		Mapper: mgr.GetRESTMapper(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = controllerutil.CreateOrUpdate(ctx, virtualClient, config, mutate)
+
+	return err
+}
+
 func (w *webhookHandler) Default(ctx context.Context, obj runtime.Object) error {
 	pod, ok := obj.(*v1.Pod)
 	if !ok {
@@ -81,38 +108,86 @@ func (w *webhookHandler) Default(ctx context.Context, obj runtime.Object) error
 		pod.Annotations = make(map[string]string)
 	}
 
-	for i, container := range pod.Spec.Containers {
-		for j, env := range container.Env {
+	annotations := len(pod.Annotations)
+
+	// the init containers are included, since they can be sidecars running for the whole lifecycle of the pod
+	removeStatusFieldPathEnvs(pod.Annotations, FieldpathField, pod.Spec.Containers)
+	removeStatusFieldPathEnvs(pod.Annotations, FieldpathInitField, pod.Spec.InitContainers)
+
+	tolerations := len(pod.Spec.Tolerations)
+
+	// the system pods (i.e. coredns) have to run on the virtual nodes, even if tainted
+	if pod.Namespace == metav1.NamespaceSystem {
+		if err := w.addNodeTaintsTolerations(ctx, pod); err != nil {
+			return err
+		}
+	}
+
+	if len(pod.Annotations) > annotations || len(pod.Spec.Tolerations) > tolerations {
+		metrics.WebhookPodMutations.WithLabelValues("mutated").Inc()
+	} else {
+		metrics.WebhookPodMutations.WithLabelValues("unchanged").Inc()
+	}
+
+	return nil
+}
+
+// addNodeTaintsTolerations adds to the pod the tolerations of the taints of the virtual nodes declared in the cluster
+func (w *webhookHandler) addNodeTaintsTolerations(ctx context.Context, pod *v1.Pod) error {
+	var cluster v1alpha1.Cluster
+	if err := w.hostClient.Get(ctx, types.NamespacedName{Name: w.clusterName, Namespace: w.clusterNamespace}, &cluster); err != nil {
+		return err
+	}
+
+	if cluster.Spec.MirrorHostNodes {
+		return nil
+	}
+
+	for _, taint := range cluster.Spec.NodeTaints {
+		if slices.ContainsFunc(pod.Spec.Tolerations, func(toleration v1.Toleration) bool { return toleration.ToleratesTaint(&taint) }) {
+			continue
+		}
+
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, v1.Toleration{
+			Key:      taint.Key,
+			Operator: v1.TolerationOpExists,
+			Effect:   taint.Effect,
+		})
+	}
+
+	return nil
+}
+
+// removeStatusFieldPathEnvs removes the envs referencing the status fields from the containers,
+// and saves them in the annotations with the given prefix, the index of the container and the name of the env
+func removeStatusFieldPathEnvs(annotations map[string]string, annotationPrefix string, containers []v1.Container) {
+	for i := range containers {
+		containers[i].Env = slices.DeleteFunc(containers[i].Env, func(env v1.EnvVar) bool {
 			if env.ValueFrom == nil || env.ValueFrom.FieldRef == nil {
-				continue
+				return false
 			}
 
 			fieldPath := env.ValueFrom.FieldRef.FieldPath
-			if strings.Contains(fieldPath, "status.") {
-				annotationKey := fmt.Sprintf("%s_%d_%s", FieldpathField, i, env.Name)
-				pod.Annotations[annotationKey] = fieldPath
-				pod.Spec.Containers[i].Env = removeEnv(pod.Spec.Containers[i].Env, j)
+			if !strings.Contains(fieldPath, "status.") {
+				return false
 			}
-		}
-	}
 
-	return nil
+			annotationKey := fmt.Sprintf("%s_%d_%s", annotationPrefix, i, env.Name)
+			annotations[annotationKey] = fieldPath
+
+			return true
+		})
+	}
 }
 
 func (w *webhookHandler) configuration(ctx context.Context, hostClient ctrlruntimeclient.Client) (*admissionregistrationv1.MutatingWebhookConfiguration, error) {
 	w.logger.Infow("extracting webhook tls from host cluster")
 
-	var webhookTLSSecret v1.Secret
-
-	if err := hostClient.Get(ctx, types.NamespacedName{Name: agent.WebhookSecretName(w.clusterName), Namespace: w.clusterNamespace}, &webhookTLSSecret); err != nil {
+	caBundle, err := webhookCABundle(ctx, hostClient, w.clusterName, w.clusterNamespace)
+	if err != nil {
 		return nil, err
 	}
 
-	caBundle, ok := webhookTLSSecret.Data["ca.crt"]
-	if !ok {
-		return nil, errors.New("webhook CABundle does not exist in secret")
-	}
-
 	webhookURL := fmt.Sprintf("https://%s:%d%s", w.serviceName, w.webhookPort, webhookPath)
 
 	return &admissionregistrationv1.MutatingWebhookConfiguration{
@@ -151,9 +226,20 @@ func (w *webhookHandler) configuration(ctx context.Context, hostClient ctrlrunti
 	}, nil
 }
 
-func removeEnv(envs []v1.EnvVar, i int) []v1.EnvVar {
-	envs[i] = envs[len(envs)-1]
-	return envs[:len(envs)-1]
+// webhookCABundle returns the CA bundle of the webhook TLS secret created by the controller in the host cluster
+func webhookCABundle(ctx context.Context, hostClient ctrlruntimeclient.Client, clusterName, clusterNamespace string) ([]byte, error) {
+	var webhookTLSSecret v1.Secret
+
+	if err := hostClient.Get(ctx, types.NamespacedName{Name: agent.WebhookSecretName(clusterName), Namespace: clusterNamespace}, &webhookTLSSecret); err != nil {
+		return nil, err
+	}
+
+	caBundle, ok := webhookTLSSecret.Data["ca.crt"]
+	if !ok {
+		return nil, errors.New("webhook CABundle does not exist in secret")
+	}
+
+	return caBundle, nil
 }
 
 func ParseFieldPathAnnotationKey(annotationKey string) (int, string, error) {
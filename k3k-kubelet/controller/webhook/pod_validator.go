@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rancher/k3k/k3k-kubelet/metrics"
+	"github.com/rancher/k3k/k3k-kubelet/policy"
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/log"
+)
+
+const (
+	validatorWebhookName = "podvalidator.k3k.io"
+	validatorWebhookPath = "/validate--v1-pod"
+)
+
+type podValidator struct {
+	hostClient       ctrlruntimeclient.Client
+	serviceName      string
+	clusterName      string
+	clusterNamespace string
+	logger           *log.Logger
+	webhookPort      int
+}
+
+// AddPodValidatorWebhook will add a validating webhook to the virtual cluster to reject the pods
+// using host-level features or images not allowed by the VirtualClusterPolicy of the cluster
+func AddPodValidatorWebhook(ctx context.Context, mgr manager.Manager, hostClient ctrlruntimeclient.Client, clusterName, clusterNamespace, serviceName string, logger *log.Logger, webhookPort int) error {
+	validator := podValidator{
+		hostClient:       hostClient,
+		logger:           logger,
+		serviceName:      serviceName,
+		clusterName:      clusterName,
+		clusterNamespace: clusterNamespace,
+		webhookPort:      webhookPort,
+	}
+
+	// create or update the validating webhook configuration of the cluster, e.g. with a renewed CA bundle
+	expectedConfig, err := validator.configuration(ctx)
+	if err != nil {
+		return err
+	}
+
+	config := expectedConfig.DeepCopy()
+
+	if err := ensureConfiguration(ctx, mgr, config, func() error {
+		config.Webhooks = expectedConfig.Webhooks
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// register webhook with the manager
+	return ctrl.NewWebhookManagedBy(mgr).For(&v1.Pod{}).WithValidator(&validator).Complete()
+}
+
+func (v *podValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("invalid request: object was type %t not pod", obj)
+	}
+
+	v.logger.Infow("validator webhook request", "Pod", pod.Name, "Namespace", pod.Namespace)
+
+	return nil, v.validate(ctx, pod, true)
+}
+
+// ValidateUpdate will check only the images of the pod, since the host-level features cannot be changed after its creation,
+// with the exception of the added ephemeral containers.
+// Updates not changing the images are always allowed, to avoid blocking the pods created before a change of the policy.
+func (v *podValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldPod, okOld := oldObj.(*v1.Pod)
+	pod, okNew := newObj.(*v1.Pod)
+
+	if !okOld || !okNew {
+		return nil, fmt.Errorf("invalid request: object was type %t not pod", newObj)
+	}
+
+	if reflect.DeepEqual(containerImages(oldPod), containerImages(pod)) {
+		return nil, nil
+	}
+
+	v.logger.Infow("validator webhook request", "Pod", pod.Name, "Namespace", pod.Namespace)
+
+	// ephemeral containers are added with an update of the subresource, and they could be privileged
+	var checkHostAccess bool
+	if req, err := admission.RequestFromContext(ctx); err == nil {
+		checkHostAccess = req.SubResource == "ephemeralcontainers"
+	}
+
+	return nil, v.validate(ctx, pod, checkHostAccess)
+}
+
+func (v *podValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the pod against the VirtualClusterPolicy of the cluster
+func (v *podValidator) validate(ctx context.Context, pod *v1.Pod, checkHostAccess bool) error {
+	var cluster v1alpha1.Cluster
+	if err := v.hostClient.Get(ctx, types.NamespacedName{Name: v.clusterName, Namespace: v.clusterNamespace}, &cluster); err != nil {
+		return err
+	}
+
+	vcp, err := policy.Get(ctx, v.hostClient, &cluster)
+	if err != nil {
+		return err
+	}
+
+	if vcp == nil {
+		return nil
+	}
+
+	if checkHostAccess {
+		violations := policy.HostAccessViolations(pod, vcp.Spec.HostAccess, cluster.Name)
+		if len(violations) > 0 {
+			metrics.WebhookPodDenials.WithLabelValues("hostAccess").Inc()
+
+			err := fmt.Errorf("host access denied by VirtualClusterPolicy %q: %s", vcp.Name, strings.Join(violations, ", "))
+
+			return apierrors.NewForbidden(v1.Resource("pods"), pod.Name, err)
+		}
+	}
+
+	// the images are resolved on a copy, since the rewrite rules are applied only to the host pod
+	if err := policy.ApplyImagePolicy(vcp.Spec.ImagePolicy, pod.DeepCopy()); err != nil {
+		metrics.WebhookPodDenials.WithLabelValues("image").Inc()
+
+		err := fmt.Errorf("image denied by VirtualClusterPolicy %q: %s", vcp.Name, strings.ReplaceAll(err.Error(), "\n", ", "))
+
+		return apierrors.NewForbidden(v1.Resource("pods"), pod.Name, err)
+	}
+
+	return nil
+}
+
+// containerImages returns the images of all the containers of the pod, by container name
+func containerImages(pod *v1.Pod) map[string]string {
+	images := make(map[string]string)
+
+	for _, container := range pod.Spec.InitContainers {
+		images[container.Name] = container.Image
+	}
+
+	for _, container := range pod.Spec.Containers {
+		images[container.Name] = container.Image
+	}
+
+	for _, container := range pod.Spec.EphemeralContainers {
+		images[container.Name] = container.Image
+	}
+
+	return images
+}
+
+func (v *podValidator) configuration(ctx context.Context) (*admissionregistrationv1.ValidatingWebhookConfiguration, error) {
+	v.logger.Infow("extracting webhook tls from host cluster")
+
+	caBundle, err := webhookCABundle(ctx, v.hostClient, v.clusterName, v.clusterNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookURL := fmt.Sprintf("https://%s:%d%s", v.serviceName, v.webhookPort, validatorWebhookPath)
+
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admissionregistration.k8s.io/v1",
+			Kind:       "ValidatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: validatorWebhookName + "-configuration",
+		},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{
+				Name:                    validatorWebhookName,
+				AdmissionReviewVersions: []string{"v1"},
+				SideEffects:             ptr.To(admissionregistrationv1.SideEffectClassNone),
+				TimeoutSeconds:          ptr.To(webhookTimeout),
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					URL:      ptr.To(webhookURL),
+					CABundle: caBundle,
+				},
+				Rules: []admissionregistrationv1.RuleWithOperations{
+					{
+						Operations: []admissionregistrationv1.OperationType{
+							"CREATE",
+							"UPDATE",
+						},
+						Rule: admissionregistrationv1.Rule{
+							APIGroups:   []string{""},
+							APIVersions: []string{"v1"},
+							Resources:   []string{"pods", "pods/ephemeralcontainers"},
+							Scope:       ptr.To(admissionregistrationv1.NamespacedScope),
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
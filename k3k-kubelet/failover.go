@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/node"
+	"github.com/virtual-kubelet/virtual-kubelet/node/nodeutil"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster/agent"
+)
+
+// Every virtual node is run by the replica holding its Lease in the host cluster. The replica of the host node of a
+// virtual node holds the Lease while it's running: when the Lease expires, or it's released on shutdown, another
+// replica takes over the virtual node, updating its status and lease and running its pods, until the replica of
+// the host node is started again.
+
+// nodeLeaseName returns the name of the Lease of a virtual node, in the namespace of the cluster
+func nodeLeaseName(clusterName, nodeName string) string {
+	return controller.SafeConcatNameWithPrefix(clusterName, "node", nodeName)
+}
+
+// nodeElector returns the elector of the Lease of a virtual node, held by this replica
+func (k *kubelet) nodeElector(cfg config, nodeName string, callbacks leaderelection.LeaderCallbacks) (*leaderelection.LeaderElector, error) {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      nodeLeaseName(cfg.ClusterName, nodeName),
+			Namespace: cfg.ClusterNamespace,
+		},
+		Client: k.hostClientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: k.name,
+		},
+	}
+
+	return leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks:       callbacks,
+		Name:            nodeName,
+	})
+}
+
+// runNode runs the virtual node of this replica while it holds its Lease. The Lease is acquired as soon as
+// a replica that took over the virtual node releases it.
+func (k *kubelet) runNode(ctx context.Context, cfg config) {
+	elector, err := k.nodeElector(cfg, k.name, leaderelection.LeaderCallbacks{
+		OnStartedLeading: func(ctx context.Context) {
+			if err := k.node.Run(ctx); err != nil {
+				k.logger.Fatalw("node errored when running", zap.Error(err))
+			}
+		},
+		OnStoppedLeading: func() {
+			if ctx.Err() == nil {
+				k.logger.Fatal("lost the lease of the node")
+			}
+		},
+	})
+	if err != nil {
+		k.logger.Fatalw("failed to create the elector of the node lease", zap.Error(err))
+	}
+
+	elector.Run(ctx)
+}
+
+// failover takes over the virtual nodes of the other replicas until the context is cancelled
+type failover struct {
+	mu        sync.Mutex
+	takenOver map[string]struct{}
+}
+
+// runFailover periodically checks the Leases of the virtual nodes of the other replicas, and takes over the
+// virtual nodes with an expired Lease
+func (k *kubelet) runFailover(ctx context.Context, cfg config) {
+	f := &failover{takenOver: make(map[string]struct{})}
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		var pods v1.PodList
+		if err := k.hostClient.List(ctx, &pods, ctrlruntimeclient.InNamespace(cfg.ClusterNamespace), ctrlruntimeclient.MatchingLabels(agent.SharedAgentLabels(cfg.ClusterName))); err != nil {
+			k.logger.Errorw("failed to list the replicas of the k3k-kubelet", zap.Error(err))
+			return
+		}
+
+		for _, pod := range pods.Items {
+			nodeName := pod.Spec.NodeName
+			if nodeName == "" || nodeName == k.name || !f.start(nodeName) {
+				continue
+			}
+
+			go func() {
+				defer f.stop(nodeName)
+
+				if err := k.takeOverNode(ctx, cfg, nodeName); err != nil {
+					k.logger.Errorw("failed to take over the node", "node", nodeName, zap.Error(err))
+				}
+			}()
+		}
+	}, retryPeriod)
+}
+
+// start marks a virtual node as taken over, it returns false if the node is already taken over
+func (f *failover) start(nodeName string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, found := f.takenOver[nodeName]; found {
+		return false
+	}
+
+	f.takenOver[nodeName] = struct{}{}
+
+	return true
+}
+
+func (f *failover) stop(nodeName string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.takenOver, nodeName)
+}
+
+// takeOverNode runs a virtual node of another replica while its Lease is expired, and until the replica of its
+// host node is started again. The virtual nodes of the deleted host nodes are not taken over.
+func (k *kubelet) takeOverNode(ctx context.Context, cfg config, nodeName string) error {
+	expired, err := k.nodeLeaseExpired(ctx, cfg, nodeName)
+	if err != nil || !expired {
+		return err
+	}
+
+	var hostNode v1.Node
+	if err := k.hostClient.Get(ctx, types.NamespacedName{Name: nodeName}, &hostNode); err != nil {
+		return ctrlruntimeclient.IgnoreNotFound(err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var takenOverAt time.Time
+
+	elector, err := k.nodeElector(cfg, nodeName, leaderelection.LeaderCallbacks{
+		OnStartedLeading: func(ctx context.Context) {
+			defer cancel()
+
+			k.logger.Infow("taking over the node", "node", nodeName)
+
+			if err := k.runTakenOverNode(ctx, cfg, nodeName); err != nil {
+				k.logger.Errorw("taken over node errored when running", "node", nodeName, zap.Error(err))
+			}
+		},
+		OnStoppedLeading: func() {
+			k.logger.Infow("stopped running the taken over node", "node", nodeName)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// the Lease is released when the replica of the host node is started, or when another replica acquired it first
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if !elector.IsLeader() {
+			if expired, err := k.nodeLeaseExpired(ctx, cfg, nodeName); err == nil && !expired {
+				cancel()
+			}
+
+			return
+		}
+
+		if takenOverAt.IsZero() {
+			takenOverAt = time.Now()
+		}
+
+		started, err := k.replicaStartedSince(ctx, cfg, nodeName, takenOverAt)
+		if err != nil {
+			k.logger.Errorw("failed to get the replica of the node", "node", nodeName, zap.Error(err))
+			return
+		}
+
+		if started {
+			k.logger.Infow("giving back the node to its replica", "node", nodeName)
+			cancel()
+		}
+	}, retryPeriod)
+
+	elector.Run(ctx)
+
+	return nil
+}
+
+// runTakenOverNode runs a virtual node of another replica. The node has no kubelet API server of its own: its address
+// is the Service of the k3k-kubelet, and the API requests are served by the providers of all the replicas.
+func (k *kubelet) runTakenOverNode(ctx context.Context, cfg config, nodeName string) error {
+	takenOverNode, err := nodeutil.NewNode(nodeName, func(pc nodeutil.ProviderConfig) (nodeutil.Provider, node.NodeProvider, error) {
+		return k.newProvider(cfg, pc, nodeName)
+	}, nodeutil.WithClient(k.virtClient))
+	if err != nil {
+		return errors.New("unable to create the taken over node: " + err.Error())
+	}
+
+	return takenOverNode.Run(log.WithLogger(ctx, k.logger))
+}
+
+// nodeLeaseExpired returns true if the Lease of a virtual node is released or expired. The virtual nodes whose replica
+// never acquired the Lease are not taken over.
+func (k *kubelet) nodeLeaseExpired(ctx context.Context, cfg config, nodeName string) (bool, error) {
+	var lease coordinationv1.Lease
+
+	key := types.NamespacedName{Name: nodeLeaseName(cfg.ClusterName, nodeName), Namespace: cfg.ClusterNamespace}
+	if err := k.hostClient.Get(ctx, key, &lease); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" {
+		return true, nil
+	}
+
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true, nil
+	}
+
+	expiration := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+
+	return time.Now().After(expiration), nil
+}
+
+// replicaStartedSince returns true if the k3k-kubelet of the replica of a host node was started after the given time
+func (k *kubelet) replicaStartedSince(ctx context.Context, cfg config, nodeName string, since time.Time) (bool, error) {
+	var pods v1.PodList
+	if err := k.hostClient.List(ctx, &pods, ctrlruntimeclient.InNamespace(cfg.ClusterNamespace), ctrlruntimeclient.MatchingLabels(agent.SharedAgentLabels(cfg.ClusterName))); err != nil {
+		return false, err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == nodeName && replicaStartedSince(&pod, since) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func replicaStartedSince(pod *v1.Pod, since time.Time) bool {
+	if pod.DeletionTimestamp != nil {
+		return false
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Running != nil && status.State.Running.StartedAt.After(since) {
+			return true
+		}
+	}
+
+	return false
+}
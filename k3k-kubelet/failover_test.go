@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_replicaStartedSince(t *testing.T) {
+	takenOverAt := time.Now()
+
+	runningPod := func(startedAt time.Time) *v1.Pod {
+		return &v1.Pod{
+			Status: v1.PodStatus{
+				ContainerStatuses: []v1.ContainerStatus{
+					{State: v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: metav1.NewTime(startedAt)}}},
+				},
+			},
+		}
+	}
+
+	deletedPod := runningPod(takenOverAt.Add(time.Second))
+	deletedPod.DeletionTimestamp = ptr.To(metav1.NewTime(takenOverAt.Add(time.Second)))
+
+	tests := []struct {
+		name string
+		pod  *v1.Pod
+		want bool
+	}{
+		{name: "started after the takeover", pod: runningPod(takenOverAt.Add(time.Second)), want: true},
+		{name: "started before the takeover", pod: runningPod(takenOverAt.Add(-time.Minute))},
+		{name: "not running", pod: &v1.Pod{Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{}}}}},
+		{name: "deleted", pod: deletedPod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, replicaStartedSince(tt.pod, takenOverAt))
+		})
+	}
+}
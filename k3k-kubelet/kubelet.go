@@ -2,18 +2,19 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
-	"net"
+	"io"
 	"net/http"
-	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/zapr"
 	"github.com/virtual-kubelet/virtual-kubelet/log"
 	"github.com/virtual-kubelet/virtual-kubelet/node"
+	"github.com/virtual-kubelet/virtual-kubelet/node/api"
 	"github.com/virtual-kubelet/virtual-kubelet/node/nodeutil"
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -22,21 +23,26 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/certificate"
 	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
-	certutil "github.com/rancher/dynamiclistener/cert"
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
+	"github.com/rancher/k3k/k3k-kubelet/audit"
+	"github.com/rancher/k3k/k3k-kubelet/controller/csr"
 	"github.com/rancher/k3k/k3k-kubelet/controller/syncer"
 	k3kwebhook "github.com/rancher/k3k/k3k-kubelet/controller/webhook"
+	"github.com/rancher/k3k/k3k-kubelet/metrics"
 	"github.com/rancher/k3k/k3k-kubelet/provider"
 	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
 	"github.com/rancher/k3k/pkg/controller"
@@ -51,6 +57,14 @@ var (
 	k3kKubeletName = "k3k-kubelet"
 )
 
+// The leader election timings of the managers are shorter than the defaults, so that the syncers
+// fail over to another replica within a few seconds. The lease is also released on shutdown.
+const (
+	leaseDuration = 8 * time.Second
+	renewDeadline = 5 * time.Second
+	retryPeriod   = 1 * time.Second
+)
+
 func init() {
 	_ = clientgoscheme.AddToScheme(baseScheme)
 	_ = v1alpha1.AddToScheme(baseScheme)
@@ -59,19 +73,29 @@ func init() {
 type kubelet struct {
 	virtualCluster v1alpha1.Cluster
 
-	name       string
-	port       int
-	hostConfig *rest.Config
-	virtConfig *rest.Config
-	agentIP    string
-	dnsIP      string
-	hostClient ctrlruntimeclient.Client
-	virtClient kubernetes.Interface
-	hostMgr    manager.Manager
-	virtualMgr manager.Manager
-	node       *nodeutil.Node
-	logger     *k3klog.Logger
-	token      string
+	// mu guards the config and the log level annotation, that are reloaded at runtime
+	mu                 sync.Mutex
+	config             config
+	logLevelAnnotation string
+	nodeProvider       *provider.Node
+	provider           *provider.Provider
+
+	name          string
+	port          int
+	hostConfig    *rest.Config
+	virtConfig    *rest.Config
+	agentIP       string
+	dnsIP         string
+	hostClient    ctrlruntimeclient.Client
+	hostClientset kubernetes.Interface
+	virtClient    kubernetes.Interface
+	hostMgr       manager.Manager
+	virtualMgr    manager.Manager
+	node          *nodeutil.Node
+	certManager   certificate.Manager
+	clientCAs     *x509.CertPool
+	auditor       *audit.Auditor
+	logger        *k3klog.Logger
 }
 
 func newKubelet(ctx context.Context, c *config, logger *k3klog.Logger) (*kubelet, error) {
@@ -80,6 +104,8 @@ func newKubelet(ctx context.Context, c *config, logger *k3klog.Logger) (*kubelet
 		return nil, err
 	}
 
+	metrics.InstrumentConfig(hostConfig, metrics.ClusterHost)
+
 	hostClient, err := ctrlruntimeclient.New(hostConfig, ctrlruntimeclient.Options{
 		Scheme: baseScheme,
 	})
@@ -87,11 +113,23 @@ func newKubelet(ctx context.Context, c *config, logger *k3klog.Logger) (*kubelet
 		return nil, err
 	}
 
+	hostClientset, err := kubernetes.NewForConfig(hostConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var virtualCluster v1alpha1.Cluster
+	if err := hostClient.Get(ctx, types.NamespacedName{Name: c.ClusterName, Namespace: c.ClusterNamespace}, &virtualCluster); err != nil {
+		return nil, errors.New("failed to get virtualCluster spec: " + err.Error())
+	}
+
 	virtConfig, err := virtRestConfig(ctx, c.VirtKubeconfig, hostClient, c.ClusterName, c.ClusterNamespace, c.Token, logger)
 	if err != nil {
 		return nil, err
 	}
 
+	metrics.InstrumentConfig(virtConfig, metrics.ClusterVirtual)
+
 	virtClient, err := kubernetes.NewForConfig(virtConfig)
 	if err != nil {
 		return nil, err
@@ -99,27 +137,30 @@ func newKubelet(ctx context.Context, c *config, logger *k3klog.Logger) (*kubelet
 
 	ctrl.SetLogger(zapr.NewLogger(logger.Desugar().WithOptions(zap.AddCallerSkip(1))))
 
-	hostMetricsBindAddress := ":8083"
-	virtualMetricsBindAddress := ":8084"
-
-	if c.MirrorHostNodes {
-		hostMetricsBindAddress = "0"
-		virtualMetricsBindAddress = "0"
+	// the managers share the same metrics registry, so the metrics are served only by the host manager
+	metricsBindAddress := "0"
+	if c.MetricsPort != 0 {
+		metricsBindAddress = fmt.Sprintf(":%d", c.MetricsPort)
 	}
 
 	hostMgr, err := ctrl.NewManager(hostConfig, manager.Options{
-		Scheme:                  baseScheme,
-		LeaderElection:          true,
-		LeaderElectionNamespace: c.ClusterNamespace,
-		LeaderElectionID:        c.ClusterName,
+		Scheme:                        baseScheme,
+		LeaderElection:                true,
+		LeaderElectionNamespace:       c.ClusterNamespace,
+		LeaderElectionID:              c.ClusterName,
+		LeaderElectionReleaseOnCancel: true,
+		LeaseDuration:                 ptr.To(leaseDuration),
+		RenewDeadline:                 ptr.To(renewDeadline),
+		RetryPeriod:                   ptr.To(retryPeriod),
 		Metrics: ctrlserver.Options{
-			BindAddress: hostMetricsBindAddress,
+			BindAddress: metricsBindAddress,
 		},
 		Cache: cache.Options{
 			DefaultNamespaces: map[string]cache.Config{
 				c.ClusterNamespace: {},
 			},
 		},
+		Client: hostClientOptions(virtualCluster.Spec.NamespaceMapping),
 	})
 	if err != nil {
 		return nil, errors.New("unable to create controller-runtime mgr for host cluster: " + err.Error())
@@ -137,13 +178,17 @@ func newKubelet(ctx context.Context, c *config, logger *k3klog.Logger) (*kubelet
 	})
 
 	virtualMgr, err := ctrl.NewManager(virtConfig, manager.Options{
-		Scheme:                  virtualScheme,
-		WebhookServer:           webhookServer,
-		LeaderElection:          true,
-		LeaderElectionNamespace: "kube-system",
-		LeaderElectionID:        c.ClusterName,
+		Scheme:                        virtualScheme,
+		WebhookServer:                 webhookServer,
+		LeaderElection:                true,
+		LeaderElectionNamespace:       "kube-system",
+		LeaderElectionID:              c.ClusterName,
+		LeaderElectionReleaseOnCancel: true,
+		LeaseDuration:                 ptr.To(leaseDuration),
+		RenewDeadline:                 ptr.To(renewDeadline),
+		RetryPeriod:                   ptr.To(retryPeriod),
 		Metrics: ctrlserver.Options{
-			BindAddress: virtualMetricsBindAddress,
+			BindAddress: "0",
 		},
 	})
 	if err != nil {
@@ -156,6 +201,12 @@ func newKubelet(ctx context.Context, c *config, logger *k3klog.Logger) (*kubelet
 		return nil, errors.New("unable to add pod mutator webhook for virtual cluster: " + err.Error())
 	}
 
+	logger.Info("adding pod validator webhook")
+
+	if err := k3kwebhook.AddPodValidatorWebhook(ctx, virtualMgr, hostClient, c.ClusterName, c.ClusterNamespace, c.ServiceName, logger, c.WebhookPort); err != nil {
+		return nil, errors.New("unable to add pod validator webhook for virtual cluster: " + err.Error())
+	}
+
 	if err := addControllers(ctx, hostMgr, virtualMgr, c, hostClient); err != nil {
 		return nil, errors.New("failed to add controller: " + err.Error())
 	}
@@ -173,29 +224,84 @@ func newKubelet(ctx context.Context, c *config, logger *k3klog.Logger) (*kubelet
 		return nil, errors.New("failed to get the DNS service for the cluster: " + err.Error())
 	}
 
-	var virtualCluster v1alpha1.Cluster
-	if err := hostClient.Get(ctx, types.NamespacedName{Name: c.ClusterName, Namespace: c.ClusterNamespace}, &virtualCluster); err != nil {
-		return nil, errors.New("failed to get virtualCluster spec: " + err.Error())
+	certManager, err := newServingCertificateManager(virtClient, c.AgentHostname, c.AgentHostname, clusterIP, logger)
+	if err != nil {
+		return nil, errors.New("failed to create the serving certificate manager: " + err.Error())
+	}
+
+	// the requests to the kubelet API are authenticated with the client CAs of the virtual API server
+	clientCAs, err := clientCAs(ctx, virtClient)
+	if err != nil {
+		return nil, errors.New("failed to get the client CAs of the virtual cluster: " + err.Error())
+	}
+
+	// the requests of the serving certificate are approved only if they are sent with the client certificate of the k3k-kubelet,
+	// otherwise they have to be approved manually
+	if username, err := certificateUser(rest.CopyConfig(virtConfig)); err != nil {
+		logger.Warnw("serving certificate requests will not be approved automatically", zap.Error(err))
+	} else {
+		logger.Info("adding serving certificate approver controller")
+
+		if err := csr.AddServingCertificateApprover(ctx, virtualMgr, c.AgentHostname, username); err != nil {
+			return nil, errors.New("failed to add the serving certificate approver: " + err.Error())
+		}
+	}
+
+	auditConfig := audit.Config{
+		LogPath:      c.AuditLogPath,
+		WebhookURL:   c.AuditWebhookURL,
+		RecordingDir: c.AuditRecordingDir,
+	}
+
+	auditor, err := audit.New(auditConfig, c.ClusterName, c.ClusterNamespace, c.AgentHostname, logger)
+	if err != nil {
+		return nil, errors.New("failed to create the auditor: " + err.Error())
 	}
 
 	return &kubelet{
 		virtualCluster: virtualCluster,
-
-		name:       c.AgentHostname,
-		hostConfig: hostConfig,
-		hostClient: hostClient,
-		virtConfig: virtConfig,
-		virtClient: virtClient,
-		hostMgr:    hostMgr,
-		virtualMgr: virtualMgr,
-		agentIP:    clusterIP,
-		logger:     logger.Named(k3kKubeletName),
-		token:      c.Token,
-		dnsIP:      dnsService.Spec.ClusterIP,
-		port:       c.KubeletPort,
+		config:         *c,
+
+		name:          c.AgentHostname,
+		hostConfig:    hostConfig,
+		hostClient:    hostClient,
+		hostClientset: hostClientset,
+		virtConfig:    virtConfig,
+		virtClient:    virtClient,
+		hostMgr:       hostMgr,
+		virtualMgr:    virtualMgr,
+		auditor:       auditor,
+		certManager:   certManager,
+		clientCAs:     clientCAs,
+		agentIP:       clusterIP,
+		logger:        logger.Named(k3kKubeletName),
+		dnsIP:         dnsService.Spec.ClusterIP,
+		port:          c.KubeletPort,
 	}, nil
 }
 
+// hostClientOptions returns the options of the client of the host manager. With the "PerNamespace" namespace mapping
+// the resources of the virtual cluster are in namespaces created at runtime, that can't be added to the cache: they
+// are read from the API server.
+func hostClientOptions(namespaceMapping v1alpha1.NamespaceMapping) ctrlruntimeclient.Options {
+	if namespaceMapping != v1alpha1.PerNamespaceMapping {
+		return ctrlruntimeclient.Options{}
+	}
+
+	return ctrlruntimeclient.Options{
+		Cache: &ctrlruntimeclient.CacheOptions{
+			DisableFor: []ctrlruntimeclient.Object{
+				&v1.Pod{},
+				&v1.Secret{},
+				&v1.ConfigMap{},
+				&v1.Service{},
+				&v1.PersistentVolumeClaim{},
+				&networkingv1.Ingress{},
+			},
+		},
+	}
+}
+
 func clusterIP(ctx context.Context, serviceName, clusterNamespace string, hostClient ctrlruntimeclient.Client) (string, error) {
 	var service v1.Service
 
@@ -211,9 +317,9 @@ func clusterIP(ctx context.Context, serviceName, clusterNamespace string, hostCl
 	return service.Spec.ClusterIP, nil
 }
 
-func (k *kubelet) registerNode(ctx context.Context, agentIP string, cfg config) error {
+func (k *kubelet) registerNode(cfg config) error {
 	providerFunc := k.newProviderFunc(cfg)
-	nodeOpts := k.nodeOpts(ctx, cfg.KubeletPort, cfg.ClusterNamespace, cfg.ClusterName, cfg.AgentHostname, agentIP)
+	nodeOpts := k.nodeOpts(cfg.KubeletPort)
 
 	var err error
 
@@ -228,7 +334,13 @@ func (k *kubelet) registerNode(ctx context.Context, agentIP string, cfg config)
 func (k *kubelet) start(ctx context.Context) {
 	// any one of the following 3 tasks (host manager, virtual manager, node) crashing will stop the
 	// program, and all 3 of them block on start, so we start them here in go-routines
+	var managers sync.WaitGroup
+
+	managers.Add(2)
+
 	go func() {
+		defer managers.Done()
+
 		err := k.hostMgr.Start(ctx)
 		if err != nil {
 			k.logger.Fatalw("host manager stopped", zap.Error(err))
@@ -236,20 +348,26 @@ func (k *kubelet) start(ctx context.Context) {
 	}()
 
 	go func() {
+		defer managers.Done()
+
 		err := k.virtualMgr.Start(ctx)
 		if err != nil {
 			k.logger.Fatalw("virtual manager stopped", zap.Error(err))
 		}
 	}()
 
-	// run the node async so that we can wait for it to be ready in another call
+	// the serving certificate is requested, and rotated, in the background
+	k.certManager.Start()
+	defer k.certManager.Stop()
 
-	go func() {
-		ctx = log.WithLogger(ctx, k.logger)
-		if err := k.node.Run(ctx); err != nil {
-			k.logger.Fatalw("node errored when running", zap.Error(err))
-		}
-	}()
+	k.mu.Lock()
+	cfg := k.config
+	k.mu.Unlock()
+
+	// run the node async so that we can wait for it to be ready in another call, the node is run
+	// only while holding its lease, and the nodes of the other replicas are taken over when they are down
+	go k.runNode(log.WithLogger(ctx, k.logger), cfg)
+	go k.runFailover(ctx, cfg)
 
 	if err := k.node.WaitReady(context.Background(), time.Minute*1); err != nil {
 		k.logger.Fatalw("node was not ready within timeout of 1 minute", zap.Error(err))
@@ -261,23 +379,39 @@ func (k *kubelet) start(ctx context.Context) {
 		k.logger.Fatalw("node stopped with an error", zap.Error(err))
 	}
 
+	// wait for the managers to stop, and to release the leader election leases
+	managers.Wait()
+
 	k.logger.Info("node exited successfully")
 }
 
 func (k *kubelet) newProviderFunc(cfg config) nodeutil.NewProviderFunc {
 	return func(pc nodeutil.ProviderConfig) (nodeutil.Provider, node.NodeProvider, error) {
-		utilProvider, err := provider.New(*k.hostConfig, k.hostMgr, k.virtualMgr, k.logger, cfg.ClusterNamespace, cfg.ClusterName, cfg.ServerIP, k.dnsIP)
+		utilProvider, nodeProvider, err := k.newProvider(cfg, pc, cfg.AgentHostname)
 		if err != nil {
-			return nil, nil, errors.New("unable to make nodeutil provider: " + err.Error())
+			return nil, nil, err
 		}
 
-		provider.ConfigureNode(k.logger, pc.Node, cfg.AgentHostname, k.port, k.agentIP, utilProvider.CoreClient, utilProvider.VirtualClient, k.virtualCluster, cfg.Version, cfg.MirrorHostNodes)
+		k.provider = utilProvider
+		k.nodeProvider = nodeProvider
 
-		return utilProvider, &provider.Node{}, nil
+		return utilProvider, nodeProvider, nil
 	}
 }
 
-func (k *kubelet) nodeOpts(ctx context.Context, srvPort int, namespace, name, hostname, agentIP string) nodeutil.NodeOpt {
+// newProvider returns the providers of a virtual node, run by this replica or taken over from another one
+func (k *kubelet) newProvider(cfg config, pc nodeutil.ProviderConfig, nodeName string) (*provider.Provider, *provider.Node, error) {
+	utilProvider, err := provider.New(*k.hostConfig, k.hostMgr, k.virtualMgr, k.auditor, k.logger, cfg.ClusterNamespace, cfg.ClusterName, k.virtualCluster.Spec.NamingStrategy, k.virtualCluster.Spec.NamespaceMapping, cfg.ServerIP, k.dnsIP)
+	if err != nil {
+		return nil, nil, errors.New("unable to make nodeutil provider: " + err.Error())
+	}
+
+	provider.ConfigureNode(k.logger, pc.Node, nodeName, k.port, k.agentIP, utilProvider.CoreClient, utilProvider.HostClient, utilProvider.VirtualClient, k.virtualCluster, cfg.Version, cfg.MirrorHostNodes)
+
+	return utilProvider, provider.NewNode(utilProvider, pc.Node, k.virtualCluster, cfg.MirrorHostNodes), nil
+}
+
+func (k *kubelet) nodeOpts(srvPort int) nodeutil.NodeOpt {
 	return func(c *nodeutil.NodeConfig) error {
 		c.HTTPListenAddr = fmt.Sprintf(":%d", srvPort)
 		// set up the routes
@@ -286,17 +420,53 @@ func (k *kubelet) nodeOpts(ctx context.Context, srvPort int, namespace, name, ho
 			return errors.New("unable to attach routes: " + err.Error())
 		}
 
-		c.Handler = mux
+		c.Handler = k.authenticatedHandler(mux, c.StreamIdleTimeout, c.StreamCreationTimeout)
+		c.TLSConfig = servingTLSConfig(k.certManager, k.clientCAs)
 
-		tlsConfig, err := loadTLSConfig(ctx, k.hostClient, name, namespace, k.name, hostname, k.token, agentIP)
-		if err != nil {
-			return errors.New("unable to get tls config: " + err.Error())
+		return nil
+	}
+}
+
+// authenticatedHandler serves the sessions of the pods with the caller authenticated by the client certificate of the
+// request in their context, for the audit. The handlers of the virtual-kubelet don't pass the context of the request
+// to the exec and port-forward sessions, so the sessions are served by a handler with the user of each request.
+func (k *kubelet) authenticatedHandler(next http.Handler, streamIdleTimeout, streamCreationTimeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if k.provider == nil || !sessionPath(req.URL.Path) {
+			next.ServeHTTP(w, req)
+			return
 		}
 
-		c.TLSConfig = tlsConfig
+		p, caller := k.provider, requestUser(req)
 
-		return nil
+		api.PodHandler(api.PodHandlerConfig{
+			RunInContainer: func(ctx context.Context, namespace, pod, container string, cmd []string, attach api.AttachIO) error {
+				return p.RunInContainer(audit.WithCaller(ctx, caller), namespace, pod, container, cmd, attach)
+			},
+			AttachToContainer: func(ctx context.Context, namespace, pod, container string, attach api.AttachIO) error {
+				return p.AttachToContainer(audit.WithCaller(ctx, caller), namespace, pod, container, attach)
+			},
+			GetContainerLogs: func(ctx context.Context, namespace, pod, container string, opts api.ContainerLogOpts) (io.ReadCloser, error) {
+				return p.GetContainerLogs(audit.WithCaller(ctx, caller), namespace, pod, container, opts)
+			},
+			PortForward: func(ctx context.Context, namespace, pod string, port int32, stream io.ReadWriteCloser) error {
+				return p.PortForward(audit.WithCaller(ctx, caller), namespace, pod, port, stream)
+			},
+			StreamIdleTimeout:     streamIdleTimeout,
+			StreamCreationTimeout: streamCreationTimeout,
+		}, false).ServeHTTP(w, req)
+	})
+}
+
+// sessionPath returns true if the path of the kubelet API is a session of a pod audited by the provider
+func sessionPath(path string) bool {
+	for _, prefix := range []string{"/exec/", "/attach/", "/containerLogs/", "/portForward/"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
 	}
+
+	return false
 }
 
 func virtRestConfig(ctx context.Context, virtualConfigPath string, hostClient ctrlruntimeclient.Client, clusterName, clusterNamespace, token string, logger *k3klog.Logger) (*rest.Config, error) {
@@ -369,65 +539,6 @@ func kubeconfigBytes(url string, serverCA, clientCert, clientKey []byte) ([]byte
 	return clientcmd.Write(*config)
 }
 
-func loadTLSConfig(ctx context.Context, hostClient ctrlruntimeclient.Client, clusterName, clusterNamespace, nodeName, hostname, token, agentIP string) (*tls.Config, error) {
-	var (
-		cluster v1alpha1.Cluster
-		b       *bootstrap.ControlRuntimeBootstrap
-	)
-
-	if err := hostClient.Get(ctx, types.NamespacedName{Name: clusterName, Namespace: clusterNamespace}, &cluster); err != nil {
-		return nil, err
-	}
-
-	endpoint := fmt.Sprintf("%s.%s", server.ServiceName(cluster.Name), cluster.Namespace)
-
-	if err := retry.OnError(controller.Backoff, func(err error) bool {
-		return err != nil
-	}, func() error {
-		var err error
-		b, err = bootstrap.DecodedBootstrap(token, endpoint)
-		return err
-	}); err != nil {
-		return nil, errors.New("unable to decode bootstrap: " + err.Error())
-	}
-	// POD IP
-	podIP := net.ParseIP(os.Getenv("POD_IP"))
-	ip := net.ParseIP(agentIP)
-
-	altNames := certutil.AltNames{
-		DNSNames: []string{hostname},
-		IPs:      []net.IP{ip, podIP},
-	}
-
-	cert, key, err := certs.CreateClientCertKey(nodeName, nil, &altNames, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, 0, b.ServerCA.Content, b.ServerCAKey.Content)
-	if err != nil {
-		return nil, errors.New("unable to get cert and key: " + err.Error())
-	}
-
-	clientCert, err := tls.X509KeyPair(cert, key)
-	if err != nil {
-		return nil, errors.New("unable to get key pair: " + err.Error())
-	}
-
-	// create rootCA CertPool
-	certs, err := certutil.ParseCertsPEM([]byte(b.ServerCA.Content))
-	if err != nil {
-		return nil, errors.New("unable to create ca certs: " + err.Error())
-	}
-
-	if len(certs) < 1 {
-		return nil, errors.New("ca cert is not parsed correctly")
-	}
-
-	pool := x509.NewCertPool()
-	pool.AddCert(certs[0])
-
-	return &tls.Config{
-		RootCAs:      pool,
-		Certificates: []tls.Certificate{clientCert},
-	}, nil
-}
-
 func addControllers(ctx context.Context, hostMgr, virtualMgr manager.Manager, c *config, hostClient ctrlruntimeclient.Client) error {
 	var cluster v1alpha1.Cluster
 
@@ -478,5 +589,11 @@ func addControllers(ctx context.Context, hostMgr, virtualMgr manager.Manager, c
 		return errors.New("failed to add priorityclass controller: " + err.Error())
 	}
 
+	logger.Info("adding projected token refresher controller")
+
+	if err := provider.AddProjectedTokenRefresher(ctx, hostMgr, virtualMgr, c.ClusterName, c.ClusterNamespace); err != nil {
+		return errors.New("failed to add projected token refresher controller: " + err.Error())
+	}
+
 	return nil
 }
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -14,6 +13,7 @@ import (
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
+	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlruntimelog "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/rancher/k3k/pkg/log"
@@ -23,7 +23,6 @@ var (
 	configFile string
 	cfg        config
 	logger     *log.Logger
-	debug      bool
 )
 
 func main() {
@@ -34,7 +33,7 @@ func main() {
 			if err := InitializeConfig(cmd); err != nil {
 				return err
 			}
-			logger = log.New(debug)
+			logger = log.New(cfg.Debug)
 			ctrlruntimelog.SetLogger(zapr.NewLogger(logger.Desugar().WithOptions(zap.AddCallerSkip(1))))
 			return nil
 		},
@@ -48,13 +47,17 @@ func main() {
 	rootCmd.PersistentFlags().StringVar(&cfg.VirtKubeconfig, "virt-kubeconfig", "", "Path to the k3k cluster kubeconfig, if empty then virtual-kubelet will create its own config from k3k cluster")
 	rootCmd.PersistentFlags().IntVar(&cfg.KubeletPort, "kubelet-port", 0, "kubelet API port number")
 	rootCmd.PersistentFlags().IntVar(&cfg.WebhookPort, "webhook-port", 0, "Webhook port number")
+	rootCmd.PersistentFlags().IntVar(&cfg.MetricsPort, "metrics-port", 0, "Port number of the metrics endpoint, 0 disables the metrics")
 	rootCmd.PersistentFlags().StringVar(&cfg.ServiceName, "service-name", "", "The service name deployed by the k3k controller")
 	rootCmd.PersistentFlags().StringVar(&cfg.AgentHostname, "agent-hostname", "", "Agent Hostname used for TLS SAN for the kubelet server")
 	rootCmd.PersistentFlags().StringVar(&cfg.ServerIP, "server-ip", "", "Server IP used for registering the virtual kubelet to the cluster")
 	rootCmd.PersistentFlags().StringVar(&cfg.Version, "version", "", "Version of kubernetes server")
 	rootCmd.PersistentFlags().StringVar(&configFile, "config", "/opt/rancher/k3k/config.yaml", "Path to k3k-kubelet config file")
-	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug logging")
+	rootCmd.PersistentFlags().BoolVar(&cfg.Debug, "debug", false, "Enable debug logging")
 	rootCmd.PersistentFlags().BoolVar(&cfg.MirrorHostNodes, "mirror-host-nodes", false, "Mirror real node objects from host cluster")
+	rootCmd.PersistentFlags().StringVar(&cfg.AuditLogPath, "audit-log-path", "", "Path of the file where the exec, attach, port-forward and logs requests are audited, '-' means standard out")
+	rootCmd.PersistentFlags().StringVar(&cfg.AuditWebhookURL, "audit-webhook-url", "", "URL of an HTTP endpoint receiving the audit records as JSON")
+	rootCmd.PersistentFlags().StringVar(&cfg.AuditRecordingDir, "audit-recording-dir", "", "Directory where the exec and attach sessions are recorded, in the asciicast format")
 
 	if err := rootCmd.Execute(); err != nil {
 		logrus.Fatal(err)
@@ -62,7 +65,8 @@ func main() {
 }
 
 func run(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	// the context is cancelled on SIGTERM, so the leader election leases are released on shutdown
+	ctx := ctrl.SetupSignalHandler()
 
 	if err := cfg.validate(); err != nil {
 		return fmt.Errorf("failed to validate config: %w", err)
@@ -73,10 +77,17 @@ func run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create new virtual kubelet instance: %w", err)
 	}
 
-	if err := k.registerNode(ctx, k.agentIP, cfg); err != nil {
+	if err := k.registerNode(cfg); err != nil {
 		return fmt.Errorf("failed to register new node: %w", err)
 	}
 
+	if err := k.addLogLevelController(); err != nil {
+		return fmt.Errorf("failed to add the log level controller: %w", err)
+	}
+
+	// the config file is watched after the node is registered, so that the version of the node can be updated
+	k.watchConfig()
+
 	k.start(ctx)
 
 	return nil
@@ -120,8 +131,6 @@ func InitializeConfig(cmd *cobra.Command) error {
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
-	// Separately get the debug flag, as it's not part of the main config struct.
-	debug = viper.GetBool("debug")
 
 	return nil
 }
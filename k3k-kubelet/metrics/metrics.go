@@ -0,0 +1,141 @@
+// Package metrics defines the Prometheus metrics of the k3k-kubelet. The metrics are registered in the
+// controller-runtime registry, and served with the metrics of the controllers (i.e. the reconciles of the syncers)
+// and of the webhooks.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/rest"
+
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "k3k_kubelet"
+
+// The operations of the provider on the pods
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+// The clusters the k3k-kubelet sends requests to
+const (
+	ClusterHost    = "host"
+	ClusterVirtual = "virtual"
+)
+
+var (
+	// PodOperationDuration is the duration of the operations of the provider on the pods, retries included
+	PodOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "pod_operation_duration_seconds",
+		Help:      "Duration of the create, update and delete operations of the pods, retries included",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"operation"})
+
+	// PodOperationErrors is the number of failed operations of the provider on the pods
+	PodOperationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pod_operation_errors_total",
+		Help:      "Total number of failed create, update and delete operations of the pods",
+	}, []string{"operation"})
+
+	// PodOperationRetries is the number of failed attempts retried by the provider
+	PodOperationRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pod_operation_retries_total",
+		Help:      "Total number of failed attempts of the operations of the pods",
+	}, []string{"operation"})
+
+	// PodOperationTimeouts is the number of operations of the provider that timed out while retrying
+	PodOperationTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pod_operation_timeouts_total",
+		Help:      "Total number of operations of the pods that timed out while retrying",
+	}, []string{"operation"})
+
+	// WebhookPodMutations is the number of pods processed by the mutator webhook, by result (mutated or unchanged)
+	WebhookPodMutations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_pod_mutations_total",
+		Help:      "Total number of pods processed by the mutator webhook, by result",
+	}, []string{"result"})
+
+	// WebhookPodDenials is the number of pods denied by the validator webhook, by reason
+	WebhookPodDenials = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_pod_denials_total",
+		Help:      "Total number of pods denied by the validator webhook, by reason",
+	}, []string{"reason"})
+
+	// APIRequests is the number of requests sent to the API servers of the host and of the virtual cluster
+	APIRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "Total number of requests sent to the API server of the host and of the virtual cluster",
+	}, []string{"cluster", "method", "code"})
+
+	// APIRequestDuration is the duration of the requests sent to the API servers of the host and of the virtual cluster
+	APIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Duration of the requests sent to the API server of the host and of the virtual cluster",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"cluster", "method"})
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(
+		PodOperationDuration,
+		PodOperationErrors,
+		PodOperationRetries,
+		PodOperationTimeouts,
+		WebhookPodMutations,
+		WebhookPodDenials,
+		APIRequests,
+		APIRequestDuration,
+	)
+}
+
+// ObservePodOperation records the duration of an operation on a pod, and its failure
+func ObservePodOperation(operation string, start time.Time, err error) {
+	PodOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		PodOperationErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// InstrumentConfig wraps the transport of the config to record the requests sent to the API server of the cluster
+func InstrumentConfig(config *rest.Config, cluster string) {
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &instrumentedRoundTripper{cluster: cluster, next: rt}
+	})
+}
+
+type instrumentedRoundTripper struct {
+	cluster string
+	next    http.RoundTripper
+}
+
+func (r *instrumentedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := r.next.RoundTrip(req)
+
+	APIRequestDuration.WithLabelValues(r.cluster, req.Method).Observe(time.Since(start).Seconds())
+
+	// the requests without a response are counted with the "<error>" code, as the client-go metrics
+	code := "<error>"
+	if resp != nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+
+	APIRequests.WithLabelValues(r.cluster, req.Method, code).Inc()
+
+	return resp, err
+}
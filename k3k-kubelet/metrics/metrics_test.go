@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/rest"
+)
+
+func Test_InstrumentConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	config := &rest.Config{Host: server.URL}
+	InstrumentConfig(config, ClusterVirtual)
+
+	client, err := rest.HTTPClientFor(config)
+	assert.NoError(t, err)
+
+	before := testutil.ToFloat64(APIRequests.WithLabelValues(ClusterVirtual, http.MethodGet, "404"))
+
+	resp, err := client.Get(server.URL)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	resp.Body.Close()
+
+	after := testutil.ToFloat64(APIRequests.WithLabelValues(ClusterVirtual, http.MethodGet, "404"))
+	assert.Equal(t, before+1, after)
+	assert.Zero(t, testutil.ToFloat64(APIRequests.WithLabelValues(ClusterHost, http.MethodGet, "404")))
+}
+
+func Test_ObservePodOperation(t *testing.T) {
+	ObservePodOperation(OperationUpdate, time.Now(), nil)
+	assert.Zero(t, testutil.ToFloat64(PodOperationErrors.WithLabelValues(OperationUpdate)))
+
+	ObservePodOperation(OperationUpdate, time.Now(), errors.New("update failed"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(PodOperationErrors.WithLabelValues(OperationUpdate)))
+}
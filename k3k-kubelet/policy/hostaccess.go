@@ -0,0 +1,75 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+// HostAccessViolations returns the list of the host-level features used by the pod
+// that are not allowed for the cluster by the HostAccessConfig.
+func HostAccessViolations(pod *v1.Pod, hostAccess *v1alpha1.HostAccessConfig, clusterName string) []string {
+	if hostAccess == nil {
+		return nil
+	}
+
+	var violations []string
+
+	if pod.Spec.HostNetwork && !isAllowed(hostAccess.HostNetwork, clusterName) {
+		violations = append(violations, "hostNetwork is not allowed")
+	}
+
+	if pod.Spec.HostPID && !isAllowed(hostAccess.HostPID, clusterName) {
+		violations = append(violations, "hostPID is not allowed")
+	}
+
+	if !isAllowed(hostAccess.HostPath, clusterName) {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.HostPath != nil {
+				violations = append(violations, fmt.Sprintf("hostPath volume %q is not allowed", volume.Name))
+			}
+		}
+	}
+
+	checkPrivileged := !isAllowed(hostAccess.Privileged, clusterName)
+	checkHostPort := !isAllowed(hostAccess.HostPort, clusterName)
+
+	forEachContainer(pod, func(name string, securityContext *v1.SecurityContext, ports []v1.ContainerPort) {
+		if checkPrivileged && securityContext != nil && securityContext.Privileged != nil && *securityContext.Privileged {
+			violations = append(violations, fmt.Sprintf("privileged container %q is not allowed", name))
+		}
+
+		if checkHostPort {
+			for _, port := range ports {
+				if port.HostPort != 0 {
+					violations = append(violations, fmt.Sprintf("hostPort %d of container %q is not allowed", port.HostPort, name))
+				}
+			}
+		}
+	})
+
+	return violations
+}
+
+// isAllowed returns true if the cluster is in the allow-list of the rule
+func isAllowed(rule v1alpha1.HostAccessRule, clusterName string) bool {
+	return slices.Contains(rule.AllowedClusters, AllClusters) || slices.Contains(rule.AllowedClusters, clusterName)
+}
+
+// forEachContainer calls fn for all the init, regular and ephemeral containers of the pod
+func forEachContainer(pod *v1.Pod, fn func(name string, securityContext *v1.SecurityContext, ports []v1.ContainerPort)) {
+	for _, container := range pod.Spec.InitContainers {
+		fn(container.Name, container.SecurityContext, container.Ports)
+	}
+
+	for _, container := range pod.Spec.Containers {
+		fn(container.Name, container.SecurityContext, container.Ports)
+	}
+
+	for _, container := range pod.Spec.EphemeralContainers {
+		fn(container.Name, container.SecurityContext, container.Ports)
+	}
+}
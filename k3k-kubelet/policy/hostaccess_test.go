@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+func Test_HostAccessViolations(t *testing.T) {
+	unsafePod := &v1.Pod{
+		Spec: v1.PodSpec{
+			HostNetwork: true,
+			HostPID:     true,
+			Volumes: []v1.Volume{
+				{
+					Name: "host",
+					VolumeSource: v1.VolumeSource{
+						HostPath: &v1.HostPathVolumeSource{Path: "/"},
+					},
+				},
+				{
+					Name: "empty",
+					VolumeSource: v1.VolumeSource{
+						EmptyDir: &v1.EmptyDirVolumeSource{},
+					},
+				},
+			},
+			InitContainers: []v1.Container{
+				{
+					Name: "init",
+					SecurityContext: &v1.SecurityContext{
+						Privileged: ptr.To(true),
+					},
+				},
+			},
+			Containers: []v1.Container{
+				{
+					Name: "app",
+					Ports: []v1.ContainerPort{
+						{ContainerPort: 80, HostPort: 8080},
+						{ContainerPort: 443},
+					},
+				},
+			},
+		},
+	}
+
+	allViolations := []string{
+		"hostNetwork is not allowed",
+		"hostPID is not allowed",
+		`hostPath volume "host" is not allowed`,
+		`privileged container "init" is not allowed`,
+		`hostPort 8080 of container "app" is not allowed`,
+	}
+
+	tests := []struct {
+		name               string
+		pod                *v1.Pod
+		hostAccess         *v1alpha1.HostAccessConfig
+		expectedViolations []string
+	}{
+		{
+			name:       "no host access config",
+			pod:        unsafePod,
+			hostAccess: nil,
+		},
+		{
+			name:       "pod without host features",
+			pod:        &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}}},
+			hostAccess: &v1alpha1.HostAccessConfig{},
+		},
+		{
+			name:               "nothing allowed",
+			pod:                unsafePod,
+			hostAccess:         &v1alpha1.HostAccessConfig{},
+			expectedViolations: allViolations,
+		},
+		{
+			name: "allowed for other clusters",
+			pod:  unsafePod,
+			hostAccess: &v1alpha1.HostAccessConfig{
+				HostNetwork: v1alpha1.HostAccessRule{AllowedClusters: []string{"other"}},
+				Privileged:  v1alpha1.HostAccessRule{AllowedClusters: []string{"other"}},
+			},
+			expectedViolations: allViolations,
+		},
+		{
+			name: "some features allowed for the cluster",
+			pod:  unsafePod,
+			hostAccess: &v1alpha1.HostAccessConfig{
+				HostNetwork: v1alpha1.HostAccessRule{AllowedClusters: []string{"mycluster"}},
+				HostPort:    v1alpha1.HostAccessRule{AllowedClusters: []string{"other", "mycluster"}},
+			},
+			expectedViolations: []string{
+				"hostPID is not allowed",
+				`hostPath volume "host" is not allowed`,
+				`privileged container "init" is not allowed`,
+			},
+		},
+		{
+			name: "all features allowed with wildcard",
+			pod:  unsafePod,
+			hostAccess: &v1alpha1.HostAccessConfig{
+				HostNetwork: v1alpha1.HostAccessRule{AllowedClusters: []string{"*"}},
+				HostPID:     v1alpha1.HostAccessRule{AllowedClusters: []string{"*"}},
+				HostPath:    v1alpha1.HostAccessRule{AllowedClusters: []string{"*"}},
+				Privileged:  v1alpha1.HostAccessRule{AllowedClusters: []string{"*"}},
+				HostPort:    v1alpha1.HostAccessRule{AllowedClusters: []string{"*"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := HostAccessViolations(tt.pod, tt.hostAccess, "mycluster")
+			assert.Equal(t, tt.expectedViolations, violations)
+		})
+	}
+}
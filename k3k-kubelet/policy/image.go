@@ -0,0 +1,153 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+const (
+	defaultRegistry   = "docker.io"
+	defaultRepository = "library"
+	wildcard          = "*"
+)
+
+// ResolveImage applies the rewrite rules of the ImagePolicy to the image, and returns the resulting image.
+// It returns an error if the resulting image is not from one of the allowed registries.
+func ResolveImage(imagePolicy *v1alpha1.ImagePolicy, image string) (string, error) {
+	if imagePolicy == nil {
+		return image, nil
+	}
+
+	normalizedImage := normalizeImage(image)
+
+	for _, rule := range imagePolicy.RewriteRules {
+		if rewritten, matched := rewriteImage(rule, normalizedImage); matched {
+			image = rewritten
+			normalizedImage = normalizeImage(rewritten)
+
+			break
+		}
+	}
+
+	if len(imagePolicy.AllowedRegistries) == 0 {
+		return image, nil
+	}
+
+	for _, registry := range imagePolicy.AllowedRegistries {
+		registry = strings.TrimSuffix(registry, "/")
+
+		if normalizedImage == registry || strings.HasPrefix(normalizedImage, registry+"/") {
+			return image, nil
+		}
+	}
+
+	return "", fmt.Errorf("image %q is not from an allowed registry", image)
+}
+
+// ApplyImagePolicy rewrites the images of all the containers of the pod, and enforces the imagePullPolicy.
+// It returns an error listing all the containers with an image that is not allowed.
+func ApplyImagePolicy(imagePolicy *v1alpha1.ImagePolicy, pod *v1.Pod) error {
+	if imagePolicy == nil {
+		return nil
+	}
+
+	var errs []error
+
+	applyToContainers := func(containers []v1.Container) {
+		for i := range containers {
+			image, err := ResolveImage(imagePolicy, containers[i].Image)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("container %q: %w", containers[i].Name, err))
+				continue
+			}
+
+			containers[i].Image = image
+
+			if imagePolicy.PullPolicy != "" {
+				containers[i].ImagePullPolicy = imagePolicy.PullPolicy
+			}
+		}
+	}
+
+	applyToContainers(pod.Spec.InitContainers)
+	applyToContainers(pod.Spec.Containers)
+
+	for i := range pod.Spec.EphemeralContainers {
+		container := &pod.Spec.EphemeralContainers[i]
+
+		image, err := ResolveImage(imagePolicy, container.Image)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ephemeral container %q: %w", container.Name, err))
+			continue
+		}
+
+		container.Image = image
+
+		if imagePolicy.PullPolicy != "" {
+			container.ImagePullPolicy = imagePolicy.PullPolicy
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// rewriteImage applies the rewrite rule to the normalized image, returning the rewritten image and true if the rule matched.
+func rewriteImage(rule v1alpha1.ImageRewriteRule, normalizedImage string) (string, bool) {
+	from := rule.From
+
+	if !strings.HasSuffix(from, wildcard) {
+		if normalizeImage(from) != normalizedImage {
+			return "", false
+		}
+
+		return rule.To, true
+	}
+
+	prefix := strings.TrimSuffix(from, wildcard)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix = normalizeImage(prefix)
+	}
+
+	if !strings.HasPrefix(normalizedImage, prefix) {
+		return "", false
+	}
+
+	to, remainder := strings.TrimSuffix(rule.To, wildcard), strings.TrimPrefix(normalizedImage, prefix)
+
+	// the remainder continues the last component of the prefix (e.g. a tag), or is a path joined with a single "/"
+	if prefix != "" && !strings.HasSuffix(prefix, "/") && !strings.HasPrefix(remainder, "/") {
+		return to + remainder, true
+	}
+
+	return strings.TrimSuffix(to, "/") + "/" + strings.TrimPrefix(remainder, "/"), true
+}
+
+// normalizeImage returns the fully qualified reference of the image, adding the default registry
+// and repository for the images of the Docker Hub (e.g. "nginx" -> "docker.io/library/nginx").
+func normalizeImage(image string) string {
+	domain, remainder, found := strings.Cut(image, "/")
+
+	// the first component is a registry only if it looks like a hostname
+	if found && (strings.ContainsAny(domain, ".:") || domain == "localhost") {
+		if domain == "index.docker.io" {
+			domain = defaultRegistry
+		}
+
+		if domain == defaultRegistry && !strings.Contains(remainder, "/") {
+			remainder = defaultRepository + "/" + remainder
+		}
+
+		return domain + "/" + remainder
+	}
+
+	if !found {
+		return defaultRegistry + "/" + defaultRepository + "/" + image
+	}
+
+	return defaultRegistry + "/" + image
+}
@@ -0,0 +1,191 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+func Test_normalizeImage(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "nginx", want: "docker.io/library/nginx"},
+		{image: "nginx:1.27", want: "docker.io/library/nginx:1.27"},
+		{image: "rancher/k3s:v1.31.3-k3s1", want: "docker.io/rancher/k3s:v1.31.3-k3s1"},
+		{image: "docker.io/nginx", want: "docker.io/library/nginx"},
+		{image: "index.docker.io/rancher/k3s", want: "docker.io/rancher/k3s"},
+		{image: "quay.io/prometheus/prometheus", want: "quay.io/prometheus/prometheus"},
+		{image: "localhost/app", want: "localhost/app"},
+		{image: "mirror.corp:5000/app@sha256:abcd", want: "mirror.corp:5000/app@sha256:abcd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeImage(tt.image))
+		})
+	}
+}
+
+func Test_ResolveImage(t *testing.T) {
+	imagePolicy := &v1alpha1.ImagePolicy{
+		AllowedRegistries: []string{"mirror.corp", "quay.io/prometheus/"},
+		RewriteRules: []v1alpha1.ImageRewriteRule{
+			{From: "busybox:latest", To: "mirror.corp/tools/busybox:1.36"},
+			{From: "docker.io/*", To: "mirror.corp/*"},
+			{From: "ghcr.io/org*", To: "mirror.corp/ghcr/org*"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		imagePolicy *v1alpha1.ImagePolicy
+		image       string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "no policy",
+			imagePolicy: nil,
+			image:       "nginx",
+			want:        "nginx",
+		},
+		{
+			name:        "exact rewrite",
+			imagePolicy: imagePolicy,
+			image:       "busybox:latest",
+			want:        "mirror.corp/tools/busybox:1.36",
+		},
+		{
+			name:        "prefix rewrite of short image",
+			imagePolicy: imagePolicy,
+			image:       "nginx:1.27",
+			want:        "mirror.corp/library/nginx:1.27",
+		},
+		{
+			name:        "prefix rewrite without trailing slash",
+			imagePolicy: imagePolicy,
+			image:       "ghcr.io/org/app:v1",
+			want:        "mirror.corp/ghcr/org/app:v1",
+		},
+		{
+			name:        "allowed image prefix",
+			imagePolicy: imagePolicy,
+			image:       "quay.io/prometheus/prometheus:v3",
+			want:        "quay.io/prometheus/prometheus:v3",
+		},
+		{
+			name:        "allowed registry",
+			imagePolicy: imagePolicy,
+			image:       "mirror.corp/app",
+			want:        "mirror.corp/app",
+		},
+		{
+			name:        "registry with the same prefix",
+			imagePolicy: imagePolicy,
+			image:       "mirror.corporate.com/app",
+			wantErr:     true,
+		},
+		{
+			name:        "not allowed registry",
+			imagePolicy: imagePolicy,
+			image:       "quay.io/other/app",
+			wantErr:     true,
+		},
+		{
+			name: "rewrite without allow-list",
+			imagePolicy: &v1alpha1.ImagePolicy{
+				RewriteRules: []v1alpha1.ImageRewriteRule{{From: "*", To: "mirror.corp/*"}},
+			},
+			image: "quay.io/other/app",
+			want:  "mirror.corp/quay.io/other/app",
+		},
+		{
+			name: "prefix rewrite to a registry without wildcard or slash",
+			imagePolicy: &v1alpha1.ImagePolicy{
+				RewriteRules: []v1alpha1.ImageRewriteRule{{From: "docker.io/*", To: "mirror.corp"}},
+			},
+			image: "nginx:1.27",
+			want:  "mirror.corp/library/nginx:1.27",
+		},
+		{
+			name: "prefix rewrite of a repository without wildcard",
+			imagePolicy: &v1alpha1.ImagePolicy{
+				RewriteRules: []v1alpha1.ImageRewriteRule{{From: "ghcr.io/org*", To: "mirror.corp/ghcr/org"}},
+			},
+			image: "ghcr.io/org/app:v1",
+			want:  "mirror.corp/ghcr/org/app:v1",
+		},
+		{
+			name: "prefix rewrite of the tags of an image",
+			imagePolicy: &v1alpha1.ImagePolicy{
+				RewriteRules: []v1alpha1.ImageRewriteRule{{From: "nginx*", To: "mirror.corp/nginx*"}},
+			},
+			image: "nginx:1.27",
+			want:  "mirror.corp/nginx:1.27",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveImage(tt.imagePolicy, tt.image)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_ApplyImagePolicy(t *testing.T) {
+	imagePolicy := &v1alpha1.ImagePolicy{
+		AllowedRegistries: []string{"mirror.corp"},
+		RewriteRules: []v1alpha1.ImageRewriteRule{
+			{From: "docker.io/*", To: "mirror.corp/*"},
+		},
+		PullPolicy: v1.PullAlways,
+	}
+
+	t.Run("allowed images", func(t *testing.T) {
+		pod := &v1.Pod{
+			Spec: v1.PodSpec{
+				InitContainers: []v1.Container{{Name: "init", Image: "busybox"}},
+				Containers:     []v1.Container{{Name: "app", Image: "mirror.corp/app", ImagePullPolicy: v1.PullIfNotPresent}},
+			},
+		}
+
+		err := ApplyImagePolicy(imagePolicy, pod)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "mirror.corp/library/busybox", pod.Spec.InitContainers[0].Image)
+		assert.Equal(t, v1.PullAlways, pod.Spec.InitContainers[0].ImagePullPolicy)
+		assert.Equal(t, "mirror.corp/app", pod.Spec.Containers[0].Image)
+		assert.Equal(t, v1.PullAlways, pod.Spec.Containers[0].ImagePullPolicy)
+	})
+
+	t.Run("not allowed images", func(t *testing.T) {
+		pod := &v1.Pod{
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{
+					{Name: "app", Image: "nginx"},
+					{Name: "sidecar", Image: "quay.io/org/sidecar"},
+				},
+				EphemeralContainers: []v1.EphemeralContainer{
+					{EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debug", Image: "ghcr.io/org/debug"}},
+				},
+			},
+		}
+
+		err := ApplyImagePolicy(imagePolicy, pod)
+		assert.EqualError(t, err, `container "sidecar": image "quay.io/org/sidecar" is not from an allowed registry`+"\n"+
+			`ephemeral container "debug": image "ghcr.io/org/debug" is not from an allowed registry`)
+	})
+}
@@ -0,0 +1,32 @@
+package policy
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+// AllClusters is the wildcard value used in the allow-lists to match all the clusters of a policy.
+const AllClusters = "*"
+
+// Get returns the VirtualClusterPolicy bound to the Cluster.
+// It returns nil if the Cluster is not bound to any policy, or if the policy does not exist anymore.
+func Get(ctx context.Context, client ctrlruntimeclient.Client, cluster *v1alpha1.Cluster) (*v1alpha1.VirtualClusterPolicy, error) {
+	if cluster.Status.PolicyName == "" {
+		return nil, nil
+	}
+
+	var policy v1alpha1.VirtualClusterPolicy
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Name: cluster.Status.PolicyName}, &policy); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &policy, nil
+}
@@ -0,0 +1,28 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+// HostServiceAccountAnnotation is the annotation of a virtual ServiceAccount mapping it to a ServiceAccount of the host cluster
+const HostServiceAccountAnnotation = "k3k.io/host-service-account"
+
+// HostServiceAccount returns the host ServiceAccount the virtual ServiceAccount is mapped to, or an empty string if it is not mapped.
+// It returns an error if the mapping is not allowed by the VirtualClusterPolicy.
+func HostServiceAccount(vcp *v1alpha1.VirtualClusterPolicy, serviceAccount *v1.ServiceAccount) (string, error) {
+	hostServiceAccount := serviceAccount.Annotations[HostServiceAccountAnnotation]
+	if hostServiceAccount == "" {
+		return "", nil
+	}
+
+	if vcp == nil || !slices.Contains(vcp.Spec.AllowedHostServiceAccounts, hostServiceAccount) {
+		return "", fmt.Errorf("host ServiceAccount %q is not allowed", hostServiceAccount)
+	}
+
+	return hostServiceAccount, nil
+}
@@ -0,0 +1,72 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+func Test_HostServiceAccount(t *testing.T) {
+	vcp := &v1alpha1.VirtualClusterPolicy{
+		Spec: v1alpha1.VirtualClusterPolicySpec{
+			AllowedHostServiceAccounts: []string{"workload-identity"},
+		},
+	}
+
+	serviceAccount := func(annotations map[string]string) *v1.ServiceAccount {
+		return &v1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "default", Annotations: annotations},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		vcp            *v1alpha1.VirtualClusterPolicy
+		serviceAccount *v1.ServiceAccount
+		want           string
+		wantErr        bool
+	}{
+		{
+			name:           "not mapped",
+			vcp:            vcp,
+			serviceAccount: serviceAccount(nil),
+			want:           "",
+		},
+		{
+			name:           "allowed mapping",
+			vcp:            vcp,
+			serviceAccount: serviceAccount(map[string]string{HostServiceAccountAnnotation: "workload-identity"}),
+			want:           "workload-identity",
+		},
+		{
+			name:           "not allowed mapping",
+			vcp:            vcp,
+			serviceAccount: serviceAccount(map[string]string{HostServiceAccountAnnotation: "admin"}),
+			wantErr:        true,
+		},
+		{
+			name:           "mapping without policy",
+			vcp:            nil,
+			serviceAccount: serviceAccount(map[string]string{HostServiceAccountAnnotation: "workload-identity"}),
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HostServiceAccount(tt.vcp, tt.serviceAccount)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
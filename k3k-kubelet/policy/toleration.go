@@ -0,0 +1,48 @@
+package policy
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// FilterTolerations returns the tolerations matching one of the allowed tolerations, and the ones not allowed.
+// All the tolerations are allowed if the allow-list is empty.
+func FilterTolerations(allowedTolerations, tolerations []v1.Toleration) (allowed, denied []v1.Toleration) {
+	if len(allowedTolerations) == 0 {
+		return tolerations, nil
+	}
+
+	for _, toleration := range tolerations {
+		if isTolerationAllowed(allowedTolerations, toleration) {
+			allowed = append(allowed, toleration)
+		} else {
+			denied = append(denied, toleration)
+		}
+	}
+
+	return allowed, denied
+}
+
+// isTolerationAllowed checks if the toleration doesn't tolerate more than one of the allowed tolerations
+func isTolerationAllowed(allowedTolerations []v1.Toleration, toleration v1.Toleration) bool {
+	for _, allowedToleration := range allowedTolerations {
+		allowsAllKeys := allowedToleration.Key == "" && allowedToleration.Operator == v1.TolerationOpExists
+		if !allowsAllKeys && allowedToleration.Key != toleration.Key {
+			continue
+		}
+
+		if allowedToleration.Effect != "" && allowedToleration.Effect != toleration.Effect {
+			continue
+		}
+
+		if allowedToleration.Operator == v1.TolerationOpExists {
+			return true
+		}
+
+		// with the "Equal" operator only the same value is allowed
+		if toleration.Operator != v1.TolerationOpExists && toleration.Value == allowedToleration.Value {
+			return true
+		}
+	}
+
+	return false
+}
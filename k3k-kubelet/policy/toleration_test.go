@@ -0,0 +1,92 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func Test_FilterTolerations(t *testing.T) {
+	allowedTolerations := []v1.Toleration{
+		{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "tenant-a", Effect: v1.TaintEffectNoSchedule},
+		{Key: "gpu", Operator: v1.TolerationOpExists},
+	}
+
+	tests := []struct {
+		name               string
+		allowedTolerations []v1.Toleration
+		tolerations        []v1.Toleration
+		wantAllowed        []v1.Toleration
+		wantDenied         []v1.Toleration
+	}{
+		{
+			name:               "no allow-list",
+			allowedTolerations: nil,
+			tolerations:        []v1.Toleration{{Operator: v1.TolerationOpExists}},
+			wantAllowed:        []v1.Toleration{{Operator: v1.TolerationOpExists}},
+		},
+		{
+			name:               "same value and effect",
+			allowedTolerations: allowedTolerations,
+			tolerations:        []v1.Toleration{{Key: "dedicated", Value: "tenant-a", Effect: v1.TaintEffectNoSchedule}},
+			wantAllowed:        []v1.Toleration{{Key: "dedicated", Value: "tenant-a", Effect: v1.TaintEffectNoSchedule}},
+		},
+		{
+			name:               "different value",
+			allowedTolerations: allowedTolerations,
+			tolerations:        []v1.Toleration{{Key: "dedicated", Value: "tenant-b", Effect: v1.TaintEffectNoSchedule}},
+			wantDenied:         []v1.Toleration{{Key: "dedicated", Value: "tenant-b", Effect: v1.TaintEffectNoSchedule}},
+		},
+		{
+			name:               "all values of an equal toleration",
+			allowedTolerations: allowedTolerations,
+			tolerations:        []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}},
+			wantDenied:         []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}},
+		},
+		{
+			name:               "all effects of a toleration with an effect",
+			allowedTolerations: allowedTolerations,
+			tolerations:        []v1.Toleration{{Key: "dedicated", Value: "tenant-a"}},
+			wantDenied:         []v1.Toleration{{Key: "dedicated", Value: "tenant-a"}},
+		},
+		{
+			name:               "exists toleration",
+			allowedTolerations: allowedTolerations,
+			tolerations: []v1.Toleration{
+				{Key: "gpu", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute},
+				{Key: "gpu", Value: "nvidia"},
+			},
+			wantAllowed: []v1.Toleration{
+				{Key: "gpu", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute},
+				{Key: "gpu", Value: "nvidia"},
+			},
+		},
+		{
+			name:               "all the taints",
+			allowedTolerations: allowedTolerations,
+			tolerations:        []v1.Toleration{{Operator: v1.TolerationOpExists}},
+			wantDenied:         []v1.Toleration{{Operator: v1.TolerationOpExists}},
+		},
+		{
+			name:               "allow-list of all the taints",
+			allowedTolerations: []v1.Toleration{{Operator: v1.TolerationOpExists, Effect: v1.TaintEffectPreferNoSchedule}},
+			tolerations: []v1.Toleration{
+				{Key: "spot", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectPreferNoSchedule},
+				{Key: "spot", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+			},
+			wantAllowed: []v1.Toleration{{Key: "spot", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectPreferNoSchedule}},
+			wantDenied:  []v1.Toleration{{Key: "spot", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, denied := FilterTolerations(tt.allowedTolerations, tt.tolerations)
+
+			assert.Equal(t, tt.wantAllowed, allowed)
+			assert.Equal(t, tt.wantDenied, denied)
+		})
+	}
+}
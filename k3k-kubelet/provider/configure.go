@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"maps"
 	"time"
 
 	"k8s.io/apimachinery/pkg/labels"
@@ -9,6 +10,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
@@ -16,7 +18,7 @@ import (
 	k3klog "github.com/rancher/k3k/pkg/log"
 )
 
-func ConfigureNode(logger *k3klog.Logger, node *corev1.Node, hostname string, servicePort int, ip string, coreClient typedv1.CoreV1Interface, virtualClient client.Client, virtualCluster v1alpha1.Cluster, version string, mirrorHostNodes bool) {
+func ConfigureNode(logger *k3klog.Logger, node *corev1.Node, hostname string, servicePort int, ip string, coreClient typedv1.CoreV1Interface, hostClient, virtualClient client.Client, virtualCluster v1alpha1.Cluster, version string, mirrorHostNodes bool) {
 	ctx := context.Background()
 	if mirrorHostNodes {
 		hostNode, err := coreClient.Nodes().Get(ctx, node.Name, metav1.GetOptions{})
@@ -30,6 +32,11 @@ func ConfigureNode(logger *k3klog.Logger, node *corev1.Node, hostname string, se
 		node.Annotations = hostNode.GetAnnotations()
 		node.Finalizers = hostNode.GetFinalizers()
 		node.Status.DaemonEndpoints.KubeletEndpoint.Port = int32(servicePort)
+
+		node.Spec.Taints = nil
+		if _, err := setNodeTaints(node, hostNodeTaints(hostNode)); err != nil {
+			logger.Error("error setting node taints", err)
+		}
 	} else {
 		node.Status.Conditions = nodeConditions()
 		node.Status.DaemonEndpoints.KubeletEndpoint.Port = int32(servicePort)
@@ -50,14 +57,24 @@ func ConfigureNode(logger *k3klog.Logger, node *corev1.Node, hostname string, se
 		// configure versions
 		node.Status.NodeInfo.KubeletVersion = version
 
+		if _, err := setNodeTaints(node, virtualCluster.Spec.NodeTaints); err != nil {
+			logger.Error("error setting node taints", err)
+		}
+
+		clusterKey := types.NamespacedName{Name: virtualCluster.Name, Namespace: virtualCluster.Namespace}
+
 		updateNodeCapacityInterval := 10 * time.Second
 		ticker := time.NewTicker(updateNodeCapacityInterval)
 
 		go func() {
 			for range ticker.C {
-				if err := updateNodeCapacity(ctx, coreClient, virtualClient, node.Name, virtualCluster.Spec.NodeSelector); err != nil {
+				if err := updateNodeCapacity(ctx, coreClient, hostClient, virtualClient, node.Name, virtualCluster); err != nil {
 					logger.Error("error updating node capacity", err)
 				}
+
+				if err := updateNodeTaints(ctx, hostClient, virtualClient, node.Name, clusterKey); err != nil {
+					logger.Error("error updating node taints", err)
+				}
 			}
 		}()
 	}
@@ -110,8 +127,15 @@ func nodeConditions() []corev1.NodeCondition {
 }
 
 // updateNodeCapacity will update the virtual node capacity (and the allocatable field) with the sum of all the resource in the host nodes.
-// If the nodeLabels are specified only the matching nodes will be considered.
-func updateNodeCapacity(ctx context.Context, coreClient typedv1.CoreV1Interface, virtualClient client.Client, virtualNodeName string, nodeLabels map[string]string) error {
+// If the cluster has a nodeSelector only the matching nodes will be considered.
+// If a RuntimeClass is enforced on the cluster, only the nodes supporting it will be considered, and its pod overhead
+// will be subtracted from the allocatable resources for each pod running on the virtual node.
+func updateNodeCapacity(ctx context.Context, coreClient typedv1.CoreV1Interface, hostClient, virtualClient client.Client, virtualNodeName string, virtualCluster v1alpha1.Cluster) error {
+	nodeLabels, podOverhead, err := hostNodeLabels(ctx, hostClient, virtualCluster)
+	if err != nil {
+		return err
+	}
+
 	capacity, allocatable, err := getResourcesFromNodes(ctx, coreClient, nodeLabels)
 	if err != nil {
 		return err
@@ -122,12 +146,83 @@ func updateNodeCapacity(ctx context.Context, coreClient typedv1.CoreV1Interface,
 		return err
 	}
 
+	if len(podOverhead) > 0 {
+		var virtualPods corev1.PodList
+		if err := virtualClient.List(ctx, &virtualPods); err != nil {
+			return err
+		}
+
+		var runningPods int64
+
+		for _, pod := range virtualPods.Items {
+			if pod.Spec.NodeName == virtualNodeName && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+				runningPods++
+			}
+		}
+
+		allocatable = subtractPodOverhead(allocatable, podOverhead, runningPods)
+	}
+
 	virtualNode.Status.Capacity = capacity
 	virtualNode.Status.Allocatable = allocatable
 
 	return virtualClient.Status().Update(ctx, &virtualNode)
 }
 
+// hostNodeLabels returns the labels of the host nodes selected by the cluster: the nodes matching its nodeSelector, and
+// supporting its RuntimeClass, if enforced. The pod overhead of the RuntimeClass is also returned.
+func hostNodeLabels(ctx context.Context, hostClient client.Client, virtualCluster v1alpha1.Cluster) (map[string]string, corev1.ResourceList, error) {
+	nodeLabels := virtualCluster.Spec.NodeSelector
+
+	if virtualCluster.Spec.RuntimeClassName == nil {
+		return nodeLabels, nil, nil
+	}
+
+	var runtimeClass nodev1.RuntimeClass
+	if err := hostClient.Get(ctx, types.NamespacedName{Name: *virtualCluster.Spec.RuntimeClassName}, &runtimeClass); err != nil {
+		return nil, nil, err
+	}
+
+	if runtimeClass.Scheduling != nil && len(runtimeClass.Scheduling.NodeSelector) > 0 {
+		nodeLabels = make(map[string]string)
+		maps.Copy(nodeLabels, virtualCluster.Spec.NodeSelector)
+		maps.Copy(nodeLabels, runtimeClass.Scheduling.NodeSelector)
+	}
+
+	var podOverhead corev1.ResourceList
+	if runtimeClass.Overhead != nil {
+		podOverhead = runtimeClass.Overhead.PodFixed
+	}
+
+	return nodeLabels, podOverhead, nil
+}
+
+// subtractPodOverhead will subtract the pod overhead of the given number of pods from the allocatable resources.
+// The resulting quantities are never negative.
+func subtractPodOverhead(allocatable, podOverhead corev1.ResourceList, pods int64) corev1.ResourceList {
+	result := allocatable.DeepCopy()
+
+	for resourceName, overhead := range podOverhead {
+		quantity, found := result[resourceName]
+		if !found {
+			continue
+		}
+
+		totalOverhead := overhead.DeepCopy()
+		totalOverhead.Mul(pods)
+
+		quantity.Sub(totalOverhead)
+
+		if quantity.Sign() < 0 {
+			quantity.Set(0)
+		}
+
+		result[resourceName] = quantity
+	}
+
+	return result
+}
+
 // getResourcesFromNodes will return a sum of all the resource capacity of the host nodes, and the allocatable resources.
 // If some node labels are specified only the matching nodes will be considered.
 func getResourcesFromNodes(ctx context.Context, coreClient typedv1.CoreV1Interface, nodeLabels map[string]string) (corev1.ResourceList, corev1.ResourceList, error) {
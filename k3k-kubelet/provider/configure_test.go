@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_subtractPodOverhead(t *testing.T) {
+	type args struct {
+		allocatable corev1.ResourceList
+		podOverhead corev1.ResourceList
+		pods        int64
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want corev1.ResourceList
+	}{
+		{
+			name: "no pods",
+			args: args{
+				allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+				podOverhead: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+				pods:        0,
+			},
+			want: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+		},
+		{
+			name: "overhead of multiple pods",
+			args: args{
+				allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("8Gi"),
+					corev1.ResourcePods:   resource.MustParse("110"),
+				},
+				podOverhead: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("250m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				},
+				pods: 4,
+			},
+			want: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("3"),
+				corev1.ResourceMemory: resource.MustParse("7680Mi"),
+				corev1.ResourcePods:   resource.MustParse("110"),
+			},
+		},
+		{
+			name: "overhead of a resource not allocatable",
+			args: args{
+				allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+				podOverhead: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+				pods:        2,
+			},
+			want: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+		},
+		{
+			name: "overhead exceeding the allocatable resources",
+			args: args{
+				allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+				podOverhead: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				pods:        3,
+			},
+			want: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("0")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := subtractPodOverhead(tt.args.allocatable, tt.args.podOverhead, tt.args.pods)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("subtractPodOverhead() = %v, want %v", got, tt.want)
+			}
+
+			for resourceName, want := range tt.want {
+				if gotQuantity := got[resourceName]; gotQuantity.Cmp(want) != 0 {
+					t.Errorf("subtractPodOverhead()[%s] = %s, want %s", resourceName, gotQuantity.String(), want.String())
+				}
+			}
+		})
+	}
+}
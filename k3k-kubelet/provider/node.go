@@ -2,21 +2,373 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+// NodeQuotaExhausted is the condition of the virtual node reporting that a ResourceQuota of the host namespaces
+// of the cluster is exhausted
+const NodeQuotaExhausted corev1.NodeConditionType = "QuotaExhausted"
+
+const (
+	pingTimeout             = 5 * time.Second
+	nodeConditionsInterval  = 10 * time.Second
+	maxNodesInConditionsMsg = 5
 )
 
-// Node implements the node.Provider interface from Virtual Kubelet
+// pressureConditions are the conditions of the host nodes reported on the virtual node, with the reasons
+// of the virtual node condition when the host nodes are, or are not, under pressure
+var pressureConditions = []struct {
+	conditionType  corev1.NodeConditionType
+	pressureReason string
+	healthyReason  string
+	healthyMessage string
+}{
+	{corev1.NodeMemoryPressure, "HostNodesUnderMemoryPressure", "KubeletHasSufficientMemory", "kubelet has sufficient memory available"},
+	{corev1.NodeDiskPressure, "HostNodesUnderDiskPressure", "KubeletHasNoDiskPressure", "kubelet has no disk pressure"},
+	{corev1.NodePIDPressure, "HostNodesUnderPIDPressure", "KubeletHasSufficientPID", "kubelet has sufficient PID available"},
+}
+
+// Node implements the node.Provider interface from Virtual Kubelet. The node is healthy as long as the API server
+// of the host cluster is reachable, and its conditions are computed from the host nodes selected by the cluster and
+// from the quotas of its host namespaces.
 type Node struct {
-	notifyCallback func(*corev1.Node)
+	provider *Provider
+
+	mu              sync.Mutex
+	node            *corev1.Node
+	virtualCluster  v1alpha1.Cluster
+	mirrorHostNodes bool
+	notifyCallback  func(*corev1.Node)
 }
 
-// Ping is called to check if the node is healthy - in the current format it always is
-func (n *Node) Ping(context.Context) error {
+// NewNode returns the node provider of the virtual node, the node is the one configured with ConfigureNode
+func NewNode(p *Provider, node *corev1.Node, virtualCluster v1alpha1.Cluster, mirrorHostNodes bool) *Node {
+	return &Node{
+		provider:        p,
+		node:            node.DeepCopy(),
+		virtualCluster:  virtualCluster,
+		mirrorHostNodes: mirrorHostNodes,
+	}
+}
+
+// Ping is called to check if the node is healthy. It fails when the API server of the host cluster is not ready:
+// the node status and lease are not renewed, and the node is marked as NotReady by the virtual cluster.
+func (n *Node) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	if err := n.provider.CoreClient.RESTClient().Get().AbsPath("/readyz").Do(ctx).Error(); err != nil {
+		return fmt.Errorf("host API server is not ready: %w", err)
+	}
+
 	return nil
 }
 
-// NotifyNodeStatus sets the callback function for a node being changed. As of now, no changes are made
+// NotifyNodeStatus sets the callback function for a node being changed, and starts updating the conditions
+// of the node in the background
 func (n *Node) NotifyNodeStatus(ctx context.Context, cb func(*corev1.Node)) {
+	n.mu.Lock()
 	n.notifyCallback = cb
+	n.mu.Unlock()
+
+	go n.updateConditions(ctx)
+}
+
+// SetKubeletVersion updates the kubelet version reported by the node. The version of a mirrored host node is not changed.
+func (n *Node) SetKubeletVersion(version string) {
+	if n.mirrorHostNodes || version == "" {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.node.Status.NodeInfo.KubeletVersion == version {
+		return
+	}
+
+	n.node.Status.NodeInfo.KubeletVersion = version
+
+	if n.notifyCallback != nil {
+		n.notifyCallback(n.node.DeepCopy())
+	}
+}
+
+// updateConditions periodically computes the conditions of the node, and notifies the changes
+func (n *Node) updateConditions(ctx context.Context) {
+	ticker := time.NewTicker(nodeConditionsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if n.mirrorHostNodes {
+			if err := n.updateMirroredTaints(ctx); err != nil {
+				n.provider.logger.Errorw("error updating mirrored node taints", "error", err)
+			}
+		}
+
+		conditions, err := n.conditions(ctx)
+		if err != nil {
+			n.provider.logger.Errorw("error computing node conditions", "error", err)
+			continue
+		}
+
+		n.setConditions(conditions)
+	}
+}
+
+// setConditions updates the conditions of the node, and notifies the node if they changed
+func (n *Node) setConditions(conditions []corev1.NodeCondition) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !conditionsChanged(n.node.Status.Conditions, conditions) {
+		return
+	}
+
+	n.node.Status.Conditions = mergeConditions(n.node.Status.Conditions, conditions, metav1.Now())
+	n.notifyCallback(n.node.DeepCopy())
+}
+
+// updateMirroredTaints updates the taints of the virtual node with the ones of the mirrored host node
+func (n *Node) updateMirroredTaints(ctx context.Context) error {
+	hostNode, err := n.provider.CoreClient.Nodes().Get(ctx, n.node.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	return updateMirroredNodeTaints(ctx, n.provider.VirtualClient, hostNode)
+}
+
+// conditions returns the conditions of the node from the host nodes selected by the cluster, and from the quotas
+func (n *Node) conditions(ctx context.Context) ([]corev1.NodeCondition, error) {
+	hostNodes, err := n.hostNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list host nodes: %w", err)
+	}
+
+	exhaustedResources, err := n.exhaustedResources(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list resource quotas: %w", err)
+	}
+
+	return hostNodesConditions(hostNodes, exhaustedResources), nil
+}
+
+// hostNodes returns the host nodes selected by the cluster, or the mirrored host node
+func (n *Node) hostNodes(ctx context.Context) ([]corev1.Node, error) {
+	coreClient := n.provider.CoreClient
+
+	if n.mirrorHostNodes {
+		hostNode, err := coreClient.Nodes().Get(ctx, n.node.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		return []corev1.Node{*hostNode}, nil
+	}
+
+	nodeLabels, _, err := hostNodeLabels(ctx, n.provider.HostClient, n.virtualCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeList, err := coreClient.Nodes().List(ctx, metav1.ListOptions{LabelSelector: labels.Set(nodeLabels).String()})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodeList.Items, nil
+}
+
+// exhaustedResources returns the resources of the quotas of the host namespaces used up to their hard limit
+func (n *Node) exhaustedResources(ctx context.Context) ([]string, error) {
+	hostNamespaces, err := n.provider.hostNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var exhausted []string
+
+	for _, namespace := range hostNamespaces {
+		quotaList, err := n.provider.CoreClient.ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, quota := range quotaList.Items {
+			for _, resourceName := range quotaExhaustedResources(quota) {
+				if !slices.Contains(exhausted, resourceName) {
+					exhausted = append(exhausted, resourceName)
+				}
+			}
+		}
+	}
+
+	slices.Sort(exhausted)
+
+	return exhausted, nil
+}
+
+// quotaExhaustedResources returns the resources of the quota whose usage reached the hard limit
+func quotaExhaustedResources(quota corev1.ResourceQuota) []string {
+	var exhausted []string
+
+	for resourceName, hard := range quota.Status.Hard {
+		used, found := quota.Status.Used[resourceName]
+		if found && used.Cmp(hard) >= 0 {
+			exhausted = append(exhausted, string(resourceName))
+		}
+	}
+
+	return exhausted
+}
+
+// hostNodesConditions returns the conditions of the virtual node. The node is ready if at least one of the host nodes
+// is ready, and is under pressure only if all the ready host nodes are, since the pods can still be scheduled on the
+// other ones. The heartbeat and transition times are not set.
+func hostNodesConditions(hostNodes []corev1.Node, exhaustedResources []string) []corev1.NodeCondition {
+	var readyNodes []corev1.Node
+
+	for _, node := range hostNodes {
+		if nodeConditionStatus(node, corev1.NodeReady) == corev1.ConditionTrue {
+			readyNodes = append(readyNodes, node)
+		}
+	}
+
+	ready := corev1.NodeCondition{
+		Type:    corev1.NodeReady,
+		Status:  corev1.ConditionTrue,
+		Reason:  "KubeletReady",
+		Message: "kubelet is ready.",
+	}
+
+	if len(readyNodes) == 0 {
+		ready.Status = corev1.ConditionFalse
+		ready.Reason = "HostNodesNotReady"
+		ready.Message = "none of the host nodes selected by the cluster is ready"
+	}
+
+	conditions := []corev1.NodeCondition{ready}
+
+	for _, pressure := range pressureConditions {
+		condition := corev1.NodeCondition{
+			Type:    pressure.conditionType,
+			Status:  corev1.ConditionFalse,
+			Reason:  pressure.healthyReason,
+			Message: pressure.healthyMessage,
+		}
+
+		var nodesUnderPressure []string
+
+		for _, node := range readyNodes {
+			if nodeConditionStatus(node, pressure.conditionType) == corev1.ConditionTrue {
+				nodesUnderPressure = append(nodesUnderPressure, node.Name)
+			}
+		}
+
+		if len(readyNodes) > 0 && len(nodesUnderPressure) == len(readyNodes) {
+			condition.Status = corev1.ConditionTrue
+			condition.Reason = pressure.pressureReason
+			condition.Message = fmt.Sprintf("all the ready host nodes have %s: %s", pressure.conditionType, joinNames(nodesUnderPressure))
+		}
+
+		conditions = append(conditions, condition)
+	}
+
+	quota := corev1.NodeCondition{
+		Type:    NodeQuotaExhausted,
+		Status:  corev1.ConditionFalse,
+		Reason:  "ResourceQuotaAvailable",
+		Message: "the resource quotas of the cluster are not exhausted",
+	}
+
+	if len(exhaustedResources) > 0 {
+		quota.Status = corev1.ConditionTrue
+		quota.Reason = "ResourceQuotaExhausted"
+		quota.Message = "resource quota exhausted in the host cluster: " + strings.Join(exhaustedResources, ", ")
+	}
+
+	return append(conditions, corev1.NodeCondition{
+		Type:    corev1.NodeNetworkUnavailable,
+		Status:  corev1.ConditionFalse,
+		Reason:  "RouteCreated",
+		Message: "RouteController created a route",
+	}, quota)
+}
+
+// nodeConditionStatus returns the status of the condition of the node, or Unknown if the node doesn't report it
+func nodeConditionStatus(node corev1.Node, conditionType corev1.NodeConditionType) corev1.ConditionStatus {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status
+		}
+	}
+
+	return corev1.ConditionUnknown
+}
+
+// joinNames joins the names of the nodes in a message, truncating the list if too long
+func joinNames(names []string) string {
+	if len(names) <= maxNodesInConditionsMsg {
+		return strings.Join(names, ", ")
+	}
+
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxNodesInConditionsMsg], ", "), len(names)-maxNodesInConditionsMsg)
+}
+
+// conditionsChanged returns true if the status, reason or message of the conditions changed
+func conditionsChanged(current, conditions []corev1.NodeCondition) bool {
+	if len(current) != len(conditions) {
+		return true
+	}
+
+	for _, condition := range conditions {
+		i := slices.IndexFunc(current, func(c corev1.NodeCondition) bool { return c.Type == condition.Type })
+		if i < 0 {
+			return true
+		}
+
+		if current[i].Status != condition.Status || current[i].Reason != condition.Reason || current[i].Message != condition.Message {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeConditions sets the heartbeat and transition times of the conditions, keeping the transition time of the
+// current conditions whose status didn't change
+func mergeConditions(current, conditions []corev1.NodeCondition, now metav1.Time) []corev1.NodeCondition {
+	merged := make([]corev1.NodeCondition, 0, len(conditions))
+
+	for _, condition := range conditions {
+		condition.LastHeartbeatTime = now
+		condition.LastTransitionTime = now
+
+		i := slices.IndexFunc(current, func(c corev1.NodeCondition) bool { return c.Type == condition.Type })
+		if i >= 0 && current[i].Status == condition.Status {
+			condition.LastTransitionTime = current[i].LastTransitionTime
+		}
+
+		merged = append(merged, condition)
+	}
+
+	return merged
 }
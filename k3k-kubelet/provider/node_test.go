@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func hostNode(name string, conditions map[corev1.NodeConditionType]corev1.ConditionStatus) corev1.Node {
+	node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+
+	for conditionType, status := range conditions {
+		node.Status.Conditions = append(node.Status.Conditions, corev1.NodeCondition{Type: conditionType, Status: status})
+	}
+
+	return node
+}
+
+func Test_hostNodesConditions(t *testing.T) {
+	tests := []struct {
+		name               string
+		hostNodes          []corev1.Node
+		exhaustedResources []string
+		want               map[corev1.NodeConditionType]corev1.ConditionStatus
+	}{
+		{
+			name: "healthy",
+			hostNodes: []corev1.Node{
+				hostNode("node-1", map[corev1.NodeConditionType]corev1.ConditionStatus{corev1.NodeReady: corev1.ConditionTrue}),
+			},
+			want: map[corev1.NodeConditionType]corev1.ConditionStatus{
+				corev1.NodeReady:              corev1.ConditionTrue,
+				corev1.NodeMemoryPressure:     corev1.ConditionFalse,
+				corev1.NodeDiskPressure:       corev1.ConditionFalse,
+				corev1.NodePIDPressure:        corev1.ConditionFalse,
+				corev1.NodeNetworkUnavailable: corev1.ConditionFalse,
+				NodeQuotaExhausted:            corev1.ConditionFalse,
+			},
+		},
+		{
+			name: "no ready host nodes",
+			hostNodes: []corev1.Node{
+				hostNode("node-1", map[corev1.NodeConditionType]corev1.ConditionStatus{corev1.NodeReady: corev1.ConditionFalse}),
+				hostNode("node-2", nil),
+			},
+			want: map[corev1.NodeConditionType]corev1.ConditionStatus{
+				corev1.NodeReady:              corev1.ConditionFalse,
+				corev1.NodeMemoryPressure:     corev1.ConditionFalse,
+				corev1.NodeDiskPressure:       corev1.ConditionFalse,
+				corev1.NodePIDPressure:        corev1.ConditionFalse,
+				corev1.NodeNetworkUnavailable: corev1.ConditionFalse,
+				NodeQuotaExhausted:            corev1.ConditionFalse,
+			},
+		},
+		{
+			name: "pressure on some of the ready host nodes",
+			hostNodes: []corev1.Node{
+				hostNode("node-1", map[corev1.NodeConditionType]corev1.ConditionStatus{corev1.NodeReady: corev1.ConditionTrue, corev1.NodeMemoryPressure: corev1.ConditionTrue}),
+				hostNode("node-2", map[corev1.NodeConditionType]corev1.ConditionStatus{corev1.NodeReady: corev1.ConditionTrue}),
+				hostNode("node-3", map[corev1.NodeConditionType]corev1.ConditionStatus{corev1.NodeReady: corev1.ConditionFalse, corev1.NodeDiskPressure: corev1.ConditionTrue}),
+			},
+			want: map[corev1.NodeConditionType]corev1.ConditionStatus{
+				corev1.NodeReady:              corev1.ConditionTrue,
+				corev1.NodeMemoryPressure:     corev1.ConditionFalse,
+				corev1.NodeDiskPressure:       corev1.ConditionFalse,
+				corev1.NodePIDPressure:        corev1.ConditionFalse,
+				corev1.NodeNetworkUnavailable: corev1.ConditionFalse,
+				NodeQuotaExhausted:            corev1.ConditionFalse,
+			},
+		},
+		{
+			name: "pressure on all the ready host nodes and quota exhausted",
+			hostNodes: []corev1.Node{
+				hostNode("node-1", map[corev1.NodeConditionType]corev1.ConditionStatus{corev1.NodeReady: corev1.ConditionTrue, corev1.NodeDiskPressure: corev1.ConditionTrue}),
+				hostNode("node-2", map[corev1.NodeConditionType]corev1.ConditionStatus{corev1.NodeReady: corev1.ConditionTrue, corev1.NodeDiskPressure: corev1.ConditionTrue}),
+				hostNode("node-3", map[corev1.NodeConditionType]corev1.ConditionStatus{corev1.NodeReady: corev1.ConditionUnknown}),
+			},
+			exhaustedResources: []string{"pods"},
+			want: map[corev1.NodeConditionType]corev1.ConditionStatus{
+				corev1.NodeReady:              corev1.ConditionTrue,
+				corev1.NodeMemoryPressure:     corev1.ConditionFalse,
+				corev1.NodeDiskPressure:       corev1.ConditionTrue,
+				corev1.NodePIDPressure:        corev1.ConditionFalse,
+				corev1.NodeNetworkUnavailable: corev1.ConditionFalse,
+				NodeQuotaExhausted:            corev1.ConditionTrue,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[corev1.NodeConditionType]corev1.ConditionStatus)
+
+			for _, condition := range hostNodesConditions(tt.hostNodes, tt.exhaustedResources) {
+				got[condition.Type] = condition.Status
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_quotaExhaustedResources(t *testing.T) {
+	quota := corev1.ResourceQuota{
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{
+				corev1.ResourcePods:           resource.MustParse("10"),
+				corev1.ResourceRequestsCPU:    resource.MustParse("2"),
+				corev1.ResourceRequestsMemory: resource.MustParse("4Gi"),
+			},
+			Used: corev1.ResourceList{
+				corev1.ResourcePods:        resource.MustParse("10"),
+				corev1.ResourceRequestsCPU: resource.MustParse("1500m"),
+			},
+		},
+	}
+
+	assert.Equal(t, []string{"pods"}, quotaExhaustedResources(quota))
+}
+
+func Test_mergeConditions(t *testing.T) {
+	before := metav1.NewTime(time.Now().Add(-time.Hour))
+	now := metav1.Now()
+
+	current := []corev1.NodeCondition{
+		{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastTransitionTime: before, LastHeartbeatTime: before},
+		{Type: corev1.NodeDiskPressure, Status: corev1.ConditionFalse, LastTransitionTime: before, LastHeartbeatTime: before},
+	}
+
+	conditions := []corev1.NodeCondition{
+		{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+		{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue},
+		{Type: NodeQuotaExhausted, Status: corev1.ConditionFalse},
+	}
+
+	assert.True(t, conditionsChanged(current, conditions))
+
+	merged := mergeConditions(current, conditions, now)
+
+	assert.Len(t, merged, 3)
+	assert.Equal(t, before, merged[0].LastTransitionTime)
+	assert.Equal(t, now, merged[0].LastHeartbeatTime)
+	assert.Equal(t, now, merged[1].LastTransitionTime)
+	assert.Equal(t, now, merged[2].LastTransitionTime)
+
+	assert.False(t, conditionsChanged(merged, conditions))
+}
@@ -0,0 +1,324 @@
+package provider
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rancher/k3k/k3k-kubelet/translate"
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	k3kcontroller "github.com/rancher/k3k/pkg/controller"
+)
+
+const (
+	projectedTokenControllerName = "projected-token-refresher"
+
+	// projectedTokenPodLabel is the label of the host secrets containing a projected token, with the name of the host pod
+	projectedTokenPodLabel = "k3k.io/projected-token-pod"
+
+	projectedTokenPodAnnotation               = "k3k.io/projected-token-pod"
+	projectedTokenServiceAccountAnnotation    = "k3k.io/projected-token-service-account"
+	projectedTokenAudienceAnnotation          = "k3k.io/projected-token-audience"
+	projectedTokenExpirationSecondsAnnotation = "k3k.io/projected-token-expiration-seconds"
+	projectedTokenExpirationAnnotation        = "k3k.io/projected-token-expiration"
+
+	projectedTokenKey = "token"
+
+	// defaultProjectedTokenExpirationSeconds is the default expiration of the projected tokens used by Kubernetes
+	defaultProjectedTokenExpirationSeconds = 3600
+)
+
+// transformProjectedTokens replaces the serviceAccountToken sources of the projected volumes with a secret in the host cluster,
+// containing a token requested to the virtual cluster with the same audience and expiration, and bound to the virtual pod.
+// The tokens are refreshed before their expiration by the projected token refresher.
+func (p *Provider) transformProjectedTokens(ctx context.Context, cluster *v1alpha1.Cluster, pod, tPod *corev1.Pod) error {
+	for _, volume := range tPod.Spec.Volumes {
+		if volume.Projected == nil || strings.HasPrefix(volume.Name, kubeAPIAccessPrefix) {
+			continue
+		}
+
+		for i, source := range volume.Projected.Sources {
+			if source.ServiceAccountToken == nil {
+				continue
+			}
+
+			hostSecret := projectedTokenSecret(pod, volume.Name, i, source.ServiceAccountToken)
+			p.Translator.TranslateTo(hostSecret)
+			hostSecret.Labels[projectedTokenPodLabel] = tPod.Name
+
+			if err := requestProjectedToken(ctx, p.VirtualClient, pod, hostSecret); err != nil {
+				return err
+			}
+
+			token := hostSecret.DeepCopy()
+
+			if _, err := controllerutil.CreateOrUpdate(ctx, p.HostClient, hostSecret, func() error {
+				hostSecret.Labels = token.Labels
+				hostSecret.Annotations = token.Annotations
+				hostSecret.Data = token.Data
+
+				return p.Translator.SetControllerReference(cluster, hostSecret, p.HostClient.Scheme())
+			}); err != nil {
+				return err
+			}
+
+			volume.Projected.Sources[i] = corev1.VolumeProjection{
+				Secret: &corev1.SecretProjection{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: hostSecret.Name,
+					},
+					Items: []corev1.KeyToPath{
+						{
+							Key:  projectedTokenKey,
+							Path: source.ServiceAccountToken.Path,
+						},
+					},
+				},
+			}
+		}
+	}
+
+	return nil
+}
+
+// projectedTokenSecret returns the secret, with the name and namespace of the virtual cluster,
+// holding the token of a serviceAccountToken source of a projected volume of the pod.
+func projectedTokenSecret(pod *corev1.Pod, volumeName string, sourceIndex int, source *corev1.ServiceAccountTokenProjection) *corev1.Secret {
+	serviceAccountName := pod.Spec.ServiceAccountName
+	if serviceAccountName == "" {
+		serviceAccountName = "default"
+	}
+
+	expirationSeconds := int64(defaultProjectedTokenExpirationSeconds)
+	if source.ExpirationSeconds != nil {
+		expirationSeconds = *source.ExpirationSeconds
+	}
+
+	annotations := map[string]string{
+		projectedTokenPodAnnotation:               pod.Name,
+		projectedTokenServiceAccountAnnotation:    serviceAccountName,
+		projectedTokenExpirationSecondsAnnotation: strconv.FormatInt(expirationSeconds, 10),
+	}
+
+	if source.Audience != "" {
+		annotations[projectedTokenAudienceAnnotation] = source.Audience
+	}
+
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        k3kcontroller.SafeConcatNameWithPrefix(pod.Name, volumeName, strconv.Itoa(sourceIndex)),
+			Namespace:   pod.Namespace,
+			Annotations: annotations,
+		},
+	}
+}
+
+// requestProjectedToken requests a token bound to the virtual pod for the ServiceAccount, audience and expiration
+// of the host secret, and stores it in the secret with its expiration time.
+func requestProjectedToken(ctx context.Context, virtualClient client.Client, pod *corev1.Pod, hostSecret *corev1.Secret) error {
+	expirationSeconds, err := strconv.ParseInt(hostSecret.Annotations[projectedTokenExpirationSecondsAnnotation], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: ptr.To(expirationSeconds),
+			BoundObjectRef: &authenticationv1.BoundObjectReference{
+				Kind:       "Pod",
+				APIVersion: "v1",
+				Name:       pod.Name,
+				UID:        pod.UID,
+			},
+		},
+	}
+
+	if audience := hostSecret.Annotations[projectedTokenAudienceAnnotation]; audience != "" {
+		tokenRequest.Spec.Audiences = []string{audience}
+	}
+
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      hostSecret.Annotations[projectedTokenServiceAccountAnnotation],
+			Namespace: pod.Namespace,
+		},
+	}
+
+	if err := virtualClient.SubResource("token").Create(ctx, serviceAccount, tokenRequest); err != nil {
+		return err
+	}
+
+	hostSecret.Data = map[string][]byte{
+		projectedTokenKey: []byte(tokenRequest.Status.Token),
+	}
+	hostSecret.Annotations[projectedTokenExpirationAnnotation] = tokenRequest.Status.ExpirationTimestamp.Format(time.RFC3339)
+
+	return nil
+}
+
+// projectedTokenRefreshTime returns the time when the token of the host secret needs to be refreshed,
+// after 80% of its lifetime like the kubelet does. A zero time is returned if the expiration is unknown.
+func projectedTokenRefreshTime(hostSecret *corev1.Secret) time.Time {
+	expiration, err := time.Parse(time.RFC3339, hostSecret.Annotations[projectedTokenExpirationAnnotation])
+	if err != nil {
+		return time.Time{}
+	}
+
+	expirationSeconds, err := strconv.ParseInt(hostSecret.Annotations[projectedTokenExpirationSecondsAnnotation], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return expiration.Add(-time.Duration(expirationSeconds) * time.Second / 5)
+}
+
+// deleteProjectedTokens deletes the host secrets containing the projected tokens of the host pod
+func (p *Provider) deleteProjectedTokens(ctx context.Context, hostNamespace, hostPodName string) error {
+	return p.HostClient.DeleteAllOf(ctx, &corev1.Secret{},
+		client.InNamespace(hostNamespace),
+		client.MatchingLabels{
+			translate.ClusterNameLabel: p.ClusterName,
+			projectedTokenPodLabel:     hostPodName,
+		},
+	)
+}
+
+type projectedTokenRefresher struct {
+	hostClient    client.Client
+	virtualClient client.Client
+	clusterName   string
+	translator    translate.ToHostTranslator
+}
+
+// AddProjectedTokenRefresher adds the controller refreshing the projected tokens of the pods in the host cluster
+// before their expiration, and deleting them when the virtual pods are deleted.
+func AddProjectedTokenRefresher(ctx context.Context, hostMgr, virtualMgr manager.Manager, clusterName, clusterNamespace string) error {
+	var cluster v1alpha1.Cluster
+	if err := hostMgr.GetAPIReader().Get(ctx, types.NamespacedName{Name: clusterName, Namespace: clusterNamespace}, &cluster); err != nil {
+		return err
+	}
+
+	reconciler := projectedTokenRefresher{
+		hostClient:    hostMgr.GetClient(),
+		virtualClient: virtualMgr.GetClient(),
+		clusterName:   clusterName,
+		translator: translate.ToHostTranslator{
+			ClusterName:      clusterName,
+			ClusterNamespace: clusterNamespace,
+			NamingStrategy:   cluster.Spec.NamingStrategy,
+			NamespaceMapping: cluster.Spec.NamespaceMapping,
+		},
+	}
+
+	name := reconciler.translator.TranslateName(clusterNamespace, projectedTokenControllerName)
+
+	builder := ctrl.NewControllerManagedBy(hostMgr).
+		Named(name).
+		For(&corev1.Secret{}).WithEventFilter(predicate.NewPredicateFuncs(reconciler.filterResources))
+
+	// the secrets in the dedicated host namespaces are not in the cache of the host manager,
+	// they are reconciled from the events of the virtual pods
+	if cluster.Spec.NamespaceMapping == v1alpha1.PerNamespaceMapping {
+		builder = builder.WatchesRawSource(source.Kind(virtualMgr.GetCache(), &corev1.Pod{},
+			handler.TypedEnqueueRequestsFromMapFunc(reconciler.projectedTokenRequests),
+		))
+	}
+
+	return builder.Complete(&reconciler)
+}
+
+// projectedTokenRequests returns the requests of the host secrets containing the projected tokens of the virtual pod
+func (r *projectedTokenRefresher) projectedTokenRequests(_ context.Context, pod *corev1.Pod) []reconcile.Request {
+	var requests []reconcile.Request
+
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Projected == nil || strings.HasPrefix(volume.Name, kubeAPIAccessPrefix) {
+			continue
+		}
+
+		for i, source := range volume.Projected.Sources {
+			if source.ServiceAccountToken == nil {
+				continue
+			}
+
+			hostSecret := projectedTokenSecret(pod, volume.Name, i, source.ServiceAccountToken)
+			r.translator.TranslateTo(hostSecret)
+
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(hostSecret)})
+		}
+	}
+
+	return requests
+}
+
+func (r *projectedTokenRefresher) filterResources(object client.Object) bool {
+	labels := object.GetLabels()
+
+	_, found := labels[projectedTokenPodLabel]
+
+	return found && labels[translate.ClusterNameLabel] == r.clusterName
+}
+
+func (r *projectedTokenRefresher) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx).WithValues("cluster", r.clusterName)
+	ctx = ctrl.LoggerInto(ctx, log)
+
+	var hostSecret corev1.Secret
+	if err := r.hostClient.Get(ctx, req.NamespacedName, &hostSecret); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !hostSecret.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	if refreshIn := time.Until(projectedTokenRefreshTime(&hostSecret)); refreshIn > 0 {
+		return reconcile.Result{RequeueAfter: refreshIn}, nil
+	}
+
+	podKey := types.NamespacedName{
+		Name:      hostSecret.Annotations[projectedTokenPodAnnotation],
+		Namespace: hostSecret.Annotations[translate.ResourceNamespaceAnnotation],
+	}
+
+	var pod corev1.Pod
+	if err := r.virtualClient.Get(ctx, podKey, &pod); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+
+		log.Info("deleting projected token of deleted pod", "pod", podKey)
+
+		return reconcile.Result{}, client.IgnoreNotFound(r.hostClient.Delete(ctx, &hostSecret))
+	}
+
+	log.Info("refreshing projected token", "pod", podKey)
+
+	if err := requestProjectedToken(ctx, r.virtualClient, &pod, &hostSecret); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// the secret will be reconciled again after the update, and requeued until the next refresh
+	return reconcile.Result{}, r.hostClient.Update(ctx, &hostSecret)
+}
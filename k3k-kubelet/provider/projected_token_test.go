@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/utils/ptr"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_projectedTokenSecret(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+	}
+
+	secret := projectedTokenSecret(pod, "vault-token", 1, &corev1.ServiceAccountTokenProjection{
+		Audience: "vault",
+		Path:     "token",
+	})
+
+	if secret.Name != "k3k-app-vault-token-1" || secret.Namespace != "default" {
+		t.Errorf("projectedTokenSecret() = %s/%s, want default/k3k-app-vault-token-1", secret.Namespace, secret.Name)
+	}
+
+	want := map[string]string{
+		projectedTokenPodAnnotation:               "app",
+		projectedTokenServiceAccountAnnotation:    "default",
+		projectedTokenAudienceAnnotation:          "vault",
+		projectedTokenExpirationSecondsAnnotation: "3600",
+	}
+
+	for key, value := range want {
+		if secret.Annotations[key] != value {
+			t.Errorf("projectedTokenSecret() annotation %s = %q, want %q", key, secret.Annotations[key], value)
+		}
+	}
+
+	pod.Spec.ServiceAccountName = "app"
+
+	secret = projectedTokenSecret(pod, "token", 0, &corev1.ServiceAccountTokenProjection{
+		ExpirationSeconds: ptr.To[int64](600),
+		Path:              "token",
+	})
+
+	if _, found := secret.Annotations[projectedTokenAudienceAnnotation]; found {
+		t.Errorf("projectedTokenSecret() audience annotation set without audience")
+	}
+
+	if secret.Annotations[projectedTokenServiceAccountAnnotation] != "app" || secret.Annotations[projectedTokenExpirationSecondsAnnotation] != "600" {
+		t.Errorf("projectedTokenSecret() annotations = %v", secret.Annotations)
+	}
+}
+
+func Test_projectedTokenRefreshTime(t *testing.T) {
+	expiration := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        time.Time
+	}{
+		{
+			name: "refresh at 80% of the lifetime",
+			annotations: map[string]string{
+				projectedTokenExpirationAnnotation:        expiration.Format(time.RFC3339),
+				projectedTokenExpirationSecondsAnnotation: "3600",
+			},
+			want: expiration.Add(-12 * time.Minute),
+		},
+		{
+			name: "missing expiration",
+			annotations: map[string]string{
+				projectedTokenExpirationSecondsAnnotation: "3600",
+			},
+			want: time.Time{},
+		},
+		{
+			name: "invalid expiration seconds",
+			annotations: map[string]string{
+				projectedTokenExpirationAnnotation:        expiration.Format(time.RFC3339),
+				projectedTokenExpirationSecondsAnnotation: "one hour",
+			},
+			want: time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+			}
+
+			if got := projectedTokenRefreshTime(secret); !got.Equal(tt.want) {
+				t.Errorf("projectedTokenRefreshTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
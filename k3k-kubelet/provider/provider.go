@@ -8,6 +8,7 @@ import (
 	"io"
 	"maps"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -18,25 +19,30 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/httpstream"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/tools/remotecommand"
 	"k8s.io/client-go/transport/spdy"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	dto "github.com/prometheus/client_model/go"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	cv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	compbasemetrics "k8s.io/component-base/metrics"
 	stats "k8s.io/kubelet/pkg/apis/stats/v1alpha1"
 
+	"github.com/rancher/k3k/k3k-kubelet/audit"
 	"github.com/rancher/k3k/k3k-kubelet/controller/webhook"
+	"github.com/rancher/k3k/k3k-kubelet/metrics"
+	"github.com/rancher/k3k/k3k-kubelet/policy"
 	"github.com/rancher/k3k/k3k-kubelet/provider/collectors"
 	"github.com/rancher/k3k/k3k-kubelet/translate"
 	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
@@ -60,12 +66,14 @@ type Provider struct {
 	ClusterName      string
 	serverIP         string
 	dnsIP            string
+	eventRecorder    record.EventRecorder
+	auditor          *audit.Auditor
 	logger           *k3klog.Logger
 }
 
 var ErrRetryTimeout = errors.New("provider timed out")
 
-func New(hostConfig rest.Config, hostMgr, virtualMgr manager.Manager, logger *k3klog.Logger, namespace, name, serverIP, dnsIP string) (*Provider, error) {
+func New(hostConfig rest.Config, hostMgr, virtualMgr manager.Manager, auditor *audit.Auditor, logger *k3klog.Logger, namespace, name string, namingStrategy v1alpha1.NamingStrategy, namespaceMapping v1alpha1.NamespaceMapping, serverIP, dnsIP string) (*Provider, error) {
 	coreClient, err := cv1.NewForConfig(&hostConfig)
 	if err != nil {
 		return nil, err
@@ -74,6 +82,9 @@ func New(hostConfig rest.Config, hostMgr, virtualMgr manager.Manager, logger *k3
 	translator := translate.ToHostTranslator{
 		ClusterName:      name,
 		ClusterNamespace: namespace,
+		NamingStrategy:   namingStrategy,
+		Index:            translate.NewNameIndex(),
+		NamespaceMapping: namespaceMapping,
 	}
 
 	p := Provider{
@@ -85,6 +96,8 @@ func New(hostConfig rest.Config, hostMgr, virtualMgr manager.Manager, logger *k3
 		CoreClient:       coreClient,
 		ClusterNamespace: namespace,
 		ClusterName:      name,
+		eventRecorder:    virtualMgr.GetEventRecorderFor("k3k-kubelet"),
+		auditor:          auditor,
 		logger:           logger,
 		serverIP:         serverIP,
 		dnsIP:            dnsIP,
@@ -123,10 +136,19 @@ func (p *Provider) GetContainerLogs(ctx context.Context, namespace, podName, con
 		options.SinceTime = &sinceTime
 	}
 
-	closer, err := p.CoreClient.Pods(p.ClusterNamespace).GetLogs(hostPodName, &options).Stream(ctx)
-	p.logger.Infof("got error %s when getting logs for %s in %s", err, hostPodName, p.ClusterNamespace)
+	session := p.auditor.Start(ctx, audit.ActionLogs, namespace, podName, containerName)
 
-	return closer, err
+	hostNamespace := p.Translator.HostNamespace(namespace)
+
+	closer, err := p.CoreClient.Pods(hostNamespace).GetLogs(hostPodName, &options).Stream(ctx)
+	p.logger.Infof("got error %s when getting logs for %s in %s", err, hostPodName, hostNamespace)
+
+	if err != nil {
+		session.End(err)
+		return closer, err
+	}
+
+	return session.Logs(closer), nil
 }
 
 // RunInContainer executes a command in a container in the pod, copying data
@@ -136,7 +158,7 @@ func (p *Provider) RunInContainer(ctx context.Context, namespace, podName, conta
 	req := p.CoreClient.RESTClient().Post().
 		Resource("pods").
 		Name(hostPodName).
-		Namespace(p.ClusterNamespace).
+		Namespace(p.Translator.HostNamespace(namespace)).
 		SubResource("exec")
 	req.VersionedParams(&corev1.PodExecOptions{
 		Container: containerName,
@@ -147,20 +169,26 @@ func (p *Provider) RunInContainer(ctx context.Context, namespace, podName, conta
 		Stderr:    attach.Stderr() != nil,
 	}, scheme.ParameterCodec)
 
+	session := p.auditor.Start(ctx, audit.ActionExec, namespace, podName, containerName).WithCommand(cmd, attach.TTY())
+
 	exec, err := remotecommand.NewSPDYExecutor(&p.ClientConfig, http.MethodPost, req.URL())
 	if err != nil {
+		session.End(err)
 		return err
 	}
 
-	return exec.StreamWithContext(ctx, remotecommand.StreamOptions{
-		Stdin:  attach.Stdin(),
-		Stdout: attach.Stdout(),
-		Stderr: attach.Stderr(),
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  session.Stdin(attach.Stdin()),
+		Stdout: session.Stdout(attach.Stdout()),
+		Stderr: session.Stderr(attach.Stderr()),
 		Tty:    attach.TTY(),
 		TerminalSizeQueue: &translatorSizeQueue{
 			resizeChan: attach.Resize(),
 		},
 	})
+	session.End(err)
+
+	return err
 }
 
 // AttachToContainer attaches to the executing process of a container in the pod, copying data
@@ -170,7 +198,7 @@ func (p *Provider) AttachToContainer(ctx context.Context, namespace, podName, co
 	req := p.CoreClient.RESTClient().Post().
 		Resource("pods").
 		Name(hostPodName).
-		Namespace(p.ClusterNamespace).
+		Namespace(p.Translator.HostNamespace(namespace)).
 		SubResource("attach")
 	req.VersionedParams(&corev1.PodAttachOptions{
 		Container: containerName,
@@ -180,20 +208,26 @@ func (p *Provider) AttachToContainer(ctx context.Context, namespace, podName, co
 		Stderr:    attach.Stderr() != nil,
 	}, scheme.ParameterCodec)
 
+	session := p.auditor.Start(ctx, audit.ActionAttach, namespace, podName, containerName).WithCommand(nil, attach.TTY())
+
 	exec, err := remotecommand.NewSPDYExecutor(&p.ClientConfig, http.MethodPost, req.URL())
 	if err != nil {
+		session.End(err)
 		return err
 	}
 
-	return exec.StreamWithContext(ctx, remotecommand.StreamOptions{
-		Stdin:  attach.Stdin(),
-		Stdout: attach.Stdout(),
-		Stderr: attach.Stderr(),
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  session.Stdin(attach.Stdin()),
+		Stdout: session.Stdout(attach.Stdout()),
+		Stderr: session.Stderr(attach.Stderr()),
 		Tty:    attach.TTY(),
 		TerminalSizeQueue: &translatorSizeQueue{
 			resizeChan: attach.Resize(),
 		},
 	})
+	session.End(err)
+
+	return err
 }
 
 // GetStatsSummary gets the stats for the node, including running pods
@@ -243,11 +277,14 @@ func (p *Provider) GetStatsSummary(ctx context.Context) (*stats.Summary, error)
 		return nil, err
 	}
 
-	podsNameMap := make(map[string]*corev1.Pod)
+	podsNameMap := make(map[types.NamespacedName]*corev1.Pod)
 
 	for _, pod := range pods {
-		hostPodName := p.Translator.TranslateName(pod.Namespace, pod.Name)
-		podsNameMap[hostPodName] = pod
+		hostPodKey := types.NamespacedName{
+			Namespace: p.Translator.HostNamespace(pod.Namespace),
+			Name:      p.Translator.TranslateName(pod.Namespace, pod.Name),
+		}
+		podsNameMap[hostPodKey] = pod
 	}
 
 	filteredStats := &stats.Summary{
@@ -256,13 +293,13 @@ func (p *Provider) GetStatsSummary(ctx context.Context) (*stats.Summary, error)
 	}
 
 	for _, podStat := range allPodsStats {
-		// skip pods that are not in the cluster namespace
-		if podStat.PodRef.Namespace != p.ClusterNamespace {
-			continue
+		hostPodKey := types.NamespacedName{
+			Namespace: podStat.PodRef.Namespace,
+			Name:      podStat.PodRef.Name,
 		}
 
-		// rewrite the PodReference to match the data of the virtual cluster
-		if pod, found := podsNameMap[podStat.PodRef.Name]; found {
+		// rewrite the PodReference to match the data of the virtual cluster, skipping the pods of other clusters
+		if pod, found := podsNameMap[hostPodKey]; found {
 			podStat.PodRef = stats.PodReference{
 				Name:      pod.Name,
 				Namespace: pod.Namespace,
@@ -293,39 +330,108 @@ func (p *Provider) GetMetricsResource(ctx context.Context) ([]*dto.MetricFamily,
 	return metricFamily, nil
 }
 
-// PortForward forwards a local port to a port on the pod
+// PortForward forwards the stream of the virtual API server to a port on the host pod
 func (p *Provider) PortForward(ctx context.Context, namespace, pod string, port int32, stream io.ReadWriteCloser) error {
 	hostPodName := p.Translator.TranslateName(namespace, pod)
 	req := p.CoreClient.RESTClient().Post().
 		Resource("pods").
 		Name(hostPodName).
-		Namespace(p.ClusterNamespace).
+		Namespace(p.Translator.HostNamespace(namespace)).
 		SubResource("portforward")
 
+	session := p.auditor.Start(ctx, audit.ActionPortForward, namespace, pod, "").WithPort(port)
+
 	transport, upgrader, err := spdy.RoundTripperFor(&p.ClientConfig)
 	if err != nil {
+		session.End(err)
 		return err
 	}
 
 	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
-	portAsString := strconv.Itoa(int(port))
-	readyChannel := make(chan struct{})
-	stopChannel := make(chan struct{}, 1)
-
-	// Today this doesn't work properly. When the port ward is supposed to stop, the caller (this provider)
-	// should send a value on stopChannel so that the PortForward is stopped. However, we only have a ReadWriteCloser
-	// so more work is needed to detect a close and handle that appropriately.
-	fw, err := portforward.New(dialer, []string{portAsString}, stopChannel, readyChannel, stream, stream)
+
+	err = forwardStream(ctx, dialer, port, session.Stdin(stream), session.Stdout(stream))
+	session.End(err)
+
+	return err
+}
+
+// forwardStream copies the stream of the virtual API server to a port of the host pod, in a data stream of a
+// port-forward connection to the host API server
+func forwardStream(ctx context.Context, dialer httpstream.Dialer, port int32, in io.Reader, out io.Writer) error {
+	conn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
 	if err != nil {
+		return fmt.Errorf("unable to upgrade the port-forward connection: %w", err)
+	}
+	defer conn.Close()
+
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(int(port)))
+	headers.Set(corev1.PortForwardRequestIDHeader, "0")
+
+	errorStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("unable to create the error stream for port %d: %w", port, err)
+	}
+
+	// the error stream is only read
+	_ = errorStream.Close()
+
+	errorChan := make(chan error, 1)
+
+	go func() {
+		message, err := io.ReadAll(errorStream)
+
+		switch {
+		case err != nil:
+			errorChan <- fmt.Errorf("unable to read the error stream for port %d: %w", port, err)
+		case len(message) > 0:
+			errorChan <- fmt.Errorf("unable to forward port %d: %s", port, message)
+		}
+
+		close(errorChan)
+	}()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+
+	dataStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("unable to create the data stream for port %d: %w", port, err)
+	}
+
+	localError := make(chan error, 1)
+	remoteDone := make(chan struct{})
+
+	go func() {
+		// the connection is closed when the pod closes the data stream
+		_, _ = io.Copy(out, dataStream)
+
+		close(remoteDone)
+	}()
+
+	go func() {
+		// the data stream is closed when the virtual API server closes the stream
+		defer dataStream.Close()
+
+		if _, err := io.Copy(dataStream, in); err != nil {
+			localError <- err
+		}
+	}()
+
+	select {
+	case <-remoteDone:
+	case err := <-localError:
 		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 
-	return fw.ForwardPorts()
+	return <-errorChan
 }
 
 // CreatePod executes createPod with retry
 func (p *Provider) CreatePod(ctx context.Context, pod *corev1.Pod) error {
-	return p.withRetry(ctx, p.createPod, pod)
+	return p.withRetry(ctx, metrics.OperationCreate, p.createPod, pod)
 }
 
 // createPod takes a Kubernetes Pod and deploys it within the provider.
@@ -359,7 +465,17 @@ func (p *Provider) createPod(ctx context.Context, pod *corev1.Pod) error {
 	// the node was scheduled on the virtual kubelet, but leaving it this way will make it pending indefinitely
 	tPod.Spec.NodeName = ""
 
-	tPod.Spec.NodeSelector = cluster.Spec.NodeSelector
+	// node selector, affinity, topology spread constraints and tolerations
+	if err := p.translateScheduling(ctx, &cluster, pod, tPod); err != nil {
+		return fmt.Errorf("unable to translate scheduling constraints for pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+
+	// if a runtimeClass is enforced for the virtual cluster then override the provided value.
+	// The overhead will be set again by the RuntimeClass admission of the host cluster.
+	if cluster.Spec.RuntimeClassName != nil {
+		tPod.Spec.RuntimeClassName = cluster.Spec.RuntimeClassName
+		tPod.Spec.Overhead = nil
+	}
 
 	// setting the hostname for the pod if its not set
 	if pod.Spec.Hostname == "" {
@@ -390,11 +506,29 @@ func (p *Provider) createPod(ctx context.Context, pod *corev1.Pod) error {
 	if err := p.transformVolumes(pod.Namespace, tPod.Spec.Volumes); err != nil {
 		return fmt.Errorf("unable to sync volumes for pod %s/%s: %w", pod.Namespace, pod.Name, err)
 	}
+	// a virtual serviceaccount mapped to a host serviceaccount will get its projected tokens from the host cluster,
+	// otherwise they are requested to the virtual cluster
+	hostServiceAccount, err := p.hostServiceAccount(ctx, &cluster, &sourcePod)
+	if err != nil {
+		return err
+	}
+
+	if hostServiceAccount == "" {
+		if err := p.transformProjectedTokens(ctx, &cluster, &sourcePod, tPod); err != nil {
+			return fmt.Errorf("unable to transform projected tokens for pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+	}
+
 	// sync serviceaccount token to a the host cluster
 	if err := p.transformTokens(ctx, pod, tPod); err != nil {
 		return fmt.Errorf("unable to transform tokens for pod %s/%s: %w", pod.Namespace, pod.Name, err)
 	}
 
+	if hostServiceAccount != "" {
+		tPod.Spec.ServiceAccountName = hostServiceAccount
+		tPod.Spec.DeprecatedServiceAccount = ""
+	}
+
 	for i, imagePullSecret := range tPod.Spec.ImagePullSecrets {
 		tPod.Spec.ImagePullSecrets[i].Name = p.Translator.TranslateName(pod.Namespace, imagePullSecret.Name)
 	}
@@ -402,21 +536,27 @@ func (p *Provider) createPod(ctx context.Context, pod *corev1.Pod) error {
 	// inject networking information to the pod including the virtual cluster controlplane endpoint
 	configureNetworking(tPod, pod.Name, pod.Namespace, p.serverIP, p.dnsIP)
 
+	// rewrite the images and check that they are allowed by the policy
+	if err := p.applyImagePolicy(ctx, &cluster, pod, tPod); err != nil {
+		return err
+	}
+
 	p.logger.Infow("creating pod",
 		"host_namespace", tPod.Namespace, "host_name", tPod.Name,
 		"virtual_namespace", pod.Namespace, "virtual_name", pod.Name,
 	)
 
 	// set ownerReference to the cluster object
-	if err := controllerutil.SetControllerReference(&cluster, tPod, p.HostClient.Scheme()); err != nil {
+	if err := p.Translator.SetControllerReference(&cluster, tPod, p.HostClient.Scheme()); err != nil {
 		return err
 	}
 
 	return p.HostClient.Create(ctx, tPod)
 }
 
-// withRetry retries passed function with interval and timeout
-func (p *Provider) withRetry(ctx context.Context, f func(context.Context, *corev1.Pod) error, pod *corev1.Pod) error {
+// withRetry retries passed function with interval and timeout. The duration, the retries and the result
+// of the operation are recorded in the metrics.
+func (p *Provider) withRetry(ctx context.Context, operation string, f func(context.Context, *corev1.Pod) error, pod *corev1.Pod) error {
 	const (
 		interval = 2 * time.Second
 		timeout  = 10 * time.Second
@@ -424,11 +564,16 @@ func (p *Provider) withRetry(ctx context.Context, f func(context.Context, *corev
 
 	var allErrors error
 
+	start := time.Now()
+
 	// retryFn will retry until the operation succeed, or the timeout occurs
 	retryFn := func(ctx context.Context) (bool, error) {
 		if lastErr := f(ctx, pod); lastErr != nil {
 			// log that the retry failed?
 			allErrors = errors.Join(allErrors, lastErr)
+
+			metrics.PodOperationRetries.WithLabelValues(operation).Inc()
+
 			return false, nil
 		}
 
@@ -436,9 +581,16 @@ func (p *Provider) withRetry(ctx context.Context, f func(context.Context, *corev
 	}
 
 	if err := wait.PollUntilContextTimeout(ctx, interval, timeout, true, retryFn); err != nil {
-		return errors.Join(allErrors, ErrRetryTimeout)
+		metrics.PodOperationTimeouts.WithLabelValues(operation).Inc()
+
+		err = errors.Join(allErrors, ErrRetryTimeout)
+		metrics.ObservePodOperation(operation, start, err)
+
+		return err
 	}
 
+	metrics.ObservePodOperation(operation, start, nil)
+
 	return nil
 }
 
@@ -484,7 +636,7 @@ func (p *Provider) transformVolumes(podNamespace string, volumes []corev1.Volume
 
 // UpdatePod executes updatePod with retry
 func (p *Provider) UpdatePod(ctx context.Context, pod *corev1.Pod) error {
-	return p.withRetry(ctx, p.updatePod, pod)
+	return p.withRetry(ctx, metrics.OperationUpdate, p.updatePod, pod)
 }
 
 func (p *Provider) updatePod(ctx context.Context, pod *corev1.Pod) error {
@@ -501,7 +653,7 @@ func (p *Provider) updatePod(ctx context.Context, pod *corev1.Pod) error {
 	}
 
 	hostNamespaceName := types.NamespacedName{
-		Namespace: p.ClusterNamespace,
+		Namespace: p.Translator.HostNamespace(pod.Namespace),
 		Name:      p.Translator.TranslateName(pod.Namespace, pod.Name),
 	}
 
@@ -515,9 +667,19 @@ func (p *Provider) updatePod(ctx context.Context, pod *corev1.Pod) error {
 	if !cmp.Equal(currentHostPod.Spec.EphemeralContainers, pod.Spec.EphemeralContainers) {
 		p.logger.Info("Updating ephemeral containers")
 
-		currentHostPod.Spec.EphemeralContainers = pod.Spec.EphemeralContainers
+		var cluster v1alpha1.Cluster
+		if err := p.HostClient.Get(ctx, types.NamespacedName{Name: p.ClusterName, Namespace: p.ClusterNamespace}, &cluster); err != nil {
+			return fmt.Errorf("unable to get cluster %s in namespace %s: %w", p.ClusterName, p.ClusterNamespace, err)
+		}
+
+		resolvedPod := pod.DeepCopy()
+		if err := p.applyImagePolicy(ctx, &cluster, pod, resolvedPod); err != nil {
+			return err
+		}
+
+		currentHostPod.Spec.EphemeralContainers = resolvedPod.Spec.EphemeralContainers
 
-		if _, err := p.CoreClient.Pods(p.ClusterNamespace).UpdateEphemeralContainers(ctx, currentHostPod.Name, &currentHostPod, metav1.UpdateOptions{}); err != nil {
+		if _, err := p.CoreClient.Pods(currentHostPod.Namespace).UpdateEphemeralContainers(ctx, currentHostPod.Name, &currentHostPod, metav1.UpdateOptions{}); err != nil {
 			p.logger.Errorf("error when updating ephemeral containers: %v", err)
 			return err
 		}
@@ -544,13 +706,31 @@ func (p *Provider) updatePod(ctx context.Context, pod *corev1.Pod) error {
 		return fmt.Errorf("unable to update pod in the virtual cluster: %w", err)
 	}
 
-	// Update Pod in the host cluster
-	currentHostPod.Spec.Containers = updateContainerImages(currentHostPod.Spec.Containers, pod.Spec.Containers)
-	currentHostPod.Spec.InitContainers = updateContainerImages(currentHostPod.Spec.InitContainers, pod.Spec.InitContainers)
+	// Update Pod in the host cluster, with the images resolved by the policy
+	var cluster v1alpha1.Cluster
+	if err := p.HostClient.Get(ctx, types.NamespacedName{Name: p.ClusterName, Namespace: p.ClusterNamespace}, &cluster); err != nil {
+		return fmt.Errorf("unable to get cluster %s in namespace %s: %w", p.ClusterName, p.ClusterNamespace, err)
+	}
+
+	resolvedPod := pod.DeepCopy()
+	if err := p.applyImagePolicy(ctx, &cluster, pod, resolvedPod); err != nil {
+		return err
+	}
+
+	if err := p.filterTolerations(ctx, &cluster, pod, resolvedPod); err != nil {
+		return err
+	}
+
+	if err := p.translateNodeTaintTolerations(ctx, pod, resolvedPod); err != nil {
+		return err
+	}
+
+	currentHostPod.Spec.Containers = updateContainerImages(currentHostPod.Spec.Containers, resolvedPod.Spec.Containers)
+	currentHostPod.Spec.InitContainers = updateContainerImages(currentHostPod.Spec.InitContainers, resolvedPod.Spec.InitContainers)
 
 	// update ActiveDeadlineSeconds and Tolerations
 	currentHostPod.Spec.ActiveDeadlineSeconds = pod.Spec.ActiveDeadlineSeconds
-	currentHostPod.Spec.Tolerations = pod.Spec.Tolerations
+	currentHostPod.Spec.Tolerations = resolvedPod.Spec.Tolerations
 
 	// in the virtual cluster we can update also the labels and annotations
 	maps.Copy(currentHostPod.Annotations, pod.Annotations)
@@ -563,6 +743,66 @@ func (p *Provider) updatePod(ctx context.Context, pod *corev1.Pod) error {
 	return nil
 }
 
+// applyImagePolicy will rewrite the images of the target pod, and enforce the imagePullPolicy, with the ImagePolicy of the
+// VirtualClusterPolicy bound to the cluster. If some images are not allowed an event is recorded on the virtual pod.
+// The target pod must have the images of the virtual pod, since the rewrite rules are not idempotent.
+func (p *Provider) applyImagePolicy(ctx context.Context, cluster *v1alpha1.Cluster, virtualPod, targetPod *corev1.Pod) error {
+	vcp, err := policy.Get(ctx, p.HostClient, cluster)
+	if err != nil {
+		return fmt.Errorf("unable to get policy of cluster %s in namespace %s: %w", cluster.Name, cluster.Namespace, err)
+	}
+
+	if vcp == nil {
+		return nil
+	}
+
+	if err := policy.ApplyImagePolicy(vcp.Spec.ImagePolicy, targetPod); err != nil {
+		message := fmt.Sprintf("image denied by VirtualClusterPolicy %q: %s", vcp.Name, strings.ReplaceAll(err.Error(), "\n", ", "))
+		p.eventRecorder.Event(virtualPod, corev1.EventTypeWarning, "ImageNotAllowed", message)
+
+		return errors.New(message)
+	}
+
+	return nil
+}
+
+// hostServiceAccount returns the host serviceaccount the serviceaccount of the virtual pod is mapped to, if any.
+// If the mapping is not allowed by the VirtualClusterPolicy bound to the cluster an event is recorded on the virtual pod.
+func (p *Provider) hostServiceAccount(ctx context.Context, cluster *v1alpha1.Cluster, virtualPod *corev1.Pod) (string, error) {
+	serviceAccountKey := types.NamespacedName{
+		Name:      virtualPod.Spec.ServiceAccountName,
+		Namespace: virtualPod.Namespace,
+	}
+
+	if serviceAccountKey.Name == "" {
+		serviceAccountKey.Name = "default"
+	}
+
+	var serviceAccount corev1.ServiceAccount
+	if err := p.VirtualClient.Get(ctx, serviceAccountKey, &serviceAccount); err != nil {
+		return "", client.IgnoreNotFound(err)
+	}
+
+	if serviceAccount.Annotations[policy.HostServiceAccountAnnotation] == "" {
+		return "", nil
+	}
+
+	vcp, err := policy.Get(ctx, p.HostClient, cluster)
+	if err != nil {
+		return "", fmt.Errorf("unable to get policy of cluster %s in namespace %s: %w", cluster.Name, cluster.Namespace, err)
+	}
+
+	hostServiceAccount, err := policy.HostServiceAccount(vcp, &serviceAccount)
+	if err != nil {
+		message := fmt.Sprintf("serviceaccount %s denied by VirtualClusterPolicy: %s", serviceAccount.Name, err.Error())
+		p.eventRecorder.Event(virtualPod, corev1.EventTypeWarning, "HostServiceAccountNotAllowed", message)
+
+		return "", errors.New(message)
+	}
+
+	return hostServiceAccount, nil
+}
+
 // updateContainerImages will update the images of the original container images with the same name
 func updateContainerImages(original, updated []corev1.Container) []corev1.Container {
 	newImages := make(map[string]string)
@@ -582,7 +822,7 @@ func updateContainerImages(original, updated []corev1.Container) []corev1.Contai
 
 // DeletePod executes deletePod with retry
 func (p *Provider) DeletePod(ctx context.Context, pod *corev1.Pod) error {
-	return p.withRetry(ctx, p.deletePod, pod)
+	return p.withRetry(ctx, metrics.OperationDelete, p.deletePod, pod)
 }
 
 // deletePod takes a Kubernetes Pod and deletes it from the provider. Once a pod is deleted, the provider is
@@ -591,12 +831,25 @@ func (p *Provider) DeletePod(ctx context.Context, pod *corev1.Pod) error {
 func (p *Provider) deletePod(ctx context.Context, pod *corev1.Pod) error {
 	p.logger.Infof("Got request to delete pod %s", pod.Name)
 	hostName := p.Translator.TranslateName(pod.Namespace, pod.Name)
+	hostNamespace := p.Translator.HostNamespace(pod.Namespace)
+
+	// the grace period requested for the deletion of the virtual pod is propagated, to let the containers
+	// and their preStop hooks terminate within the same period
+	deleteOptions := metav1.DeleteOptions{
+		GracePeriodSeconds: pod.DeletionGracePeriodSeconds,
+	}
 
-	err := p.CoreClient.Pods(p.ClusterNamespace).Delete(ctx, hostName, metav1.DeleteOptions{})
+	err := p.CoreClient.Pods(hostNamespace).Delete(ctx, hostName, deleteOptions)
 	if err != nil {
 		return fmt.Errorf("unable to delete pod %s/%s: %w", pod.Namespace, pod.Name, err)
 	}
 
+	if err := p.deleteProjectedTokens(ctx, hostNamespace, hostName); err != nil {
+		return fmt.Errorf("unable to delete projected tokens of pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+
+	p.Translator.Index.Delete(hostName)
+
 	p.logger.Infof("Deleted pod %s", pod.Name)
 
 	return nil
@@ -609,7 +862,7 @@ func (p *Provider) deletePod(ctx context.Context, pod *corev1.Pod) error {
 func (p *Provider) GetPod(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
 	p.logger.Debugw("got a request for get pod", "Namespace", namespace, "Name", name)
 	hostNamespaceName := types.NamespacedName{
-		Namespace: p.ClusterNamespace,
+		Namespace: p.Translator.HostNamespace(namespace),
 		Name:      p.Translator.TranslateName(namespace, name),
 	}
 
@@ -619,6 +872,8 @@ func (p *Provider) GetPod(ctx context.Context, namespace, name string) (*corev1.
 		return nil, fmt.Errorf("error when retrieving pod: %w", err)
 	}
 
+	// the name of the pod is known, so it's indexed in case the annotations of the host pod are erased
+	p.Translator.Index.Add(hostNamespaceName.Name, types.NamespacedName{Namespace: namespace, Name: name})
 	p.Translator.TranslateFrom(&pod)
 
 	return &pod, nil
@@ -638,7 +893,16 @@ func (p *Provider) GetPodStatus(ctx context.Context, namespace, name string) (*c
 
 	p.logger.Debugw("got pod status", "Namespace", namespace, "Name", name, "Status", pod.Status)
 
-	return pod.Status.DeepCopy(), nil
+	var virtualPod corev1.Pod
+	if err := p.VirtualClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &virtualPod); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("unable to get virtual pod for status: %w", err)
+		}
+
+		return pod.Status.DeepCopy(), nil
+	}
+
+	return translatePodStatus(&virtualPod, &pod.Status), nil
 }
 
 // GetPods retrieves a list of all pods running on the provider (can be cached).
@@ -655,23 +919,61 @@ func (p *Provider) GetPods(ctx context.Context) ([]*corev1.Pod, error) {
 
 	selector = selector.Add(*requirement)
 
-	var podList corev1.PodList
-
-	err = p.HostClient.List(ctx, &podList, &client.ListOptions{LabelSelector: selector})
+	hostNamespaces, err := p.hostNamespaces(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("unable to list pods: %w", err)
+		return nil, err
 	}
 
 	retPods := []*corev1.Pod{}
 
-	for _, pod := range podList.DeepCopy().Items {
-		p.Translator.TranslateFrom(&pod)
-		retPods = append(retPods, &pod)
+	for _, hostNamespace := range hostNamespaces {
+		var podList corev1.PodList
+
+		err = p.HostClient.List(ctx, &podList, &client.ListOptions{LabelSelector: selector, Namespace: hostNamespace})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list pods in namespace %s: %w", hostNamespace, err)
+		}
+
+		for _, pod := range podList.DeepCopy().Items {
+			hostName := pod.Name
+
+			// a pod left out of the list would be taken for a deleted pod
+			if !p.Translator.TranslateFrom(&pod) {
+				return nil, fmt.Errorf("unable to find the virtual name of the host pod %s/%s", hostNamespace, hostName)
+			}
+
+			retPods = append(retPods, &pod)
+		}
 	}
 
 	return retPods, nil
 }
 
+// hostNamespaces returns the namespaces of the host cluster containing the pods of the virtual cluster: the namespace
+// of the cluster, and the dedicated namespaces of the virtual namespaces with the "PerNamespace" namespace mapping.
+func (p *Provider) hostNamespaces(ctx context.Context) ([]string, error) {
+	hostNamespaces := []string{p.ClusterNamespace}
+
+	if p.Translator.NamespaceMapping != v1alpha1.PerNamespaceMapping {
+		return hostNamespaces, nil
+	}
+
+	var namespaceList corev1.NamespaceList
+
+	if err := p.HostClient.List(ctx, &namespaceList, client.MatchingLabels{
+		translate.ClusterNameLabel:      p.ClusterName,
+		translate.ClusterNamespaceLabel: p.ClusterNamespace,
+	}); err != nil {
+		return nil, fmt.Errorf("unable to list namespaces of cluster %s: %w", p.ClusterName, err)
+	}
+
+	for _, namespace := range namespaceList.Items {
+		hostNamespaces = append(hostNamespaces, namespace.Name)
+	}
+
+	return hostNamespaces, nil
+}
+
 // configureNetworking will inject network information to each pod to connect them to the
 // virtual cluster api server, as well as confiugre DNS information to connect them to the
 // synced coredns on the host cluster.
@@ -777,13 +1079,29 @@ func (p *Provider) configureFieldPathEnv(pod, tPod *corev1.Pod) error {
 	}
 
 	for name, value := range pod.Annotations {
-		if strings.Contains(name, webhook.FieldpathField) {
-			containerIndex, envName, err := webhook.ParseFieldPathAnnotationKey(name)
-			if err != nil {
-				return err
-			}
-			// re-adding these envs to the pod
-			tPod.Spec.Containers[containerIndex].Env = append(tPod.Spec.Containers[containerIndex].Env, corev1.EnvVar{
+		var containers []corev1.Container
+
+		switch {
+		case strings.HasPrefix(name, webhook.FieldpathInitField+"_"):
+			containers = tPod.Spec.InitContainers
+		case strings.HasPrefix(name, webhook.FieldpathField+"_"):
+			containers = tPod.Spec.Containers
+		default:
+			continue
+		}
+
+		containerIndex, envName, err := webhook.ParseFieldPathAnnotationKey(name)
+		if err != nil {
+			return err
+		}
+
+		if containerIndex < 0 || containerIndex >= len(containers) {
+			return fmt.Errorf("fieldpath annotation %s refers to a missing container", name)
+		}
+
+		// re-adding these envs to the pod, if not already added to the host pod
+		if !slices.ContainsFunc(containers[containerIndex].Env, func(env corev1.EnvVar) bool { return env.Name == envName }) {
+			containers[containerIndex].Env = append(containers[containerIndex].Env, corev1.EnvVar{
 				Name: envName,
 				ValueFrom: &corev1.EnvVarSource{
 					FieldRef: &corev1.ObjectFieldSelector{
@@ -791,9 +1109,9 @@ func (p *Provider) configureFieldPathEnv(pod, tPod *corev1.Pod) error {
 					},
 				},
 			})
-			// removing the annotation from the pod
-			delete(tPod.Annotations, name)
 		}
+		// removing the annotation from the pod
+		delete(tPod.Annotations, name)
 	}
 
 	return nil
@@ -68,3 +68,93 @@ func Test_mergeEnvVars(t *testing.T) {
 		})
 	}
 }
+
+func Test_configureFieldPathEnv(t *testing.T) {
+	podIPEnv := func(name string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name               string
+		annotations        map[string]string
+		hostPod            *corev1.Pod
+		wantInitContainers []corev1.Container
+		wantContainers     []corev1.Container
+		wantErr            bool
+	}{
+		{
+			name: "container and sidecar envs",
+			annotations: map[string]string{
+				"k3k.io/fieldpath_1_POD_IP":      "status.podIP",
+				"k3k.io/fieldpath-init_0_POD_IP": "status.podIP",
+			},
+			hostPod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "proxy"}},
+					Containers:     []corev1.Container{{Name: "app"}, {Name: "worker"}},
+				},
+			},
+			wantInitContainers: []corev1.Container{{Name: "proxy", Env: []corev1.EnvVar{podIPEnv("POD_IP")}}},
+			wantContainers:     []corev1.Container{{Name: "app"}, {Name: "worker", Env: []corev1.EnvVar{podIPEnv("POD_IP")}}},
+		},
+		{
+			name: "env already in the host pod",
+			annotations: map[string]string{
+				"k3k.io/fieldpath_0_POD_IP": "status.podIP",
+			},
+			hostPod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Env: []corev1.EnvVar{podIPEnv("POD_IP")}}},
+				},
+			},
+			wantContainers: []corev1.Container{{Name: "app", Env: []corev1.EnvVar{podIPEnv("POD_IP")}}},
+		},
+		{
+			name: "missing container",
+			annotations: map[string]string{
+				"k3k.io/fieldpath-init_0_POD_IP": "status.podIP",
+			},
+			hostPod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app"}},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{}
+
+			virtualPod := tt.hostPod.DeepCopy()
+			virtualPod.Annotations = tt.annotations
+
+			err := p.configureFieldPathEnv(virtualPod, tt.hostPod)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("configureFieldPathEnv() expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("configureFieldPathEnv() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(tt.hostPod.Spec.InitContainers, tt.wantInitContainers) {
+				t.Errorf("configureFieldPathEnv() initContainers = %v, want %v", tt.hostPod.Spec.InitContainers, tt.wantInitContainers)
+			}
+
+			if !reflect.DeepEqual(tt.hostPod.Spec.Containers, tt.wantContainers) {
+				t.Errorf("configureFieldPathEnv() containers = %v, want %v", tt.hostPod.Spec.Containers, tt.wantContainers)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/k3k-kubelet/policy"
+	"github.com/rancher/k3k/k3k-kubelet/translate"
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+// translateScheduling translates the scheduling constraints of the pod to the host cluster. The node selector is merged with the one
+// of the cluster, the pod affinity and topology spread constraints are restricted to the pods of the virtual cluster,
+// the tolerations not allowed by the VirtualClusterPolicy are removed, and the tolerations of the taints of the virtual
+// node are translated.
+func (p *Provider) translateScheduling(ctx context.Context, cluster *v1alpha1.Cluster, virtualPod, tPod *corev1.Pod) error {
+	tPod.Spec.NodeSelector = mergeNodeSelector(tPod.Spec.NodeSelector, cluster.Spec.NodeSelector)
+
+	terms := podAffinityTerms(tPod.Spec.Affinity)
+
+	// the namespaces are listed only if needed to resolve the namespace selectors
+	var namespaces []corev1.Namespace
+
+	if slices.ContainsFunc(terms, func(term *corev1.PodAffinityTerm) bool { return term.NamespaceSelector != nil }) {
+		var namespaceList corev1.NamespaceList
+		if err := p.VirtualClient.List(ctx, &namespaceList); err != nil {
+			return fmt.Errorf("unable to list namespaces: %w", err)
+		}
+
+		namespaces = namespaceList.Items
+	}
+
+	for _, term := range terms {
+		namespaceNames, err := translatePodAffinityTerm(term, cluster.Name, virtualPod.Namespace, namespaces)
+		if err != nil {
+			return err
+		}
+
+		p.translateAffinityNamespaces(term, namespaceNames)
+	}
+
+	for i := range tPod.Spec.TopologySpreadConstraints {
+		constraint := &tPod.Spec.TopologySpreadConstraints[i]
+		constraint.LabelSelector = translateLabelSelector(constraint.LabelSelector, cluster.Name, []string{virtualPod.Namespace})
+	}
+
+	if err := p.filterTolerations(ctx, cluster, virtualPod, tPod); err != nil {
+		return err
+	}
+
+	return p.translateNodeTaintTolerations(ctx, virtualPod, tPod)
+}
+
+// filterTolerations removes the tolerations not allowed by the VirtualClusterPolicy bound to the cluster,
+// recording an event on the virtual pod.
+func (p *Provider) filterTolerations(ctx context.Context, cluster *v1alpha1.Cluster, virtualPod, tPod *corev1.Pod) error {
+	if len(tPod.Spec.Tolerations) == 0 {
+		return nil
+	}
+
+	vcp, err := policy.Get(ctx, p.HostClient, cluster)
+	if err != nil {
+		return fmt.Errorf("unable to get policy of cluster %s in namespace %s: %w", cluster.Name, cluster.Namespace, err)
+	}
+
+	if vcp == nil {
+		return nil
+	}
+
+	allowed, denied := policy.FilterTolerations(vcp.Spec.AllowedTolerations, tPod.Spec.Tolerations)
+	if len(denied) > 0 {
+		message := fmt.Sprintf("tolerations removed by VirtualClusterPolicy %q: %v", vcp.Name, denied)
+		p.eventRecorder.Event(virtualPod, corev1.EventTypeWarning, "TolerationNotAllowed", message)
+	}
+
+	tPod.Spec.Tolerations = allowed
+
+	return nil
+}
+
+// mergeNodeSelector merges the node selector of the pod with the one of the cluster, that takes precedence
+func mergeNodeSelector(podNodeSelector, clusterNodeSelector map[string]string) map[string]string {
+	if len(podNodeSelector) == 0 {
+		return clusterNodeSelector
+	}
+
+	nodeSelector := maps.Clone(podNodeSelector)
+	maps.Copy(nodeSelector, clusterNodeSelector)
+
+	return nodeSelector
+}
+
+// podAffinityTerms returns all the required and preferred terms of the pod affinity and anti-affinity
+func podAffinityTerms(affinity *corev1.Affinity) []*corev1.PodAffinityTerm {
+	var terms []*corev1.PodAffinityTerm
+
+	if affinity == nil {
+		return terms
+	}
+
+	addTerms := func(required []corev1.PodAffinityTerm, preferred []corev1.WeightedPodAffinityTerm) {
+		for i := range required {
+			terms = append(terms, &required[i])
+		}
+
+		for i := range preferred {
+			terms = append(terms, &preferred[i].PodAffinityTerm)
+		}
+	}
+
+	if affinity.PodAffinity != nil {
+		addTerms(affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution, affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+	}
+
+	if affinity.PodAntiAffinity != nil {
+		addTerms(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+	}
+
+	return terms
+}
+
+// translatePodAffinityTerm restricts the pod affinity term to the pods of the virtual cluster in the namespaces selected by the term.
+// All the host pods are in the namespace of the cluster, so the virtual namespaces are matched with the namespace label.
+// The selected virtual namespaces are returned, none if all the namespaces are selected.
+func translatePodAffinityTerm(term *corev1.PodAffinityTerm, clusterName, podNamespace string, namespaces []corev1.Namespace) ([]string, error) {
+	var namespaceNames []string
+
+	switch {
+	case term.NamespaceSelector == nil && len(term.Namespaces) == 0:
+		namespaceNames = []string{podNamespace}
+	case term.NamespaceSelector == nil:
+		namespaceNames = term.Namespaces
+	default:
+		selector, err := metav1.LabelSelectorAsSelector(term.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace selector: %w", err)
+		}
+
+		// an empty namespace selector matches all the namespaces
+		if selector.Empty() {
+			break
+		}
+
+		namespaceNames = slices.Clone(term.Namespaces)
+
+		for _, namespace := range namespaces {
+			if selector.Matches(labels.Set(namespace.Labels)) && !slices.Contains(namespaceNames, namespace.Name) {
+				namespaceNames = append(namespaceNames, namespace.Name)
+			}
+		}
+
+		// a null label selector matches no pods
+		if len(namespaceNames) == 0 {
+			term.LabelSelector = nil
+		}
+	}
+
+	term.Namespaces = nil
+	term.NamespaceSelector = nil
+	term.LabelSelector = translateLabelSelector(term.LabelSelector, clusterName, namespaceNames)
+
+	return namespaceNames, nil
+}
+
+// translateAffinityNamespaces sets the host namespaces of the translated pod affinity term. With the "PerNamespace"
+// namespace mapping the pods are in the dedicated host namespaces of the selected virtual namespaces, or in any of the
+// host namespaces of the cluster if all the namespaces are selected.
+func (p *Provider) translateAffinityNamespaces(term *corev1.PodAffinityTerm, namespaceNames []string) {
+	if p.Translator.NamespaceMapping != v1alpha1.PerNamespaceMapping {
+		return
+	}
+
+	if len(namespaceNames) == 0 {
+		term.Namespaces = []string{p.ClusterNamespace}
+		term.NamespaceSelector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				translate.ClusterNameLabel:      p.ClusterName,
+				translate.ClusterNamespaceLabel: p.ClusterNamespace,
+			},
+		}
+
+		return
+	}
+
+	for _, namespace := range namespaceNames {
+		if hostNamespace := p.Translator.HostNamespace(namespace); !slices.Contains(term.Namespaces, hostNamespace) {
+			term.Namespaces = append(term.Namespaces, hostNamespace)
+		}
+	}
+}
+
+// translateLabelSelector restricts the label selector to the objects of the virtual cluster in the namespaces.
+// If no namespaces are provided the objects of all the namespaces are matched.
+func translateLabelSelector(selector *metav1.LabelSelector, clusterName string, namespaces []string) *metav1.LabelSelector {
+	if selector == nil {
+		return nil
+	}
+
+	selector = selector.DeepCopy()
+
+	if selector.MatchLabels == nil {
+		selector.MatchLabels = make(map[string]string)
+	}
+
+	selector.MatchLabels[translate.ClusterNameLabel] = clusterName
+
+	if len(namespaces) == 1 {
+		selector.MatchLabels[translate.ResourceNamespaceLabel] = namespaces[0]
+	} else if len(namespaces) > 1 {
+		selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      translate.ResourceNamespaceLabel,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   namespaces,
+		})
+	}
+
+	return selector
+}
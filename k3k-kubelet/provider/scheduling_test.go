@@ -0,0 +1,240 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/k3k-kubelet/translate"
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+func Test_mergeNodeSelector(t *testing.T) {
+	tests := []struct {
+		name                string
+		podNodeSelector     map[string]string
+		clusterNodeSelector map[string]string
+		want                map[string]string
+	}{
+		{
+			name:                "only cluster node selector",
+			clusterNodeSelector: map[string]string{"pool": "tenants"},
+			want:                map[string]string{"pool": "tenants"},
+		},
+		{
+			name:            "only pod node selector",
+			podNodeSelector: map[string]string{"disktype": "ssd"},
+			want:            map[string]string{"disktype": "ssd"},
+		},
+		{
+			name:                "cluster node selector takes precedence",
+			podNodeSelector:     map[string]string{"disktype": "ssd", "pool": "system"},
+			clusterNodeSelector: map[string]string{"pool": "tenants"},
+			want:                map[string]string{"disktype": "ssd", "pool": "tenants"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeNodeSelector(tt.podNodeSelector, tt.clusterNodeSelector); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeNodeSelector() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_translatePodAffinityTerm(t *testing.T) {
+	appSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+	namespaces := []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-a-dev", Labels: map[string]string{"team": "a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"team": "b"}}},
+	}
+
+	tests := []struct {
+		name string
+		term corev1.PodAffinityTerm
+		want *metav1.LabelSelector
+	}{
+		{
+			name: "pod namespace",
+			term: corev1.PodAffinityTerm{LabelSelector: appSelector, TopologyKey: "kubernetes.io/hostname"},
+			want: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app":                            "web",
+					translate.ClusterNameLabel:       "mycluster",
+					translate.ResourceNamespaceLabel: "default",
+				},
+			},
+		},
+		{
+			name: "namespaces",
+			term: corev1.PodAffinityTerm{LabelSelector: appSelector, Namespaces: []string{"team-a", "team-b"}},
+			want: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app":                      "web",
+					translate.ClusterNameLabel: "mycluster",
+				},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: translate.ResourceNamespaceLabel, Operator: metav1.LabelSelectorOpIn, Values: []string{"team-a", "team-b"}},
+				},
+			},
+		},
+		{
+			name: "namespace selector",
+			term: corev1.PodAffinityTerm{
+				LabelSelector:     appSelector,
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+			},
+			want: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app":                      "web",
+					translate.ClusterNameLabel: "mycluster",
+				},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: translate.ResourceNamespaceLabel, Operator: metav1.LabelSelectorOpIn, Values: []string{"team-a", "team-a-dev"}},
+				},
+			},
+		},
+		{
+			name: "empty namespace selector",
+			term: corev1.PodAffinityTerm{
+				LabelSelector:     appSelector,
+				NamespaceSelector: &metav1.LabelSelector{},
+			},
+			want: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app":                      "web",
+					translate.ClusterNameLabel: "mycluster",
+				},
+			},
+		},
+		{
+			name: "namespace selector not matching",
+			term: corev1.PodAffinityTerm{
+				LabelSelector:     appSelector,
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "c"}},
+			},
+			want: nil,
+		},
+		{
+			name: "null label selector",
+			term: corev1.PodAffinityTerm{Namespaces: []string{"team-a"}},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term := tt.term.DeepCopy()
+
+			if _, err := translatePodAffinityTerm(term, "mycluster", "default", namespaces); err != nil {
+				t.Fatalf("translatePodAffinityTerm() error = %v", err)
+			}
+
+			if term.Namespaces != nil || term.NamespaceSelector != nil {
+				t.Errorf("translatePodAffinityTerm() namespaces = %v, namespaceSelector = %v, want nil", term.Namespaces, term.NamespaceSelector)
+			}
+
+			if !reflect.DeepEqual(term.LabelSelector, tt.want) {
+				t.Errorf("translatePodAffinityTerm() labelSelector = %v, want %v", term.LabelSelector, tt.want)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(appSelector.MatchLabels, map[string]string{"app": "web"}) {
+		t.Errorf("translatePodAffinityTerm() modified the original label selector: %v", appSelector)
+	}
+}
+
+func TestProvider_translateAffinityNamespaces(t *testing.T) {
+	p := &Provider{
+		ClusterName:      "mycluster",
+		ClusterNamespace: "k3k-mycluster",
+		Translator: translate.ToHostTranslator{
+			ClusterName:      "mycluster",
+			ClusterNamespace: "k3k-mycluster",
+			NamespaceMapping: v1alpha1.PerNamespaceMapping,
+		},
+	}
+
+	tests := []struct {
+		name           string
+		namespaceNames []string
+		want           corev1.PodAffinityTerm
+	}{
+		{
+			name:           "namespaces",
+			namespaceNames: []string{"team-a", "kube-system", "team-b"},
+			want: corev1.PodAffinityTerm{
+				Namespaces: []string{"mycluster-team-a", "k3k-mycluster", "mycluster-team-b"},
+			},
+		},
+		{
+			name: "all namespaces",
+			want: corev1.PodAffinityTerm{
+				Namespaces: []string{"k3k-mycluster"},
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						translate.ClusterNameLabel:      "mycluster",
+						translate.ClusterNamespaceLabel: "k3k-mycluster",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var term corev1.PodAffinityTerm
+
+			p.translateAffinityNamespaces(&term, tt.namespaceNames)
+
+			if !reflect.DeepEqual(term, tt.want) {
+				t.Errorf("translateAffinityNamespaces() = %v, want %v", term, tt.want)
+			}
+		})
+	}
+
+	// the namespaces are unchanged with the default mapping
+	p.Translator.NamespaceMapping = v1alpha1.SingleNamespaceMapping
+
+	var term corev1.PodAffinityTerm
+
+	p.translateAffinityNamespaces(&term, []string{"team-a"})
+
+	if term.Namespaces != nil || term.NamespaceSelector != nil {
+		t.Errorf("translateAffinityNamespaces() = %v, want empty namespaces", term)
+	}
+}
+
+func Test_podAffinityTerms(t *testing.T) {
+	affinity := &corev1.Affinity{
+		PodAffinity: &corev1.PodAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{TopologyKey: "zone"}},
+		},
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{Weight: 10, PodAffinityTerm: corev1.PodAffinityTerm{TopologyKey: "kubernetes.io/hostname"}},
+			},
+		},
+	}
+
+	terms := podAffinityTerms(affinity)
+	if len(terms) != 2 {
+		t.Fatalf("podAffinityTerms() returned %d terms, want 2", len(terms))
+	}
+
+	terms[1].TopologyKey = "rack"
+
+	if got := affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm.TopologyKey; got != "rack" {
+		t.Errorf("podAffinityTerms() did not return a reference to the term, topologyKey = %s", got)
+	}
+
+	if terms := podAffinityTerms(nil); len(terms) != 0 {
+		t.Errorf("podAffinityTerms(nil) = %v, want no terms", terms)
+	}
+}
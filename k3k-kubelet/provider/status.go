@@ -0,0 +1,55 @@
+package provider
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// translatePodStatus returns the status of the host pod to report for the virtual pod. The statuses of the containers
+// are filtered and ordered as the containers of the virtual pod, since the host pod could have containers injected
+// by the host cluster (i.e. by mutating webhooks), and the init containers include the sidecars running with the pod.
+func translatePodStatus(virtualPod *corev1.Pod, hostStatus *corev1.PodStatus) *corev1.PodStatus {
+	status := hostStatus.DeepCopy()
+
+	initContainers := make([]string, 0, len(virtualPod.Spec.InitContainers))
+	for _, container := range virtualPod.Spec.InitContainers {
+		initContainers = append(initContainers, container.Name)
+	}
+
+	containers := make([]string, 0, len(virtualPod.Spec.Containers))
+	for _, container := range virtualPod.Spec.Containers {
+		containers = append(containers, container.Name)
+	}
+
+	ephemeralContainers := make([]string, 0, len(virtualPod.Spec.EphemeralContainers))
+	for _, container := range virtualPod.Spec.EphemeralContainers {
+		ephemeralContainers = append(ephemeralContainers, container.Name)
+	}
+
+	status.InitContainerStatuses = orderContainerStatuses(initContainers, status.InitContainerStatuses)
+	status.ContainerStatuses = orderContainerStatuses(containers, status.ContainerStatuses)
+	status.EphemeralContainerStatuses = orderContainerStatuses(ephemeralContainers, status.EphemeralContainerStatuses)
+
+	return status
+}
+
+// orderContainerStatuses returns the statuses of the named containers, in the same order
+func orderContainerStatuses(names []string, statuses []corev1.ContainerStatus) []corev1.ContainerStatus {
+	if statuses == nil {
+		return nil
+	}
+
+	statusByName := make(map[string]corev1.ContainerStatus, len(statuses))
+	for _, status := range statuses {
+		statusByName[status.Name] = status
+	}
+
+	orderedStatuses := make([]corev1.ContainerStatus, 0, len(names))
+
+	for _, name := range names {
+		if status, found := statusByName[name]; found {
+			orderedStatuses = append(orderedStatuses, status)
+		}
+	}
+
+	return orderedStatuses
+}
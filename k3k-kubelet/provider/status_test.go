@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/utils/ptr"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_translatePodStatus(t *testing.T) {
+	always := ptr.To(corev1.ContainerRestartPolicyAlways)
+
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	terminated := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"}}
+
+	tests := []struct {
+		name       string
+		virtualPod *corev1.Pod
+		hostStatus *corev1.PodStatus
+		want       *corev1.PodStatus
+	}{
+		{
+			name: "same containers",
+			virtualPod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app"}},
+				},
+			},
+			hostStatus: &corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "app", Ready: true, State: running}},
+			},
+			want: &corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "app", Ready: true, State: running}},
+			},
+		},
+		{
+			name: "sidecar and init container statuses ordered as the spec",
+			virtualPod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{Name: "migrate"},
+						{Name: "proxy", RestartPolicy: always},
+					},
+					Containers: []corev1.Container{{Name: "app"}, {Name: "worker"}},
+				},
+			},
+			hostStatus: &corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: "proxy", Ready: true, Started: ptr.To(true), State: running},
+					{Name: "migrate", State: terminated},
+				},
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "worker", Ready: false, State: running},
+					{Name: "app", Ready: true, State: running},
+				},
+			},
+			want: &corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: "migrate", State: terminated},
+					{Name: "proxy", Ready: true, Started: ptr.To(true), State: running},
+				},
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "app", Ready: true, State: running},
+					{Name: "worker", Ready: false, State: running},
+				},
+			},
+		},
+		{
+			name: "containers injected in the host pod",
+			virtualPod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app"}},
+				},
+			},
+			hostStatus: &corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: "istio-init", State: terminated},
+				},
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "app", Ready: true, State: running},
+					{Name: "istio-proxy", Ready: true, State: running},
+				},
+			},
+			want: &corev1.PodStatus{
+				Phase:                 corev1.PodRunning,
+				InitContainerStatuses: []corev1.ContainerStatus{},
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "app", Ready: true, State: running},
+				},
+			},
+		},
+		{
+			name: "ephemeral containers",
+			virtualPod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app"}},
+					EphemeralContainers: []corev1.EphemeralContainer{
+						{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger"}},
+					},
+				},
+			},
+			hostStatus: &corev1.PodStatus{
+				Phase:                      corev1.PodRunning,
+				ContainerStatuses:          []corev1.ContainerStatus{{Name: "app", Ready: true, State: running}},
+				EphemeralContainerStatuses: []corev1.ContainerStatus{{Name: "debugger", State: running}},
+			},
+			want: &corev1.PodStatus{
+				Phase:                      corev1.PodRunning,
+				ContainerStatuses:          []corev1.ContainerStatus{{Name: "app", Ready: true, State: running}},
+				EphemeralContainerStatuses: []corev1.ContainerStatus{{Name: "debugger", State: running}},
+			},
+		},
+		{
+			name: "pending pod without statuses",
+			virtualPod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "proxy", RestartPolicy: always}},
+					Containers:     []corev1.Container{{Name: "app"}},
+				},
+			},
+			hostStatus: &corev1.PodStatus{Phase: corev1.PodPending},
+			want:       &corev1.PodStatus{Phase: corev1.PodPending},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := translatePodStatus(tt.virtualPod, tt.hostStatus); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("translatePodStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+// nodeTaintsAnnotation records the taints of the virtual node managed by the k3k-kubelet,
+// so that the taints added by the virtual cluster (i.e. unreachable) or by the users are preserved
+const nodeTaintsAnnotation = "k3k.io/node-taints"
+
+// hostNodeTaints returns the taints of the host node mirrored on the virtual node. The taints managed by the node
+// lifecycle controller are skipped, since they are set by the virtual cluster on its own nodes.
+func hostNodeTaints(hostNode *corev1.Node) []corev1.Taint {
+	var taints []corev1.Taint
+
+	for _, taint := range hostNode.Spec.Taints {
+		if !strings.HasPrefix(taint.Key, "node.kubernetes.io/") {
+			taints = append(taints, taint)
+		}
+	}
+
+	return taints
+}
+
+// managedNodeTaints returns the taints of the virtual node managed by the k3k-kubelet
+func managedNodeTaints(node *corev1.Node) ([]corev1.Taint, error) {
+	value, found := node.Annotations[nodeTaintsAnnotation]
+	if !found {
+		return nil, nil
+	}
+
+	var taints []corev1.Taint
+	if err := json.Unmarshal([]byte(value), &taints); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation on node %s: %w", nodeTaintsAnnotation, node.Name, err)
+	}
+
+	return taints, nil
+}
+
+// setNodeTaints replaces the taints of the virtual node managed by the k3k-kubelet, and returns true if they changed
+func setNodeTaints(node *corev1.Node, taints []corev1.Taint) (bool, error) {
+	currentTaints, err := managedNodeTaints(node)
+	if err != nil {
+		return false, err
+	}
+
+	if taints == nil {
+		taints = []corev1.Taint{}
+	}
+
+	value, err := json.Marshal(taints)
+	if err != nil {
+		return false, err
+	}
+
+	nodeTaints := slices.DeleteFunc(slices.Clone(node.Spec.Taints), func(taint corev1.Taint) bool {
+		matchTaint := func(t corev1.Taint) bool { return taint.MatchTaint(&t) }
+		return slices.ContainsFunc(currentTaints, matchTaint) || slices.ContainsFunc(taints, matchTaint)
+	})
+
+	nodeTaints = append(nodeTaints, taints...)
+
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+
+	changed := !reflect.DeepEqual(node.Spec.Taints, nodeTaints) || node.Annotations[nodeTaintsAnnotation] != string(value)
+
+	node.Spec.Taints = nodeTaints
+	node.Annotations[nodeTaintsAnnotation] = string(value)
+
+	return changed, nil
+}
+
+// updateNodeTaints updates the taints of the virtual node with the ones of the cluster
+func updateNodeTaints(ctx context.Context, hostClient, virtualClient client.Client, virtualNodeName string, clusterKey types.NamespacedName) error {
+	var cluster v1alpha1.Cluster
+	if err := hostClient.Get(ctx, clusterKey, &cluster); err != nil {
+		return err
+	}
+
+	var virtualNode corev1.Node
+	if err := virtualClient.Get(ctx, types.NamespacedName{Name: virtualNodeName}, &virtualNode); err != nil {
+		return err
+	}
+
+	changed, err := setNodeTaints(&virtualNode, cluster.Spec.NodeTaints)
+	if err != nil || !changed {
+		return err
+	}
+
+	return virtualClient.Update(ctx, &virtualNode)
+}
+
+// updateMirroredNodeTaints updates the taints of the virtual node with the ones of the mirrored host node, with the
+// same name
+func updateMirroredNodeTaints(ctx context.Context, virtualClient client.Client, hostNode *corev1.Node) error {
+	var virtualNode corev1.Node
+	if err := virtualClient.Get(ctx, types.NamespacedName{Name: hostNode.Name}, &virtualNode); err != nil {
+		return err
+	}
+
+	changed, err := setNodeTaints(&virtualNode, hostNodeTaints(hostNode))
+	if err != nil || !changed {
+		return err
+	}
+
+	return virtualClient.Update(ctx, &virtualNode)
+}
+
+// translateNodeTaintTolerations adds to the host pod the tolerations of the taints of the virtual node tolerated
+// by the virtual pod. Only the taints managed by the k3k-kubelet, declared in the cluster or mirrored from the host
+// node, are translated: they are tolerated in the host cluster even if not allowed by the VirtualClusterPolicy.
+func (p *Provider) translateNodeTaintTolerations(ctx context.Context, virtualPod, tPod *corev1.Pod) error {
+	if virtualPod.Spec.NodeName == "" {
+		return nil
+	}
+
+	var virtualNode corev1.Node
+	if err := p.VirtualClient.Get(ctx, types.NamespacedName{Name: virtualPod.Spec.NodeName}, &virtualNode); err != nil {
+		return fmt.Errorf("unable to get node %s: %w", virtualPod.Spec.NodeName, err)
+	}
+
+	taints, err := managedNodeTaints(&virtualNode)
+	if err != nil {
+		return err
+	}
+
+	tPod.Spec.Tolerations = append(tPod.Spec.Tolerations, nodeTaintTolerations(virtualPod.Spec.Tolerations, tPod.Spec.Tolerations, taints)...)
+
+	return nil
+}
+
+// nodeTaintTolerations returns the tolerations of the taints tolerated by the pod tolerations, and not already
+// tolerated by the host tolerations. The tolerations match exactly the taints, so that they don't allow other taints.
+func nodeTaintTolerations(podTolerations, hostTolerations []corev1.Toleration, taints []corev1.Taint) []corev1.Toleration {
+	var tolerations []corev1.Toleration
+
+	for _, taint := range taints {
+		i := slices.IndexFunc(podTolerations, func(toleration corev1.Toleration) bool { return toleration.ToleratesTaint(&taint) })
+		if i < 0 {
+			continue
+		}
+
+		if slices.ContainsFunc(hostTolerations, func(toleration corev1.Toleration) bool { return toleration.ToleratesTaint(&taint) }) {
+			continue
+		}
+
+		toleration := corev1.Toleration{
+			Key:      taint.Key,
+			Operator: corev1.TolerationOpEqual,
+			Value:    taint.Value,
+			Effect:   taint.Effect,
+		}
+
+		if taint.Value == "" {
+			toleration.Operator = corev1.TolerationOpExists
+		}
+
+		if taint.Effect == corev1.TaintEffectNoExecute {
+			toleration.TolerationSeconds = podTolerations[i].TolerationSeconds
+		}
+
+		tolerations = append(tolerations, toleration)
+	}
+
+	return tolerations
+}
@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_setNodeTaints(t *testing.T) {
+	gpuTaint := corev1.Taint{Key: "nvidia.com/gpu", Value: "present", Effect: corev1.TaintEffectNoSchedule}
+	spotTaint := corev1.Taint{Key: "spot", Effect: corev1.TaintEffectNoExecute}
+	unreachableTaint := corev1.Taint{Key: corev1.TaintNodeUnreachable, Effect: corev1.TaintEffectNoExecute}
+
+	node := &corev1.Node{}
+
+	changed, err := setNodeTaints(node, []corev1.Taint{gpuTaint, spotTaint})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []corev1.Taint{gpuTaint, spotTaint}, node.Spec.Taints)
+
+	changed, err = setNodeTaints(node, []corev1.Taint{gpuTaint, spotTaint})
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// the taints not managed by the k3k-kubelet are preserved
+	node.Spec.Taints = append(node.Spec.Taints, unreachableTaint)
+
+	changed, err = setNodeTaints(node, []corev1.Taint{gpuTaint})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []corev1.Taint{unreachableTaint, gpuTaint}, node.Spec.Taints)
+
+	taints, err := managedNodeTaints(node)
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.Taint{gpuTaint}, taints)
+
+	changed, err = setNodeTaints(node, nil)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []corev1.Taint{unreachableTaint}, node.Spec.Taints)
+}
+
+func Test_hostNodeTaints(t *testing.T) {
+	gpuTaint := corev1.Taint{Key: "nvidia.com/gpu", Value: "present", Effect: corev1.TaintEffectNoSchedule}
+
+	hostNode := &corev1.Node{
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{
+				gpuTaint,
+				{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule},
+				{Key: corev1.TaintNodeNotReady, Effect: corev1.TaintEffectNoExecute},
+			},
+		},
+	}
+
+	assert.Equal(t, []corev1.Taint{gpuTaint}, hostNodeTaints(hostNode))
+}
+
+func Test_nodeTaintTolerations(t *testing.T) {
+	gpuTaint := corev1.Taint{Key: "nvidia.com/gpu", Value: "present", Effect: corev1.TaintEffectNoSchedule}
+	spotTaint := corev1.Taint{Key: "spot", Effect: corev1.TaintEffectNoExecute}
+	poolTaint := corev1.Taint{Key: "pool", Value: "batch", Effect: corev1.TaintEffectNoSchedule}
+
+	tests := []struct {
+		name            string
+		podTolerations  []corev1.Toleration
+		hostTolerations []corev1.Toleration
+		want            []corev1.Toleration
+	}{
+		{
+			name: "no tolerations",
+		},
+		{
+			name: "toleration of all the taints",
+			podTolerations: []corev1.Toleration{
+				{Operator: corev1.TolerationOpExists, TolerationSeconds: ptr.To(int64(60))},
+			},
+			want: []corev1.Toleration{
+				{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpEqual, Value: "present", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "spot", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: ptr.To(int64(60))},
+				{Key: "pool", Operator: corev1.TolerationOpEqual, Value: "batch", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+		{
+			name: "taint already tolerated in the host cluster",
+			podTolerations: []corev1.Toleration{
+				{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists},
+				{Key: "pool", Operator: corev1.TolerationOpEqual, Value: "batch"},
+			},
+			hostTolerations: []corev1.Toleration{
+				{Key: "pool", Operator: corev1.TolerationOpEqual, Value: "batch"},
+			},
+			want: []corev1.Toleration{
+				{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpEqual, Value: "present", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nodeTaintTolerations(tt.podTolerations, tt.hostTolerations, []corev1.Taint{gpuTaint, spotTaint, poolTaint})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
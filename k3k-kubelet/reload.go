@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+const (
+	logLevelControllerName = "kubelet-log-level"
+
+	// kubeletLogLevelAnnotation sets the log level of the k3k-kubelet of the cluster (debug, info, warn or error),
+	// it takes precedence over the debug option of the config
+	kubeletLogLevelAnnotation = "k3k.io/kubelet-log-level"
+)
+
+// watchConfig watches the config file, and applies the changes that don't require a restart of the k3k-kubelet
+func (k *kubelet) watchConfig() {
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		var newConfig config
+		if err := viper.Unmarshal(&newConfig); err != nil {
+			k.logger.Errorw("failed to unmarshal the updated config", "file", e.Name, zap.Error(err))
+			return
+		}
+
+		k.reloadConfig(&newConfig)
+	})
+
+	viper.WatchConfig()
+}
+
+// reloadConfig applies the log level and the version of the new config. The other fields are applied when the
+// k3k-kubelet restarts: the controller restarts the pods of the agents when they change.
+func (k *kubelet) reloadConfig(newConfig *config) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if fields := k.config.changedStartupFields(newConfig); len(fields) > 0 {
+		k.logger.Infow("config changes will be applied by the restart of the k3k-kubelet", "fields", fields)
+	}
+
+	if k.config.Version != newConfig.Version {
+		k.logger.Infow("updating the kubelet version of the node", "version", newConfig.Version)
+
+		k.config.Version = newConfig.Version
+		if k.nodeProvider != nil {
+			k.nodeProvider.SetKubeletVersion(newConfig.Version)
+		}
+	}
+
+	k.config.Debug = newConfig.Debug
+	k.applyLogLevel()
+}
+
+// addLogLevelController adds a controller to the host manager watching the log level annotation of the cluster.
+// The controller doesn't need the leader election, since every replica sets its own log level.
+func (k *kubelet) addLogLevelController() error {
+	isCluster := func(object ctrlruntimeclient.Object) bool {
+		return object.GetName() == k.config.ClusterName && object.GetNamespace() == k.config.ClusterNamespace
+	}
+
+	return ctrl.NewControllerManagedBy(k.hostMgr).
+		Named(logLevelControllerName).
+		For(&v1alpha1.Cluster{}).
+		WithEventFilter(predicate.NewPredicateFuncs(isCluster)).
+		WithOptions(controller.Options{NeedLeaderElection: ptr.To(false)}).
+		Complete(reconcile.Func(k.reconcileLogLevel))
+}
+
+func (k *kubelet) reconcileLogLevel(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	var cluster v1alpha1.Cluster
+	if err := k.hostMgr.GetClient().Get(ctx, req.NamespacedName, &cluster); err != nil {
+		return reconcile.Result{}, ctrlruntimeclient.IgnoreNotFound(err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.logLevelAnnotation = cluster.Annotations[kubeletLogLevelAnnotation]
+	k.applyLogLevel()
+
+	return reconcile.Result{}, nil
+}
+
+// applyLogLevel sets the level of the logger, the caller must hold the lock
+func (k *kubelet) applyLogLevel() {
+	level, err := logLevel(k.config.Debug, k.logLevelAnnotation)
+	if err != nil {
+		k.logger.Errorw("invalid log level", zap.Error(err))
+	}
+
+	k.logger.SetLevel(level)
+}
+
+// logLevel returns the log level from the annotation of the cluster if set, or from the debug option. An invalid
+// annotation is reported, and the level of the debug option is used.
+func logLevel(debug bool, annotation string) (zapcore.Level, error) {
+	level := zapcore.InfoLevel
+	if debug {
+		level = zapcore.DebugLevel
+	}
+
+	if annotation == "" {
+		return level, nil
+	}
+
+	switch annotationLevel, err := zapcore.ParseLevel(annotation); {
+	case err != nil:
+		return level, fmt.Errorf("invalid %s annotation: %w", kubeletLogLevelAnnotation, err)
+	case annotationLevel > zapcore.ErrorLevel:
+		return level, fmt.Errorf("invalid %s annotation: unsupported level %q", kubeletLogLevelAnnotation, annotation)
+	default:
+		return annotationLevel, nil
+	}
+}
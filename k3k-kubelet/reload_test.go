@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func Test_changedStartupFields(t *testing.T) {
+	current := &config{
+		ClusterName:     "mycluster",
+		Version:         "v1.31.4-k3s1",
+		KubeletPort:     10250,
+		MirrorHostNodes: false,
+	}
+
+	newConfig := *current
+	newConfig.Version = "v1.32.1-k3s1"
+	newConfig.Debug = true
+
+	assert.Empty(t, current.changedStartupFields(&newConfig))
+
+	newConfig.KubeletPort = 10251
+	newConfig.MirrorHostNodes = true
+
+	assert.Equal(t, []string{"kubeletPort", "mirrorHostNodes"}, current.changedStartupFields(&newConfig))
+}
+
+func Test_logLevel(t *testing.T) {
+	tests := []struct {
+		name       string
+		debug      bool
+		annotation string
+		want       zapcore.Level
+		wantErr    bool
+	}{
+		{name: "info", want: zapcore.InfoLevel},
+		{name: "debug", debug: true, want: zapcore.DebugLevel},
+		{name: "annotation takes precedence", debug: true, annotation: "warn", want: zapcore.WarnLevel},
+		{name: "annotation debug", annotation: "debug", want: zapcore.DebugLevel},
+		{name: "invalid annotation", debug: true, annotation: "verbose", want: zapcore.DebugLevel, wantErr: true},
+		{name: "unsupported annotation", annotation: "fatal", want: zapcore.InfoLevel, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, err := logLevel(tt.debug, tt.annotation)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.want, level)
+		})
+	}
+}
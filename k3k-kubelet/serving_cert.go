@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/certificate"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/k3k-kubelet/controller/csr"
+	k3klog "github.com/rancher/k3k/pkg/log"
+)
+
+const (
+	authenticationConfigMapName = "extension-apiserver-authentication"
+	clientCAKey                 = "client-ca-file"
+)
+
+// newServingCertificateManager returns a certificate manager requesting the serving certificate of the node
+// with a CertificateSigningRequest to the kubelet-serving signer of the virtual cluster, as a kubelet with
+// serverTLSBootstrap. The certificate is rotated before its expiration.
+func newServingCertificateManager(virtClient kubernetes.Interface, nodeName, hostname, agentIP string, logger *k3klog.Logger) (certificate.Manager, error) {
+	var ips []net.IP
+
+	for _, address := range []string{agentIP, os.Getenv("POD_IP")} {
+		if ip := net.ParseIP(address); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+
+	return certificate.NewManager(&certificate.Config{
+		ClientsetFn: func(_ *tls.Certificate) (kubernetes.Interface, error) {
+			return virtClient, nil
+		},
+		Template: &x509.CertificateRequest{
+			Subject: pkix.Name{
+				CommonName:   csr.NodeUserPrefix + nodeName,
+				Organization: []string{csr.NodesGroup},
+			},
+			DNSNames:    []string{hostname},
+			IPAddresses: ips,
+		},
+		SignerName: certificatesv1.KubeletServingSignerName,
+		Usages: []certificatesv1.KeyUsage{
+			certificatesv1.UsageDigitalSignature,
+			certificatesv1.UsageServerAuth,
+		},
+		CertificateStore: &memoryStore{},
+		Name:             "kubelet-serving",
+		Logf:             logger.Infof,
+	})
+}
+
+// servingTLSConfig returns the TLS configuration of the kubelet server, reloading the current certificate
+// of the manager on every handshake. The client certificates are verified with the client CAs, to authenticate
+// the requests of the virtual API server.
+func servingTLSConfig(certManager certificate.Manager, clientCAs *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  clientCAs,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert := certManager.Current()
+			if cert == nil {
+				return nil, errors.New("no serving certificate available for the kubelet")
+			}
+
+			return cert, nil
+		},
+	}
+}
+
+// clientCAs returns the client CAs of the virtual API server, published in the extension-apiserver-authentication
+// ConfigMap of the kube-system namespace
+func clientCAs(ctx context.Context, virtClient kubernetes.Interface) (*x509.CertPool, error) {
+	configMap, err := virtClient.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(ctx, authenticationConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(configMap.Data[clientCAKey])) {
+		return nil, errors.New("no client CA in the " + authenticationConfigMapName + " configmap")
+	}
+
+	return pool, nil
+}
+
+// requestUser returns the user authenticated by the verified client certificate of the request
+func requestUser(req *http.Request) string {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	return req.TLS.VerifiedChains[0][0].Subject.CommonName
+}
+
+// certificateUser returns the user authenticated by the client certificate of the config
+func certificateUser(config *rest.Config) (string, error) {
+	if err := rest.LoadTLSFiles(config); err != nil {
+		return "", err
+	}
+
+	block, _ := pem.Decode(config.CertData)
+	if block == nil {
+		return "", errors.New("no client certificate in the virtual cluster config")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse the client certificate: %w", err)
+	}
+
+	return cert.Subject.CommonName, nil
+}
+
+// memoryStore keeps the serving certificate in memory, a new certificate is requested on restart
+type memoryStore struct {
+	mu   sync.Mutex
+	cert *tls.Certificate
+}
+
+func (s *memoryStore) Current() (*tls.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cert == nil {
+		noCertKeyErr := certificate.NoCertKeyError("no serving certificate in memory")
+		return nil, &noCertKeyErr
+	}
+
+	return s.cert, nil
+}
+
+func (s *memoryStore) Update(certData, keyData []byte) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(certData, keyData)
+	if err != nil {
+		return nil, err
+	}
+
+	// the manager relies on the leaf to compute the rotation deadline
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cert = &cert
+
+	return s.cert, nil
+}
@@ -1,11 +1,21 @@
 package translate
 
 import (
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
 	"github.com/rancher/k3k/pkg/controller"
 )
 
@@ -13,18 +23,34 @@ const (
 	// ClusterNameLabel is the key for the label that contains the name of the virtual cluster
 	// this resource was made in
 	ClusterNameLabel = "k3k.io/clusterName"
+	// ResourceNamespaceLabel is the key for the label that contains the namespace of the virtual cluster
+	// this resource was made in
+	ResourceNamespaceLabel = "k3k.io/namespace"
 	// ResourceNameAnnotation is the key for the annotation that contains the original name of this
 	// resource in the virtual cluster
 	ResourceNameAnnotation = "k3k.io/name"
 	// ResourceNamespaceAnnotation is the key for the annotation that contains the original namespace of this
 	// resource in the virtual cluster
 	ResourceNamespaceAnnotation = "k3k.io/namespace"
+	// ClusterNamespaceLabel is the key for the label that contains the namespace of the virtual cluster
+	// a dedicated host namespace was created for
+	ClusterNamespaceLabel = "k3k.io/clusterNamespace"
 	// MetadataNameField is the downwardapi field for object's name
 	MetadataNameField = "metadata.name"
 	// MetadataNamespaceField is the downward field for the object's namespace
 	MetadataNamespaceField = "metadata.namespace"
+
+	// maxNameLength is the maximum length of the translated names, as a DNS label
+	maxNameLength = 63
+	// shortHashLength is the length of the hash of the "ShortHash" naming strategy
+	shortHashLength = 8
+	// readableSeparator is the separator of the names with the "Readable" naming strategy
+	readableSeparator = "-x-"
 )
 
+// ErrNameCollision is returned when the translated name of a virtual object is used by another object in the host cluster
+var ErrNameCollision = errors.New("name collision")
+
 type ToHostTranslator struct {
 	// ClusterName is the name of the virtual cluster whose resources we are
 	// translating to a host cluster
@@ -32,6 +58,12 @@ type ToHostTranslator struct {
 	// ClusterNamespace is the namespace of the virtual cluster whose resources
 	// we are translating to a host cluster
 	ClusterNamespace string
+	// NamingStrategy is the strategy used to translate the names, "Hash" if empty
+	NamingStrategy v1alpha1.NamingStrategy
+	// Index is the optional reverse lookup index of the names of the objects translated from the host cluster
+	Index *NameIndex
+	// NamespaceMapping is the mapping of the virtual namespaces to the host namespaces, "Single" if empty
+	NamespaceMapping v1alpha1.NamespaceMapping
 }
 
 // Translate translates a virtual cluster object to a host cluster object. This should only be used for
@@ -58,6 +90,12 @@ func (t *ToHostTranslator) TranslateTo(obj client.Object) {
 	}
 
 	labels[ClusterNameLabel] = t.ClusterName
+
+	// and the namespace, so that label selectors can match the objects of a virtual namespace
+	if obj.GetNamespace() != "" {
+		labels[ResourceNamespaceLabel] = obj.GetNamespace()
+	}
+
 	obj.SetLabels(labels)
 
 	// resource version/UID won't match what's in the host cluster.
@@ -67,35 +105,109 @@ func (t *ToHostTranslator) TranslateTo(obj client.Object) {
 	// set the name and the namespace so that this goes in the proper host namespace
 	// and doesn't collide with other resources
 	obj.SetName(t.TranslateName(obj.GetNamespace(), obj.GetName()))
-	obj.SetNamespace(t.ClusterNamespace)
+	obj.SetNamespace(t.HostNamespace(obj.GetNamespace()))
 	obj.SetFinalizers(nil)
 }
 
-func (t *ToHostTranslator) TranslateFrom(obj client.Object) {
+// TranslateFrom translates a host cluster object to a virtual cluster object. It returns false if the original
+// name of the object can't be found, in this case the name of the object is not changed.
+func (t *ToHostTranslator) TranslateFrom(obj client.Object) bool {
 	// owning objects may be in the virtual cluster, but may not be in the host cluster
 	obj.SetOwnerReferences(nil)
 
+	virtualName, found := t.VirtualName(obj)
+	if found {
+		obj.SetName(virtualName.Name)
+		obj.SetNamespace(virtualName.Namespace)
+	}
+
 	// remove the annotations added to track original name
 	annotations := obj.GetAnnotations()
-	// TODO: It's possible that this was erased by a change on the host cluster
-	// In this case, we need to have some sort of fallback or error return
-	name := annotations[ResourceNameAnnotation]
-	namespace := annotations[ResourceNamespaceAnnotation]
-
-	obj.SetName(name)
-	obj.SetNamespace(namespace)
 	delete(annotations, ResourceNameAnnotation)
 	delete(annotations, ResourceNamespaceAnnotation)
 	obj.SetAnnotations(annotations)
 
-	// remove the clusteName tracking label
+	// remove the clusteName and namespace tracking labels
 	labels := obj.GetLabels()
 	delete(labels, ClusterNameLabel)
+	delete(labels, ResourceNamespaceLabel)
 	obj.SetLabels(labels)
 
 	// resource version/UID won't match what's in the virtual cluster.
 	obj.SetResourceVersion("")
 	obj.SetUID("")
+
+	return found
+}
+
+// VirtualName returns the name and namespace in the virtual cluster of a host cluster object. They are read from
+// the annotations of the object, and recorded in the index. If the annotations were erased by a change on the host
+// cluster the name is looked up in the index, or parsed with the "Readable" naming strategy.
+func (t *ToHostTranslator) VirtualName(obj client.Object) (types.NamespacedName, bool) {
+	annotations := obj.GetAnnotations()
+
+	if name, found := annotations[ResourceNameAnnotation]; found {
+		virtualName := types.NamespacedName{
+			Name:      name,
+			Namespace: annotations[ResourceNamespaceAnnotation],
+		}
+
+		t.Index.Add(obj.GetName(), virtualName)
+
+		return virtualName, true
+	}
+
+	if virtualName, found := t.Index.Get(obj.GetName()); found {
+		return virtualName, true
+	}
+
+	return t.parseReadableName(obj.GetName())
+}
+
+// CheckCollision returns an error if the host cluster object, with the translated name of the virtual object,
+// belongs to another cluster or to another virtual object.
+func (t *ToHostTranslator) CheckCollision(hostObj client.Object, namespace, name string) error {
+	if hostObj.GetLabels()[ClusterNameLabel] != t.ClusterName {
+		return fmt.Errorf("%w: %s does not belong to cluster %s", ErrNameCollision, hostObj.GetName(), t.ClusterName)
+	}
+
+	virtualName, found := t.VirtualName(hostObj)
+	if !found || virtualName.Namespace != namespace || virtualName.Name != name {
+		return fmt.Errorf("%w: %s does not belong to %s/%s", ErrNameCollision, hostObj.GetName(), namespace, name)
+	}
+
+	return nil
+}
+
+// HostNamespace returns the namespace in the host cluster of the resources of a virtual namespace. With the
+// "PerNamespace" mapping each virtual namespace has a dedicated host namespace, except "kube-system", whose
+// resources (i.e. coredns) stay in the namespace of the cluster with the services exposing them.
+func (t *ToHostTranslator) HostNamespace(namespace string) string {
+	if t.NamespaceMapping != v1alpha1.PerNamespaceMapping || namespace == "" || namespace == metav1.NamespaceSystem {
+		return t.ClusterNamespace
+	}
+
+	return controller.SafeConcatName(t.ClusterName, namespace)
+}
+
+// IsHostNamespace returns true if the namespace of the host cluster contains resources of the virtual cluster
+func (t *ToHostTranslator) IsHostNamespace(namespace *corev1.Namespace) bool {
+	if namespace.Name == t.ClusterNamespace {
+		return true
+	}
+
+	return namespace.Labels[ClusterNameLabel] == t.ClusterName && namespace.Labels[ClusterNamespaceLabel] == t.ClusterNamespace
+}
+
+// SetControllerReference sets the cluster as the controller of the host object. The objects in a dedicated host
+// namespace are not owned by the cluster, since the owner must be in the same namespace: they are deleted with
+// their namespace.
+func (t *ToHostTranslator) SetControllerReference(cluster *v1alpha1.Cluster, obj client.Object, scheme *runtime.Scheme) error {
+	if obj.GetNamespace() != cluster.Namespace {
+		return nil
+	}
+
+	return controllerutil.SetControllerReference(cluster, obj, scheme)
 }
 
 // TranslateName returns the name of the resource in the host cluster. Will not update the object with this name.
@@ -110,12 +222,22 @@ func (t *ToHostTranslator) TranslateName(namespace string, name string) string {
 		names = []string{name, namespace, t.ClusterName}
 	}
 
-	// we need to come up with a name which is:
-	// - somewhat connectable to the original resource
-	// - a valid k8s name
-	// - idempotently calculatable
-	// - unique for this combination of name/namespace/cluster
+	switch t.NamingStrategy {
+	case v1alpha1.ShortHashNamingStrategy:
+		return shortHashName(names)
+	case v1alpha1.ReadableNamingStrategy:
+		return readableName(names)
+	default:
+		return hashName(names)
+	}
+}
 
+// hashName returns a name which is:
+// - somewhat connectable to the original resource
+// - a valid k8s name
+// - idempotently calculatable
+// - unique for this combination of name/namespace/cluster
+func hashName(names []string) string {
 	namePrefix := strings.Join(names, "-")
 
 	// use + as a separator since it can't be in an object name
@@ -125,3 +247,58 @@ func (t *ToHostTranslator) TranslateName(namespace string, name string) string {
 
 	return controller.SafeConcatName(namePrefix, nameSuffix)
 }
+
+// shortHashName returns the joined names with a short stable hash of the name/namespace/cluster, truncating the
+// names so that the hash is always kept
+func shortHashName(names []string) string {
+	digest := sha256.Sum256([]byte(strings.Join(names, "+")))
+	nameSuffix := hex.EncodeToString(digest[:])[:shortHashLength]
+
+	namePrefix := strings.Join(names, "-")
+	if maxPrefixLength := maxNameLength - shortHashLength - 1; len(namePrefix) > maxPrefixLength {
+		namePrefix = strings.TrimRight(namePrefix[:maxPrefixLength], "-.")
+	}
+
+	return namePrefix + "-" + nameSuffix
+}
+
+// readableName returns the names joined with the "-x-" separator. The names containing the separator, or starting
+// or ending with part of it, would make the translated name ambiguous, and could collide with the name of another
+// resource: for these names, and for the names too long, the short hash is used.
+func readableName(names []string) string {
+	for _, name := range names {
+		if strings.Contains(name, readableSeparator) ||
+			strings.HasPrefix(name, "x-") ||
+			strings.HasSuffix(name, "-x") {
+			return shortHashName(names)
+		}
+	}
+
+	readable := strings.Join(names, readableSeparator)
+	if len(readable) > maxNameLength {
+		return shortHashName(names)
+	}
+
+	return readable
+}
+
+// parseReadableName returns the name and namespace of a host name translated with the "Readable" naming strategy
+func (t *ToHostTranslator) parseReadableName(hostName string) (types.NamespacedName, bool) {
+	if t.NamingStrategy != v1alpha1.ReadableNamingStrategy {
+		return types.NamespacedName{}, false
+	}
+
+	names := strings.Split(hostName, readableSeparator)
+	if names[len(names)-1] != t.ClusterName {
+		return types.NamespacedName{}, false
+	}
+
+	switch len(names) {
+	case 2:
+		return types.NamespacedName{Name: names[0]}, true
+	case 3:
+		return types.NamespacedName{Name: names[0], Namespace: names[1]}, true
+	default:
+		return types.NamespacedName{}, false
+	}
+}
@@ -0,0 +1,200 @@
+package translate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+)
+
+func TestToHostTranslator_TranslateName(t *testing.T) {
+	tests := []struct {
+		name      string
+		strategy  v1alpha1.NamingStrategy
+		namespace string
+		objName   string
+		expected  string
+	}{
+		{
+			name:      "hash",
+			strategy:  v1alpha1.HashNamingStrategy,
+			namespace: "default",
+			objName:   "web",
+			expected:  "web-default-mycluster-7765622b64656661756c742b6d79636c757-72ca1",
+		},
+		{
+			name:      "empty strategy is hash",
+			namespace: "default",
+			objName:   "web",
+			expected:  "web-default-mycluster-7765622b64656661756c742b6d79636c757-72ca1",
+		},
+		{
+			name:      "short hash",
+			strategy:  v1alpha1.ShortHashNamingStrategy,
+			namespace: "default",
+			objName:   "web",
+			expected:  "web-default-mycluster-2679536e",
+		},
+		{
+			name:      "readable",
+			strategy:  v1alpha1.ReadableNamingStrategy,
+			namespace: "default",
+			objName:   "web",
+			expected:  "web-x-default-x-mycluster",
+		},
+		{
+			name:     "readable without namespace",
+			strategy: v1alpha1.ReadableNamingStrategy,
+			objName:  "high-priority",
+			expected: "high-priority-x-mycluster",
+		},
+		{
+			name:      "readable with ambiguous name",
+			strategy:  v1alpha1.ReadableNamingStrategy,
+			namespace: "default",
+			objName:   "web-x-prod",
+			expected:  "web-x-prod-default-mycluster-975e07d3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translator := ToHostTranslator{
+				ClusterName:      "mycluster",
+				ClusterNamespace: "k3k-mycluster",
+				NamingStrategy:   tt.strategy,
+			}
+
+			assert.Equal(t, tt.expected, translator.TranslateName(tt.namespace, tt.objName))
+		})
+	}
+}
+
+func Test_shortHashName(t *testing.T) {
+	long := strings.Repeat("a", 80)
+
+	name := shortHashName([]string{long, "default", "mycluster"})
+	assert.Len(t, name, maxNameLength)
+	assert.Equal(t, shortHashName([]string{long, "default", "mycluster"}), name)
+	assert.NotEqual(t, shortHashName([]string{long, "other", "mycluster"}), name)
+}
+
+func Test_readableName(t *testing.T) {
+	// the names would be the same without the fallback to the short hash
+	first := readableName([]string{"a-x-b", "c", "mycluster"})
+	second := readableName([]string{"a", "b-x-c", "mycluster"})
+	assert.NotEqual(t, first, second)
+
+	// the names longer than a DNS label use the short hash
+	name := readableName([]string{strings.Repeat("a", 60), "default", "mycluster"})
+	assert.Len(t, name, maxNameLength)
+}
+
+func TestToHostTranslator_TranslateFrom(t *testing.T) {
+	translator := ToHostTranslator{
+		ClusterName:      "mycluster",
+		ClusterNamespace: "k3k-mycluster",
+		NamingStrategy:   v1alpha1.ReadableNamingStrategy,
+		Index:            NewNameIndex(),
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "default",
+		},
+	}
+	translator.TranslateTo(pod)
+	assert.Equal(t, "web-x-default-x-mycluster", pod.Name)
+
+	// the name is parsed if the annotations are erased
+	hostPod := pod.DeepCopy()
+	hostPod.Annotations = nil
+	assert.True(t, translator.TranslateFrom(hostPod))
+	assert.Equal(t, "web", hostPod.Name)
+	assert.Equal(t, "default", hostPod.Namespace)
+
+	// the index is used for the names that can't be parsed
+	translator.NamingStrategy = v1alpha1.HashNamingStrategy
+
+	hostPod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: translator.TranslateName("default", "web")}}
+	assert.False(t, translator.TranslateFrom(hostPod.DeepCopy()))
+
+	translator.Index.Add(hostPod.Name, types.NamespacedName{Namespace: "default", Name: "web"})
+	assert.True(t, translator.TranslateFrom(hostPod))
+	assert.Equal(t, "web", hostPod.Name)
+}
+
+func TestToHostTranslator_CheckCollision(t *testing.T) {
+	translator := ToHostTranslator{
+		ClusterName:      "mycluster",
+		ClusterNamespace: "k3k-mycluster",
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "config",
+			Namespace: "default",
+		},
+	}
+	translator.TranslateTo(configMap)
+
+	assert.NoError(t, translator.CheckCollision(configMap, "default", "config"))
+	assert.ErrorIs(t, translator.CheckCollision(configMap, "other", "config"), ErrNameCollision)
+
+	otherCluster := ToHostTranslator{ClusterName: "other", ClusterNamespace: "k3k-mycluster"}
+	assert.ErrorIs(t, otherCluster.CheckCollision(configMap, "default", "config"), ErrNameCollision)
+}
+
+func TestToHostTranslator_HostNamespace(t *testing.T) {
+	translator := ToHostTranslator{
+		ClusterName:      "mycluster",
+		ClusterNamespace: "k3k-mycluster",
+	}
+
+	assert.Equal(t, "k3k-mycluster", translator.HostNamespace("default"))
+
+	translator.NamespaceMapping = v1alpha1.PerNamespaceMapping
+
+	assert.Equal(t, "mycluster-default", translator.HostNamespace("default"))
+	assert.Equal(t, "k3k-mycluster", translator.HostNamespace("kube-system"))
+	assert.Equal(t, "k3k-mycluster", translator.HostNamespace(""))
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "config",
+			Namespace: "apps",
+		},
+	}
+	translator.TranslateTo(configMap)
+	assert.Equal(t, "mycluster-apps", configMap.Namespace)
+}
+
+func TestToHostTranslator_IsHostNamespace(t *testing.T) {
+	translator := ToHostTranslator{
+		ClusterName:      "mycluster",
+		ClusterNamespace: "k3k-mycluster",
+		NamespaceMapping: v1alpha1.PerNamespaceMapping,
+	}
+
+	namespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+
+	assert.True(t, translator.IsHostNamespace(namespace("k3k-mycluster", nil)))
+	assert.True(t, translator.IsHostNamespace(namespace("mycluster-default", map[string]string{
+		ClusterNameLabel:      "mycluster",
+		ClusterNamespaceLabel: "k3k-mycluster",
+	})))
+	assert.False(t, translator.IsHostNamespace(namespace("mycluster-default", nil)))
+	assert.False(t, translator.IsHostNamespace(namespace("mycluster-default", map[string]string{
+		ClusterNameLabel:      "mycluster",
+		ClusterNamespaceLabel: "other",
+	})))
+}
@@ -0,0 +1,58 @@
+package translate
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// NameIndex is a reverse lookup index from the names of the host cluster objects to their names in the virtual cluster.
+// A nil NameIndex is valid, and doesn't index anything.
+type NameIndex struct {
+	mu    sync.RWMutex
+	names map[string]types.NamespacedName
+}
+
+func NewNameIndex() *NameIndex {
+	return &NameIndex{
+		names: make(map[string]types.NamespacedName),
+	}
+}
+
+// Add records the name in the virtual cluster of the host object
+func (i *NameIndex) Add(hostName string, virtualName types.NamespacedName) {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.names[hostName] = virtualName
+}
+
+// Get returns the name in the virtual cluster of the host object
+func (i *NameIndex) Get(hostName string) (types.NamespacedName, bool) {
+	if i == nil {
+		return types.NamespacedName{}, false
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	virtualName, found := i.names[hostName]
+
+	return virtualName, found
+}
+
+// Delete removes the host object from the index
+func (i *NameIndex) Delete(hostName string) {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.names, hostName)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
 	"github.com/go-logr/zapr"
@@ -37,6 +38,7 @@ var (
 	kubeletPortRange        string
 	webhookPortRange        string
 	maxConcurrentReconciles int
+	activityPort            int
 	debug                   bool
 	logger                  *log.Logger
 )
@@ -74,8 +76,15 @@ func main() {
 	rootCmd.PersistentFlags().StringVar(&config.K3SServerImagePullPolicy, "k3s-server-image-pull-policy", "", "K3K server image pull policy")
 	rootCmd.PersistentFlags().StringSliceVar(&config.ServerImagePullSecrets, "server-image-pull-secret", nil, "Image pull secret used for for servers")
 	rootCmd.PersistentFlags().StringSliceVar(&config.AgentImagePullSecrets, "agent-image-pull-secret", nil, "Image pull secret used for for agents")
+	rootCmd.PersistentFlags().StringVar(&config.ControllerImage, "controller-image", "rancher/k3k", "K3K controller image running the etcd snapshots, the restores and the wake proxies of the clusters")
+	rootCmd.PersistentFlags().StringVar(&config.ControllerImagePullPolicy, "controller-image-pull-policy", "", "K3K controller image pull policy must be one of Always, IfNotPresent or Never")
+	rootCmd.PersistentFlags().StringVar(&config.ActivityURL, "activity-url", "", "URL of the activity tracker of the controller reached by the virtual clusters, enabling the automatic sleep of the idle clusters")
+	rootCmd.PersistentFlags().StringVar(&config.ControllerNamespace, "controller-namespace", "", "Namespace of the controller, reached by the clusters sending their activity to the activity tracker")
+	rootCmd.PersistentFlags().IntVar(&activityPort, "activity-port", 8082, "Port of the activity tracker of the controller")
 	rootCmd.PersistentFlags().IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 50, "maximum number of concurrent reconciles")
 
+	rootCmd.AddCommand(newSnapshotCmd(), newWakeProxyCmd())
+
 	if err := rootCmd.Execute(); err != nil {
 		logger.Fatalw("failed to run k3k controller", zap.Error(err))
 	}
@@ -113,6 +122,8 @@ func run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	config.ActivityAddress = ":" + strconv.Itoa(activityPort)
+
 	if err := cluster.Add(ctx, mgr, &config, maxConcurrentReconciles, portAllocator, nil); err != nil {
 		return fmt.Errorf("failed to add the new cluster controller: %v", err)
 	}
@@ -123,9 +134,15 @@ func run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to add the new cluster controller: %v", err)
 	}
 
+	logger.Info("adding clusterbackup controller")
+
+	if err := cluster.AddBackupController(ctx, mgr, &config, maxConcurrentReconciles); err != nil {
+		return fmt.Errorf("failed to add the clusterbackup controller: %v", err)
+	}
+
 	logger.Info("adding clusterpolicy controller")
 
-	if err := policy.Add(mgr, config.ClusterCIDR, maxConcurrentReconciles); err != nil {
+	if err := policy.Add(mgr, config.ClusterCIDR, config.ActivityURL, config.ControllerNamespace, maxConcurrentReconciles); err != nil {
 		return fmt.Errorf("failed to add the clusterpolicy controller: %v", err)
 	}
 
@@ -147,5 +164,9 @@ func validate() error {
 		}
 	}
 
+	if config.ActivityURL != "" && config.ControllerNamespace == "" {
+		return errors.New("controller namespace is required by the activity tracker")
+	}
+
 	return nil
 }
@@ -0,0 +1,77 @@
+package backup
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	certutil "github.com/rancher/dynamiclistener/cert"
+)
+
+// RestoreCADir is the directory of the new CAs of a restored cluster, in the restore directory
+const RestoreCADir = "ca"
+
+// clusterCAs are the CAs of a K3s cluster rotated with "k3s certificate rotate-ca", with the common names of
+// the CAs generated by K3s
+var clusterCAs = map[string]string{
+	"server-ca":         "k3s-server-ca",
+	"client-ca":         "k3s-client-ca",
+	"request-header-ca": "k3s-request-header-ca",
+	"etcd/server-ca":    "etcd-server-ca",
+	"etcd/peer-ca":      "etcd-peer-ca",
+}
+
+// GenerateCAs writes new self-signed CAs of a K3s cluster in the directory, in the layout expected by
+// "k3s certificate rotate-ca". The key of the service accounts is not generated: the server keeps its own,
+// so that the tokens of the service accounts stay valid.
+func GenerateCAs(dir string) error {
+	now := time.Now().Unix()
+
+	for file, commonName := range clusterCAs {
+		caCert, caKey, err := newCA(fmt.Sprintf("%s@%d", commonName, now))
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0o700); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, file+".crt"), caCert, 0o600); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, file+".key"), caKey, 0o600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newCA(commonName string) ([]byte, []byte, error) {
+	caKey, err := certutil.MakeEllipticPrivateKeyPEM()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signer, err := certutil.ParsePrivateKeyPEM(caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, ok := signer.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.New("invalid CA private key")
+	}
+
+	caCert, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: commonName}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certutil.EncodeCertPEM(caCert), caKey, nil
+}
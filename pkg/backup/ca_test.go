@@ -0,0 +1,35 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	certutil "github.com/rancher/dynamiclistener/cert"
+)
+
+func Test_GenerateCAs(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), RestoreCADir)
+
+	assert.NoError(t, GenerateCAs(dir))
+
+	for _, file := range []string{"server-ca", "client-ca", "request-header-ca", "etcd/server-ca", "etcd/peer-ca"} {
+		caCert, err := os.ReadFile(filepath.Join(dir, file+".crt"))
+		assert.NoError(t, err)
+
+		certs, err := certutil.ParseCertsPEM(caCert)
+		if assert.NoError(t, err, file) && assert.Len(t, certs, 1, file) {
+			assert.True(t, certs[0].IsCA, file)
+		}
+
+		caKey, err := os.ReadFile(filepath.Join(dir, file+".key"))
+		assert.NoError(t, err)
+
+		_, err = certutil.ParsePrivateKeyPEM(caKey)
+		assert.NoError(t, err, file)
+	}
+
+	assert.NoFileExists(t, filepath.Join(dir, "service.key"))
+}
@@ -0,0 +1,214 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SnapshotExtension is the extension of the etcd snapshots
+	SnapshotExtension = ".db"
+	// SecretsExtension is the extension of the Secrets stored with the snapshots
+	SecretsExtension = ".secrets.json"
+
+	// RestoreSnapshotFile is the file of the snapshot written by Fetch in the restore directory
+	RestoreSnapshotFile = "snapshot.db"
+	// RestoreTokenFile is the file of the token of the snapshot written by Fetch in the restore directory
+	RestoreTokenFile = "token"
+
+	// fetchedFile marks a restore directory where the snapshot was already fetched
+	fetchedFile = ".fetched"
+)
+
+// Save takes a snapshot of etcd, and stores it with the Secrets of the cluster. The Secrets are stored first,
+// so that a snapshot is never listed without them.
+func Save(ctx context.Context, etcdClient *clientv3.Client, store Store, name string, secrets []byte) error {
+	snapshotFile, err := os.CreateTemp("", name+"-")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = snapshotFile.Close()
+		_ = os.Remove(snapshotFile.Name())
+	}()
+
+	snapshot, err := etcdClient.Snapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to take etcd snapshot: %w", err)
+	}
+
+	defer func() {
+		_ = snapshot.Close()
+	}()
+
+	size, err := io.Copy(snapshotFile, snapshot)
+	if err != nil {
+		return fmt.Errorf("unable to read etcd snapshot: %w", err)
+	}
+
+	if _, err := snapshotFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if err := store.Put(ctx, name+SecretsExtension, bytes.NewReader(secrets), int64(len(secrets))); err != nil {
+		return fmt.Errorf("unable to store the secrets of snapshot %s: %w", name, err)
+	}
+
+	if err := store.Put(ctx, name+SnapshotExtension, snapshotFile, size); err != nil {
+		return fmt.Errorf("unable to store snapshot %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Prune deletes the oldest snapshots of the store, with their Secrets, and returns the names of the snapshots kept
+// from the oldest to the newest
+func Prune(ctx context.Context, store Store, retention int) ([]string, error) {
+	objects, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := slices.DeleteFunc(objects, func(object Object) bool {
+		return !strings.HasSuffix(object.Name, SnapshotExtension)
+	})
+
+	slices.SortFunc(snapshots, func(a, b Object) int {
+		if c := a.LastModified.Compare(b.LastModified); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	var kept []string
+
+	for i, snapshot := range snapshots {
+		name := strings.TrimSuffix(snapshot.Name, SnapshotExtension)
+
+		if i >= len(snapshots)-retention {
+			kept = append(kept, name)
+			continue
+		}
+
+		if err := store.Delete(ctx, snapshot.Name); err != nil {
+			return nil, fmt.Errorf("unable to delete snapshot %s: %w", name, err)
+		}
+
+		if err := store.Delete(ctx, name+SecretsExtension); err != nil {
+			return nil, fmt.Errorf("unable to delete the secrets of snapshot %s: %w", name, err)
+		}
+	}
+
+	return kept, nil
+}
+
+// EncodeSecrets encodes the Secrets stored with the snapshots, only their name, type and data are kept
+func EncodeSecrets(secrets ...v1.Secret) ([]byte, error) {
+	secretList := v1.SecretList{
+		TypeMeta: metav1.TypeMeta{Kind: "SecretList", APIVersion: "v1"},
+	}
+
+	for _, secret := range secrets {
+		secretList.Items = append(secretList.Items, v1.Secret{
+			TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: secret.Name},
+			Type:       secret.Type,
+			Data:       secret.Data,
+		})
+	}
+
+	return json.Marshal(secretList)
+}
+
+// DecodeSecrets decodes the Secrets stored with the snapshots
+func DecodeSecrets(data []byte) ([]v1.Secret, error) {
+	var secretList v1.SecretList
+	if err := json.Unmarshal(data, &secretList); err != nil {
+		return nil, err
+	}
+
+	return secretList.Items, nil
+}
+
+// Fetch writes the snapshot with the name, and the token of its cluster, in the restore directory of a server.
+// The snapshot is fetched only once: the files are deleted by the server after the restore, and are not fetched
+// again on restart. It returns false if the snapshot was already fetched.
+func Fetch(ctx context.Context, store Store, name, dir string) (bool, error) {
+	if _, err := os.Stat(filepath.Join(dir, fetchedFile)); err == nil {
+		return false, nil
+	}
+
+	secrets, err := get(ctx, store, name+SecretsExtension)
+	if err != nil {
+		return false, fmt.Errorf("unable to get the secrets of snapshot %s: %w", name, err)
+	}
+
+	token, err := SecretsToken(secrets)
+	if err != nil {
+		return false, fmt.Errorf("invalid secrets of snapshot %s: %w", name, err)
+	}
+
+	snapshot, err := store.Get(ctx, name+SnapshotExtension)
+	if err != nil {
+		return false, fmt.Errorf("unable to get snapshot %s: %w", name, err)
+	}
+
+	defer func() {
+		_ = snapshot.Close()
+	}()
+
+	restoreStore := &VolumeStore{Dir: dir}
+
+	if err := restoreStore.Put(ctx, RestoreTokenFile, bytes.NewReader(token), int64(len(token))); err != nil {
+		return false, err
+	}
+
+	if err := restoreStore.Put(ctx, RestoreSnapshotFile, snapshot, -1); err != nil {
+		return false, fmt.Errorf("unable to write snapshot %s: %w", name, err)
+	}
+
+	return true, os.WriteFile(filepath.Join(dir, fetchedFile), []byte(name), 0o600)
+}
+
+func get(ctx context.Context, store Store, name string) ([]byte, error) {
+	r, err := store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = r.Close()
+	}()
+
+	return io.ReadAll(r)
+}
+
+// SecretsToken returns the token of the cluster, from the token Secret stored with the snapshots
+func SecretsToken(data []byte) ([]byte, error) {
+	secrets, err := DecodeSecrets(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, secret := range secrets {
+		if token, found := secret.Data["token"]; found {
+			return token, nil
+		}
+	}
+
+	return nil, errors.New("token secret not found")
+}
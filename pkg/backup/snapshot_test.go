@@ -0,0 +1,148 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_Prune(t *testing.T) {
+	ctx := context.Background()
+	store := &VolumeStore{Dir: filepath.Join(t.TempDir(), "backup")}
+
+	now := time.Now()
+
+	for i, name := range []string{"snapshot-c", "snapshot-a", "snapshot-b"} {
+		for _, file := range []string{name + SnapshotExtension, name + SecretsExtension} {
+			err := store.Put(ctx, file, strings.NewReader(name), int64(len(name)))
+			assert.NoError(t, err)
+
+			// snapshot-c is the oldest one
+			modTime := now.Add(time.Duration(i) * time.Minute)
+			assert.NoError(t, os.Chtimes(filepath.Join(store.Dir, file), modTime, modTime))
+		}
+	}
+
+	kept, err := Prune(ctx, store, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"snapshot-a", "snapshot-b"}, kept)
+
+	objects, err := store.List(ctx)
+	assert.NoError(t, err)
+
+	var names []string
+	for _, object := range objects {
+		names = append(names, object.Name)
+	}
+
+	assert.ElementsMatch(t, []string{
+		"snapshot-a" + SnapshotExtension, "snapshot-a" + SecretsExtension,
+		"snapshot-b" + SnapshotExtension, "snapshot-b" + SecretsExtension,
+	}, names)
+}
+
+func Test_Prune_emptyStore(t *testing.T) {
+	store := &VolumeStore{Dir: filepath.Join(t.TempDir(), "notfound")}
+
+	kept, err := Prune(context.Background(), store, 5)
+	assert.NoError(t, err)
+	assert.Empty(t, kept)
+}
+
+func Test_EncodeSecrets(t *testing.T) {
+	secret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "k3k-mycluster-token",
+			Namespace:       "k3k-mycluster",
+			ResourceVersion: "42",
+		},
+		Data: map[string][]byte{"token": []byte("secret")},
+	}
+
+	data, err := EncodeSecrets(secret)
+	assert.NoError(t, err)
+
+	var secretList v1.SecretList
+	assert.NoError(t, json.Unmarshal(data, &secretList))
+
+	assert.Len(t, secretList.Items, 1)
+	assert.Equal(t, "k3k-mycluster-token", secretList.Items[0].Name)
+	assert.Empty(t, secretList.Items[0].Namespace)
+	assert.Empty(t, secretList.Items[0].ResourceVersion)
+	assert.Equal(t, []byte("secret"), secretList.Items[0].Data["token"])
+}
+
+func Test_DecodeSecrets(t *testing.T) {
+	data, err := EncodeSecrets(v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "k3k-mycluster-token"},
+		Data:       map[string][]byte{"token": []byte("secret")},
+	})
+	assert.NoError(t, err)
+
+	secrets, err := DecodeSecrets(data)
+	assert.NoError(t, err)
+
+	assert.Len(t, secrets, 1)
+	assert.Equal(t, "k3k-mycluster-token", secrets[0].Name)
+	assert.Equal(t, []byte("secret"), secrets[0].Data["token"])
+}
+
+func Test_Fetch(t *testing.T) {
+	ctx := context.Background()
+	store := &VolumeStore{Dir: filepath.Join(t.TempDir(), "backup")}
+	dir := filepath.Join(t.TempDir(), "restore")
+
+	secrets, err := EncodeSecrets(
+		v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "k3k-mycluster-bootstrap"},
+			Data:       map[string][]byte{"bootstrap": []byte("{}")},
+		},
+		v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "k3k-mycluster-token"},
+			Data:       map[string][]byte{"token": []byte("secret")},
+		},
+	)
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.Put(ctx, "snapshot-a"+SecretsExtension, strings.NewReader(string(secrets)), int64(len(secrets))))
+	assert.NoError(t, store.Put(ctx, "snapshot-a"+SnapshotExtension, strings.NewReader("etcd"), 4))
+
+	fetched, err := Fetch(ctx, store, "snapshot-a", dir)
+	assert.NoError(t, err)
+	assert.True(t, fetched)
+
+	snapshot, err := os.ReadFile(filepath.Join(dir, RestoreSnapshotFile))
+	assert.NoError(t, err)
+	assert.Equal(t, "etcd", string(snapshot))
+
+	token, err := os.ReadFile(filepath.Join(dir, RestoreTokenFile))
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(token))
+
+	// the files deleted after the restore are not fetched again
+	assert.NoError(t, os.Remove(filepath.Join(dir, RestoreSnapshotFile)))
+
+	fetched, err = Fetch(ctx, store, "snapshot-a", dir)
+	assert.NoError(t, err)
+	assert.False(t, fetched)
+	assert.NoFileExists(t, filepath.Join(dir, RestoreSnapshotFile))
+}
+
+func Test_Fetch_notFound(t *testing.T) {
+	store := &VolumeStore{Dir: filepath.Join(t.TempDir(), "backup")}
+	dir := filepath.Join(t.TempDir(), "restore")
+
+	fetched, err := Fetch(context.Background(), store, "notfound", dir)
+	assert.Error(t, err)
+	assert.False(t, fetched)
+	assert.NoDirExists(t, dir)
+}
@@ -0,0 +1,225 @@
+// Package backup saves the etcd snapshots of the virtual clusters, with the Secrets needed to restore them,
+// in a directory or in an S3-compatible bucket.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Object is a file of a store
+type Object struct {
+	Name         string
+	LastModified time.Time
+}
+
+// Store is the storage of the snapshots
+type Store interface {
+	// Put stores the content of the reader with the name
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+	// Get returns a reader of the object with the name, closed by the caller
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the objects of the store
+	List(ctx context.Context) ([]Object, error)
+	// Delete deletes the object with the name, if it exists
+	Delete(ctx context.Context, name string) error
+}
+
+// VolumeStore stores the snapshots in a directory, usually the mount path of a PVC
+type VolumeStore struct {
+	Dir string
+}
+
+func (s *VolumeStore) Put(_ context.Context, name string, r io.Reader, _ int64) error {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+
+	// the file is renamed after being written, so that a partial file is never listed
+	tmp, err := os.CreateTemp(s.Dir, "."+name+"-")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(s.Dir, name))
+}
+
+func (s *VolumeStore) Get(_ context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.Dir, name))
+}
+
+func (s *VolumeStore) List(_ context.Context) ([]Object, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var objects []Object
+
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, Object{Name: entry.Name(), LastModified: info.ModTime()})
+	}
+
+	return objects, nil
+}
+
+func (s *VolumeStore) Delete(_ context.Context, name string) error {
+	if err := os.Remove(filepath.Join(s.Dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// S3Config is the configuration of an S3-compatible bucket
+type S3Config struct {
+	Endpoint        string
+	Bucket          string
+	Folder          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Insecure        bool
+	SkipSSLVerify   bool
+}
+
+// S3Store stores the snapshots in a folder of an S3-compatible bucket
+type S3Store struct {
+	client *minio.Client
+	bucket string
+	folder string
+}
+
+// NewS3Store returns the store of the bucket, and checks that the bucket exists. Without an access key,
+// the credentials are read from the IAM role.
+func NewS3Store(ctx context.Context, config S3Config) (*S3Store, error) {
+	creds := credentials.NewIAM("")
+	if config.AccessKeyID != "" {
+		creds = credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, "")
+	}
+
+	transport, err := minio.DefaultTransport(!config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.SkipSSLVerify && transport.TLSClientConfig != nil {
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
+
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:     creds,
+		Secure:    !config.Insecure,
+		Region:    config.Region,
+		Transport: transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(ctx, config.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("unable to check bucket %s: %w", config.Bucket, err)
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("bucket %s does not exist", config.Bucket)
+	}
+
+	return &S3Store{
+		client: client,
+		bucket: config.Bucket,
+		folder: strings.Trim(config.Folder, "/"),
+	}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	_, err := s.client.PutObject(ctx, s.bucket, s.key(name), r, size, minio.PutObjectOptions{})
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, s.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// the object is requested on the first read, a missing object is reported by Stat
+	if _, err := object.Stat(); err != nil {
+		_ = object.Close()
+		return nil, err
+	}
+
+	return object, nil
+}
+
+func (s *S3Store) List(ctx context.Context) ([]Object, error) {
+	prefix := s.key("")
+
+	var objects []Object
+
+	for info := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+
+		name := strings.TrimPrefix(info.Key, prefix)
+
+		// the objects of the sub folders are not listed
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+
+		objects = append(objects, Object{Name: name, LastModified: info.LastModified})
+	}
+
+	return objects, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, name string) error {
+	return s.client.RemoveObject(ctx, s.bucket, s.key(name), minio.RemoveObjectOptions{})
+}
+
+// key returns the key of the object in the folder of the store
+func (s *S3Store) key(name string) string {
+	if s.folder == "" {
+		return name
+	}
+
+	return s.folder + "/" + name
+}
@@ -0,0 +1,251 @@
+// Package activity tracks the requests of the users to the API servers of the virtual clusters, reported by the
+// audit webhook of the servers, and the connections to the sleeping clusters, reported by their wake proxy.
+package activity
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/controller"
+)
+
+const (
+	// AuditPath is the path of the endpoint receiving the audit events of the API server of a cluster
+	AuditPath = "/audit/"
+
+	// WakeUpPath is the path of the endpoint receiving the connections to a sleeping cluster
+	WakeUpPath = "/wakeup/"
+
+	// notifyInterval is the minimum interval between two notifications of the activity of a cluster
+	notifyInterval = time.Minute
+
+	maxRequestSize = 10 << 20
+)
+
+// the requests of the internal clients of the servers and of the k3k-kubelet are sent with the admin user
+var internalUserAgents = []string{"k3s/", "k3k-kubelet/"}
+
+// TokenFunc returns the token of a cluster, authenticating the requests of its servers and of its wake proxy
+type TokenFunc func(ctx context.Context, cluster types.NamespacedName) (string, error)
+
+// Tracker records the time of the last activity of the clusters
+type Tracker struct {
+	token  TokenFunc
+	events chan event.GenericEvent
+
+	mu       sync.Mutex
+	clusters map[types.NamespacedName]*clusterActivity
+}
+
+type clusterActivity struct {
+	last     time.Time
+	notified time.Time
+}
+
+func NewTracker(token TokenFunc) *Tracker {
+	return &Tracker{
+		token:    token,
+		events:   make(chan event.GenericEvent, 100),
+		clusters: make(map[types.NamespacedName]*clusterActivity),
+	}
+}
+
+// Events returns the channel notifying the clusters with a new activity. The activity of a cluster is notified
+// at most once per minute, and the wake up requests are always notified.
+func (t *Tracker) Events() <-chan event.GenericEvent {
+	return t.events
+}
+
+// LastActivity returns the time of the last activity recorded for the cluster, or the zero time
+func (t *Tracker) LastActivity(cluster types.NamespacedName) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if activity, found := t.clusters[cluster]; found {
+		return activity.last
+	}
+
+	return time.Time{}
+}
+
+// Record records an activity of the cluster at the given time
+func (t *Tracker) Record(cluster types.NamespacedName, at time.Time, wakeUp bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	activity, found := t.clusters[cluster]
+	if !found {
+		activity = &clusterActivity{}
+		t.clusters[cluster] = activity
+	}
+
+	if at.After(activity.last) {
+		activity.last = at
+	}
+
+	now := time.Now()
+	if !wakeUp && now.Sub(activity.notified) < notifyInterval {
+		return
+	}
+
+	activity.notified = now
+
+	obj := &v1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cluster.Name,
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	// the cluster is reconciled on the next notification if the queue is full
+	select {
+	case t.events <- event.GenericEvent{Object: obj}:
+	default:
+		activity.notified = time.Time{}
+	}
+}
+
+// Forget removes the activity of a deleted cluster
+func (t *Tracker) Forget(cluster types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.clusters, cluster)
+}
+
+// Server returns the runnable serving the endpoints of the tracker over TLS on the port
+func (t *Tracker) Server(addr string, certificate *Certificate) manager.Runnable {
+	return manager.RunnableFunc(func(ctx context.Context) error {
+		server := &http.Server{
+			Addr:              addr,
+			Handler:           t,
+			ReadHeaderTimeout: 10 * time.Second,
+			TLSConfig: &tls.Config{
+				Certificates: []tls.Certificate{certificate.Certificate},
+				MinVersion:   tls.VersionTLS12,
+			},
+		}
+
+		go func() {
+			<-ctx.Done()
+
+			_ = server.Shutdown(context.Background())
+		}()
+
+		if err := server.ListenAndServeTLS("", ""); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// ServeHTTP records the activity of a cluster from the audit events sent to "/audit/<namespace>/<name>",
+// and from the wake up requests sent to "/wakeup/<namespace>/<name>"
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var (
+		path   string
+		wakeUp bool
+	)
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, AuditPath):
+		path = strings.TrimPrefix(r.URL.Path, AuditPath)
+	case strings.HasPrefix(r.URL.Path, WakeUpPath):
+		path = strings.TrimPrefix(r.URL.Path, WakeUpPath)
+		wakeUp = true
+	}
+
+	namespace, name, found := strings.Cut(path, "/")
+	if !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	cluster := types.NamespacedName{Namespace: namespace, Name: name}
+
+	if !t.authenticated(r, cluster) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if wakeUp {
+		ctrl.LoggerFrom(r.Context()).Info("wake up requested", "cluster", cluster)
+		t.Record(cluster, time.Now(), true)
+
+		return
+	}
+
+	var events auditv1.EventList
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&events); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var last time.Time
+
+	for i := range events.Items {
+		event := &events.Items[i]
+		if userActivity(event) && event.StageTimestamp.After(last) {
+			last = event.StageTimestamp.Time
+		}
+	}
+
+	if !last.IsZero() {
+		t.Record(cluster, last, false)
+	}
+}
+
+func (t *Tracker) authenticated(r *http.Request, cluster types.NamespacedName) bool {
+	requestToken, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || requestToken == "" {
+		return false
+	}
+
+	token, err := t.token(r.Context(), cluster)
+	if err != nil || token == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(requestToken), []byte(token)) == 1
+}
+
+// userActivity returns true if the request of the event was sent by a user of the cluster, and not by a node,
+// a component of the cluster or the k3k-kubelet
+func userActivity(event *auditv1.Event) bool {
+	for _, userAgent := range internalUserAgents {
+		if strings.HasPrefix(event.UserAgent, userAgent) {
+			return false
+		}
+	}
+
+	username := event.User.Username
+
+	if namespace, _, err := serviceaccount.SplitUsername(username); err == nil {
+		return namespace != metav1.NamespaceSystem
+	}
+
+	return username == controller.AdminCommonName || !strings.HasPrefix(username, "system:")
+}
@@ -0,0 +1,251 @@
+package activity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+)
+
+var testCluster = types.NamespacedName{Name: "mycluster", Namespace: "ns-1"}
+
+func testToken(_ context.Context, cluster types.NamespacedName) (string, error) {
+	if cluster != testCluster {
+		return "", errors.New("not found")
+	}
+
+	return "secret-token", nil
+}
+
+func auditEvent(username, userAgent string, at time.Time) auditv1.Event {
+	return auditv1.Event{
+		User:           authenticationv1.UserInfo{Username: username},
+		UserAgent:      userAgent,
+		StageTimestamp: metav1.NewMicroTime(at),
+	}
+}
+
+func Test_ServeHTTP(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	events, err := json.Marshal(auditv1.EventList{
+		Items: []auditv1.Event{
+			auditEvent("system:admin", "kubectl/v1.32.0", now.Add(-time.Minute)),
+			auditEvent("system:admin", "k3k-kubelet/v0.3.0", now),
+		},
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		token        string
+		body         []byte
+		expectedCode int
+		expectedLast time.Time
+	}{
+		{
+			name:         "audit events of a user",
+			method:       http.MethodPost,
+			path:         "/audit/ns-1/mycluster",
+			token:        "secret-token",
+			body:         events,
+			expectedCode: http.StatusOK,
+			expectedLast: now.Add(-time.Minute),
+		},
+		{
+			name:         "invalid token",
+			method:       http.MethodPost,
+			path:         "/audit/ns-1/mycluster",
+			token:        "wrong-token",
+			body:         events,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "token of another cluster",
+			method:       http.MethodPost,
+			path:         "/audit/ns-1/othercluster",
+			token:        "secret-token",
+			body:         events,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "unknown path",
+			method:       http.MethodPost,
+			path:         "/audit/mycluster",
+			token:        "secret-token",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid method",
+			method:       http.MethodGet,
+			path:         "/wakeup/ns-1/mycluster",
+			token:        "secret-token",
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker(testToken)
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			rec := httptest.NewRecorder()
+			tracker.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			assert.True(t, tt.expectedLast.Equal(tracker.LastActivity(testCluster)))
+		})
+	}
+}
+
+func Test_WakeUp(t *testing.T) {
+	tracker := NewTracker(testToken)
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/wakeup/ns-1/mycluster", nil)
+		req.Header.Set("Authorization", "Bearer secret-token")
+
+		rec := httptest.NewRecorder()
+		tracker.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	assert.False(t, tracker.LastActivity(testCluster).IsZero())
+
+	// the wake up requests are always notified
+	assert.Len(t, tracker.Events(), 2)
+}
+
+func Test_Record(t *testing.T) {
+	tracker := NewTracker(testToken)
+	now := time.Now()
+
+	tracker.Record(testCluster, now, false)
+	tracker.Record(testCluster, now.Add(-time.Minute), false)
+	tracker.Record(testCluster, now.Add(time.Second), false)
+
+	assert.Equal(t, now.Add(time.Second), tracker.LastActivity(testCluster))
+
+	// the activity is notified once per minute
+	assert.Len(t, tracker.Events(), 1)
+
+	event := <-tracker.Events()
+	assert.Equal(t, testCluster.Name, event.Object.GetName())
+	assert.Equal(t, testCluster.Namespace, event.Object.GetNamespace())
+
+	tracker.Forget(testCluster)
+	assert.True(t, tracker.LastActivity(testCluster).IsZero())
+}
+
+func Test_userActivity(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		event    auditv1.Event
+		expected bool
+	}{
+		{
+			name:     "admin user",
+			event:    auditEvent("system:admin", "kubectl/v1.32.0", now),
+			expected: true,
+		},
+		{
+			name:     "user",
+			event:    auditEvent("jane", "kubectl/v1.32.0", now),
+			expected: true,
+		},
+		{
+			name:     "service account of a workload",
+			event:    auditEvent("system:serviceaccount:default:my-app", "my-app/v1.0.0", now),
+			expected: true,
+		},
+		{
+			name:     "service account of a system component",
+			event:    auditEvent("system:serviceaccount:kube-system:coredns", "coredns/v1.12.0", now),
+			expected: false,
+		},
+		{
+			name:     "node",
+			event:    auditEvent("system:node:node-1", "kubelet/v1.32.0", now),
+			expected: false,
+		},
+		{
+			name:     "k3s supervisor",
+			event:    auditEvent("system:admin", "k3s/v1.32.0+k3s1", now),
+			expected: false,
+		},
+		{
+			name:     "k3k-kubelet",
+			event:    auditEvent("system:admin", "k3k-kubelet/v0.3.0", now),
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, userActivity(&tt.event))
+		})
+	}
+}
+
+func Test_AuditConflict(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverArgs []string
+		expected   string
+	}{
+		{
+			name:       "no arguments",
+			serverArgs: nil,
+			expected:   "",
+		},
+		{
+			name:       "other API server flags",
+			serverArgs: []string{"--kube-apiserver-arg=audit-log-path=/var/log/audit.log", "--disable=traefik"},
+			expected:   "",
+		},
+		{
+			name:       "audit policy in the same argument",
+			serverArgs: []string{"--kube-apiserver-arg=audit-policy-file=/etc/audit.yaml"},
+			expected:   "audit-policy-file",
+		},
+		{
+			name:       "audit webhook in the next argument",
+			serverArgs: []string{"--kube-apiserver-arg", "--audit-webhook-config-file=/etc/webhook.yaml"},
+			expected:   "audit-webhook-config-file",
+		},
+		{
+			name:       "audit policy separated by a space",
+			serverArgs: []string{"--kube-apiserver-arg audit-policy-file=/etc/audit.yaml"},
+			expected:   "audit-policy-file",
+		},
+		{
+			name:       "audit policy file of another component",
+			serverArgs: []string{"--kube-controller-manager-arg=audit-policy-file=/etc/audit.yaml", "audit-policy-file"},
+			expected:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, AuditConflict(tt.serverArgs))
+		})
+	}
+}
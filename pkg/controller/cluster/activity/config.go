@@ -0,0 +1,159 @@
+package activity
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// AuditPolicy returns the audit policy of the API server of the clusters. Only the metadata of the completed requests
+// are sent to the webhook, and the requests of the nodes, of the system components and the health checks are dropped.
+func AuditPolicy() ([]byte, error) {
+	policy := auditv1.Policy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Policy",
+			APIVersion: auditv1.SchemeGroupVersion.String(),
+		},
+		OmitStages: []auditv1.Stage{
+			auditv1.StageRequestReceived,
+			auditv1.StageResponseStarted,
+		},
+		Rules: []auditv1.PolicyRule{
+			{
+				Level:      auditv1.LevelNone,
+				UserGroups: []string{"system:nodes", "system:serviceaccounts:kube-system"},
+			},
+			{
+				Level: auditv1.LevelNone,
+				Users: []string{
+					"system:apiserver",
+					"system:kube-controller-manager",
+					"system:kube-scheduler",
+					"system:kube-proxy",
+					"system:k3s-controller",
+				},
+			},
+			{
+				Level:           auditv1.LevelNone,
+				NonResourceURLs: []string{"/healthz*", "/livez*", "/readyz*", "/metrics", "/ping"},
+			},
+			{
+				Level: auditv1.LevelMetadata,
+			},
+		},
+	}
+
+	return yaml.Marshal(policy)
+}
+
+// auditFlags are the flags of the API server configured by the controller to send the audit events to the tracker
+var auditFlags = []string{"audit-policy-file", "audit-webhook-config-file"}
+
+// AuditConflict returns the audit flag of the API server set by the "--kube-apiserver-arg" server arguments,
+// conflicting with the audit configuration of the tracker, or an empty string.
+// The arguments are joined in the command of the servers, and the value of a flag can be the next argument.
+func AuditConflict(serverArgs []string) string {
+	args := strings.Fields(strings.Join(serverArgs, " "))
+
+	for i := 0; i < len(args); i++ {
+		name, value, found := strings.Cut(args[i], "=")
+		if strings.TrimLeft(name, "-") != "kube-apiserver-arg" {
+			continue
+		}
+
+		if !found {
+			if i+1 == len(args) {
+				break
+			}
+
+			i++
+			value = args[i]
+		}
+
+		flag, _, _ := strings.Cut(strings.TrimLeft(value, "-"), "=")
+		if slices.Contains(auditFlags, flag) {
+			return flag
+		}
+	}
+
+	return ""
+}
+
+// WebhookConfig returns the kubeconfig of the audit webhook of the API server of a cluster, sending the events to the
+// tracker authenticated with the activity token of the cluster. The CA of the tracker is pinned.
+func WebhookConfig(trackerURL string, cluster types.NamespacedName, token string, ca []byte) ([]byte, error) {
+	config := clientcmdapi.NewConfig()
+
+	config.Clusters["tracker"] = &clientcmdapi.Cluster{
+		Server:                   Endpoint(trackerURL, AuditPath, cluster),
+		CertificateAuthorityData: ca,
+	}
+
+	config.AuthInfos["cluster"] = &clientcmdapi.AuthInfo{
+		Token: token,
+	}
+
+	config.Contexts["default"] = &clientcmdapi.Context{
+		Cluster:  "tracker",
+		AuthInfo: "cluster",
+	}
+
+	config.CurrentContext = "default"
+
+	return clientcmd.Write(*config)
+}
+
+// Endpoint returns the URL of an endpoint of the tracker for a cluster
+func Endpoint(trackerURL, path string, cluster types.NamespacedName) string {
+	return trackerURL + path + cluster.Namespace + "/" + cluster.Name
+}
+
+// EgressRule returns the rule of the network policies of the clusters allowing their servers, and their wake proxy,
+// to reach the tracker in the namespace of the controller
+func EgressRule(trackerURL, controllerNamespace string) (networkingv1.NetworkPolicyEgressRule, error) {
+	u, err := url.Parse(trackerURL)
+	if err != nil {
+		return networkingv1.NetworkPolicyEgressRule{}, err
+	}
+
+	port := 80
+	if u.Port() != "" {
+		if port, err = strconv.Atoi(u.Port()); err != nil {
+			return networkingv1.NetworkPolicyEgressRule{}, fmt.Errorf("invalid port of the activity tracker URL: %w", err)
+		}
+	}
+
+	protocol := v1.ProtocolTCP
+
+	return networkingv1.NetworkPolicyEgressRule{
+		To: []networkingv1.NetworkPolicyPeer{
+			{
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"kubernetes.io/metadata.name": controllerNamespace,
+					},
+				},
+			},
+		},
+		Ports: []networkingv1.NetworkPolicyPort{
+			{
+				Protocol: &protocol,
+				Port:     ptr.To(intstr.FromInt(port)),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,191 @@
+package activity
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	certutil "github.com/rancher/dynamiclistener/cert"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/controller/certs"
+)
+
+const (
+	// certificateSecretName is the name of the Secret with the CA and the serving certificate of the tracker, in the
+	// namespace of the controller
+	certificateSecretName = "k3k-activity-tracker-tls"
+
+	certificateValidity = 365 * 24 * time.Hour
+
+	// certificateRenewal is the remaining validity of a serving certificate renewed when the controller starts
+	certificateRenewal = 90 * 24 * time.Hour
+)
+
+// Certificate is the serving certificate of the tracker, and the CA pinned by the clients of the tracker
+type Certificate struct {
+	CA          []byte
+	Certificate tls.Certificate
+}
+
+// EnsureCertificate returns the serving certificate of the tracker, for the host of its URL. The certificate and its CA
+// are stored in a Secret of the namespace of the controller, shared by its replicas, and the certificate is renewed
+// with the same CA when it expires.
+func EnsureCertificate(ctx context.Context, c client.Client, namespace, trackerURL string) (*Certificate, error) {
+	u, err := url.Parse(trackerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid activity tracker URL %q: the tracker is served over https", trackerURL)
+	}
+
+	var secret v1.Secret
+
+	key := client.ObjectKey{Name: certificateSecretName, Namespace: namespace}
+	if err := c.Get(ctx, key, &secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		secret = v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      certificateSecretName,
+				Namespace: namespace,
+			},
+			Type: v1.SecretTypeTLS,
+		}
+	}
+
+	if !validCertificate(secret.Data, u.Hostname()) {
+		if err := newCertificate(&secret, u.Hostname()); err != nil {
+			return nil, err
+		}
+
+		if secret.CreationTimestamp.IsZero() {
+			err = c.Create(ctx, &secret)
+		} else {
+			err = c.Update(ctx, &secret)
+		}
+
+		// the certificate was created or renewed by another replica of the controller
+		if apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err) {
+			return EnsureCertificate(ctx, c, namespace, trackerURL)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	certificate, err := tls.X509KeyPair(secret.Data[v1.TLSCertKey], secret.Data[v1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Certificate{
+		CA:          secret.Data[v1.ServiceAccountRootCAKey],
+		Certificate: certificate,
+	}, nil
+}
+
+// validCertificate returns true if the serving certificate of the Secret is signed by its CA for the host, and does not
+// expire soon
+func validCertificate(data map[string][]byte, host string) bool {
+	if len(data[v1.ServiceAccountRootCAKey]) == 0 || len(data["ca.key"]) == 0 {
+		return false
+	}
+
+	certificate, err := tls.X509KeyPair(data[v1.TLSCertKey], data[v1.TLSPrivateKeyKey])
+	if err != nil {
+		return false
+	}
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil || time.Until(leaf.NotAfter) < certificateRenewal {
+		return false
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data[v1.ServiceAccountRootCAKey]) {
+		return false
+	}
+
+	_, err = leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+
+	return err == nil
+}
+
+// newCertificate generates the serving certificate of the tracker in the Secret, signed by its CA. The CA is generated
+// if the Secret has none.
+func newCertificate(secret *v1.Secret, host string) error {
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	caCert, caKey := secret.Data[v1.ServiceAccountRootCAKey], secret.Data["ca.key"]
+
+	if _, err := certutil.ParseCertsPEM(caCert); err != nil || len(caKey) == 0 {
+		if caCert, caKey, err = newCA(); err != nil {
+			return err
+		}
+	}
+
+	altNames := certs.AddSANs([]string{host})
+
+	cert, key, err := certs.CreateClientCertKey(
+		host,
+		nil,
+		&altNames,
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		certificateValidity,
+		string(caCert),
+		string(caKey),
+	)
+	if err != nil {
+		return err
+	}
+
+	secret.Data[v1.ServiceAccountRootCAKey] = caCert
+	secret.Data["ca.key"] = caKey
+	secret.Data[v1.TLSCertKey] = cert
+	secret.Data[v1.TLSPrivateKeyKey] = key
+
+	return nil
+}
+
+func newCA() ([]byte, []byte, error) {
+	caKey, err := certutil.MakeEllipticPrivateKeyPEM()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signer, err := certutil.ParsePrivateKeyPEM(caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, ok := signer.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.New("invalid CA private key")
+	}
+
+	caCert, err := certutil.NewSelfSignedCACert(certutil.Config{
+		CommonName: fmt.Sprintf("k3k-activity-tracker-ca@%d", time.Now().Unix()),
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certutil.EncodeCertPEM(caCert), caKey, nil
+}
@@ -0,0 +1,37 @@
+package activity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func Test_newCertificate(t *testing.T) {
+	const host = "k3k-webhook.k3k-system.svc"
+
+	secret := &v1.Secret{}
+
+	err := newCertificate(secret, host)
+	assert.NoError(t, err)
+	assert.True(t, validCertificate(secret.Data, host))
+	assert.False(t, validCertificate(secret.Data, "k3k-webhook.other.svc"))
+
+	// the certificate is renewed for another host with the same CA
+	ca := secret.Data[v1.ServiceAccountRootCAKey]
+
+	err = newCertificate(secret, "k3k-webhook.other.svc")
+	assert.NoError(t, err)
+	assert.Equal(t, ca, secret.Data[v1.ServiceAccountRootCAKey])
+	assert.True(t, validCertificate(secret.Data, "k3k-webhook.other.svc"))
+
+	// a certificate signed by another CA is not valid
+	other := &v1.Secret{}
+
+	err = newCertificate(other, host)
+	assert.NoError(t, err)
+
+	other.Data[v1.ServiceAccountRootCAKey] = ca
+	assert.False(t, validCertificate(other.Data, host))
+}
@@ -3,16 +3,20 @@ package agent
 import (
 	"context"
 	"crypto"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
 
 	certutil "github.com/rancher/dynamiclistener/cert"
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -27,6 +31,17 @@ import (
 const (
 	SharedNodeAgentName = "kubelet"
 	SharedNodeMode      = "shared"
+
+	// sharedAgentMetricsPort is the port of the metrics endpoint of the k3k-kubelet
+	sharedAgentMetricsPort = 8083
+
+	// configHashAnnotation is the hash of the config of the k3k-kubelet applied when it starts, the pods of the
+	// DaemonSet are restarted when it changes
+	configHashAnnotation = "k3k.io/config-hash"
+
+	// HostNamespaceOwnerAnnotation is the UID of the Cluster owning a dedicated host namespace, set by the controller
+	// when it creates the namespace
+	HostNamespaceOwnerAnnotation = "k3k.io/cluster-uid"
 )
 
 type SharedAgent struct {
@@ -64,8 +79,11 @@ func (s *SharedAgent) EnsureResources(ctx context.Context) error {
 		s.serviceAccount(ctx),
 		s.role(ctx),
 		s.roleBinding(ctx),
+		s.hostNamespacesRBAC(ctx),
 		s.service(ctx),
+		s.metricsService(ctx),
 		s.daemonset(ctx),
+		s.podDisruptionBudget(ctx),
 		s.dnsService(ctx),
 		s.webhookTLS(ctx),
 	); err != nil {
@@ -100,29 +118,66 @@ func (s *SharedAgent) config(ctx context.Context) error {
 }
 
 func sharedAgentData(cluster *v1alpha1.Cluster, serviceName, token, ip string, kubeletPort, webhookPort int) string {
-	version := cluster.Spec.Version
-	if cluster.Spec.Version == "" {
-		version = cluster.Status.HostVersion
-	}
+	version := controller.AgentVersion(cluster)
 
+	return fmt.Sprintf("%s\nversion: %s", startupData(cluster, serviceName, token, ip, kubeletPort, webhookPort), version)
+}
+
+// startupData returns the config of the k3k-kubelet applied when it starts. The version is reloaded
+// by the running k3k-kubelet.
+func startupData(cluster *v1alpha1.Cluster, serviceName, token, ip string, kubeletPort, webhookPort int) string {
 	return fmt.Sprintf(`clusterName: %s
 clusterNamespace: %s
 serverIP: %s
 serviceName: %s
 token: %v
 mirrorHostNodes: %t
-version: %s
 webhookPort: %d
-kubeletPort: %d`,
-		cluster.Name, cluster.Namespace, ip, serviceName, token, cluster.Spec.MirrorHostNodes, version, webhookPort, kubeletPort)
+kubeletPort: %d
+metricsPort: %d%s`,
+		cluster.Name, cluster.Namespace, ip, serviceName, token, cluster.Spec.MirrorHostNodes, webhookPort, kubeletPort, metricsPort(cluster), auditData(cluster.Spec.Audit))
 }
 
-func (s *SharedAgent) daemonset(ctx context.Context) error {
-	labels := map[string]string{
-		"cluster": s.cluster.Name,
+// configHash returns the hash of the config of the k3k-kubelet applied when it starts
+func configHash(config string) string {
+	sum := sha256.Sum256([]byte(config))
+	return hex.EncodeToString(sum[:])
+}
+
+// auditData returns the configuration of the audit sinks of the k3k-kubelet
+func auditData(audit *v1alpha1.AuditConfig) string {
+	if audit == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(`
+auditLogPath: %q
+auditWebhookURL: %q
+auditRecordingDir: %q`,
+		audit.LogPath, audit.WebhookURL, audit.RecordingDir)
+}
+
+// metricsPort returns the port of the metrics endpoint of the k3k-kubelet. The metrics are disabled when mirroring
+// the host nodes, since the k3k-kubelet runs in the host network and the port could conflict with other agents.
+func metricsPort(cluster *v1alpha1.Cluster) int {
+	if cluster.Spec.MirrorHostNodes {
+		return 0
+	}
+
+	return sharedAgentMetricsPort
+}
+
+// SharedAgentLabels returns the labels of the pods of the k3k-kubelet of a cluster in shared mode
+func SharedAgentLabels(clusterName string) map[string]string {
+	return map[string]string{
+		"cluster": clusterName,
 		"type":    "agent",
 		"mode":    "shared",
 	}
+}
+
+func (s *SharedAgent) daemonset(ctx context.Context) error {
+	labels := SharedAgentLabels(s.cluster.Name)
 
 	deploy := &apps.DaemonSet{
 		TypeMeta: metav1.TypeMeta{
@@ -141,6 +196,10 @@ func (s *SharedAgent) daemonset(ctx context.Context) error {
 			Template: v1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: labels,
+					// the k3k-kubelet reloads only the version, so the pods are restarted to apply the other changes
+					Annotations: map[string]string{
+						configHashAnnotation: configHash(startupData(s.cluster, s.Name(), s.token, s.serviceIP, s.kubeletPort, s.webhookPort)),
+					},
 				},
 				Spec: s.podSpec(),
 			},
@@ -150,6 +209,30 @@ func (s *SharedAgent) daemonset(ctx context.Context) error {
 	return s.ensureObject(ctx, deploy)
 }
 
+// podDisruptionBudget keeps at least one replica of the k3k-kubelet running during voluntary disruptions, since the
+// webhooks of the virtual cluster, the syncers and the failover of the virtual nodes depend on it.
+// Only an integer minAvailable is supported for the pods of a DaemonSet.
+func (s *SharedAgent) podDisruptionBudget(ctx context.Context) error {
+	pdb := &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PodDisruptionBudget",
+			APIVersion: "policy/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.Name(),
+			Namespace: s.cluster.Namespace,
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: ptr.To(intstr.FromInt32(1)),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: SharedAgentLabels(s.cluster.Name),
+			},
+		},
+	}
+
+	return s.ensureObject(ctx, pdb)
+}
+
 func (s *SharedAgent) podSpec() v1.PodSpec {
 	hostNetwork := false
 	dnsPolicy := v1.DNSClusterFirst
@@ -248,6 +331,16 @@ func (s *SharedAgent) podSpec() v1.PodSpec {
 						ReadOnly:  false,
 					},
 				},
+				// the replica receives the webhook requests once the webhook server is listening
+				ReadinessProbe: &v1.Probe{
+					ProbeHandler: v1.ProbeHandler{
+						TCPSocket: &v1.TCPSocketAction{
+							Port: intstr.FromInt32(int32(s.webhookPort)),
+						},
+					},
+					PeriodSeconds:    2,
+					FailureThreshold: 2,
+				},
 				Ports: []v1.ContainerPort{
 					{
 						Name:          "kubelet-port",
@@ -263,6 +356,15 @@ func (s *SharedAgent) podSpec() v1.PodSpec {
 			},
 		},
 	}
+
+	if port := metricsPort(s.cluster); port != 0 {
+		podSpec.Containers[0].Ports = append(podSpec.Containers[0].Ports, v1.ContainerPort{
+			Name:          "metrics",
+			Protocol:      v1.ProtocolTCP,
+			ContainerPort: int32(port),
+		})
+	}
+
 	for _, imagePullSecret := range s.imagePullSecrets {
 		podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, v1.LocalObjectReference{Name: imagePullSecret})
 	}
@@ -306,6 +408,46 @@ func (s *SharedAgent) service(ctx context.Context) error {
 	return s.ensureObject(ctx, svc)
 }
 
+// metricsService creates the Service of the metrics endpoints of the k3k-kubelet pods, that can be selected by a ServiceMonitor
+func (s *SharedAgent) metricsService(ctx context.Context) error {
+	port := metricsPort(s.cluster)
+	if port == 0 {
+		return nil
+	}
+
+	labels := map[string]string{
+		"cluster": s.cluster.Name,
+		"type":    "agent",
+		"mode":    "shared",
+	}
+
+	svc := &v1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controller.SafeConcatNameWithPrefix(s.cluster.Name, SharedNodeAgentName, "metrics"),
+			Namespace: s.cluster.Namespace,
+			Labels:    labels,
+		},
+		Spec: v1.ServiceSpec{
+			Type:     v1.ServiceTypeClusterIP,
+			Selector: labels,
+			Ports: []v1.ServicePort{
+				{
+					Name:       "metrics",
+					Protocol:   v1.ProtocolTCP,
+					Port:       int32(port),
+					TargetPort: intstr.FromString("metrics"),
+				},
+			},
+		},
+	}
+
+	return s.ensureObject(ctx, svc)
+}
+
 func (s *SharedAgent) dnsService(ctx context.Context) error {
 	dnsServiceName := controller.SafeConcatNameWithPrefix(s.cluster.Name, "kube-dns")
 
@@ -375,33 +517,44 @@ func (s *SharedAgent) role(ctx context.Context) error {
 			Name:      s.Name(),
 			Namespace: s.cluster.Namespace,
 		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				APIGroups: []string{""},
-				Resources: []string{"persistentvolumeclaims", "pods", "pods/log", "pods/attach", "pods/exec", "pods/ephemeralcontainers", "secrets", "configmaps", "services"},
-				Verbs:     []string{"*"},
-			},
-			{
-				APIGroups: []string{"networking.k8s.io"},
-				Resources: []string{"ingresses"},
-				Verbs:     []string{"*"},
-			},
-			{
+		Rules: append(syncedResourcesRules(),
+			rbacv1.PolicyRule{
 				APIGroups: []string{"k3k.io"},
 				Resources: []string{"clusters"},
 				Verbs:     []string{"get", "watch", "list"},
 			},
-			{
+			rbacv1.PolicyRule{
 				APIGroups: []string{"coordination.k8s.io"},
 				Resources: []string{"leases"},
 				Verbs:     []string{"*"},
 			},
-		},
+		),
 	}
 
 	return s.ensureObject(ctx, role)
 }
 
+// syncedResourcesRules returns the rules of the k3k-kubelet on the resources synced in the host cluster
+func syncedResourcesRules() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{""},
+			Resources: []string{"persistentvolumeclaims", "pods", "pods/log", "pods/attach", "pods/exec", "pods/ephemeralcontainers", "secrets", "configmaps", "services"},
+			Verbs:     []string{"*"},
+		},
+		{
+			APIGroups: []string{"networking.k8s.io"},
+			Resources: []string{"ingresses"},
+			Verbs:     []string{"*"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"resourcequotas"},
+			Verbs:     []string{"get", "list"},
+		},
+	}
+}
+
 func (s *SharedAgent) roleBinding(ctx context.Context) error {
 	roleBinding := &rbacv1.RoleBinding{
 		TypeMeta: metav1.TypeMeta{
@@ -429,6 +582,95 @@ func (s *SharedAgent) roleBinding(ctx context.Context) error {
 	return s.ensureObject(ctx, roleBinding)
 }
 
+// hostNamespacesRBAC binds the k3k-kubelet to the resources it syncs in the dedicated host namespaces of the virtual
+// namespaces, created by the controller with the "PerNamespace" namespace mapping. Only the namespaces owned by the
+// Cluster are bound. The Role and RoleBinding are not owned by the Cluster, since they are in another namespace: they
+// are deleted with the namespaces.
+func (s *SharedAgent) hostNamespacesRBAC(ctx context.Context) error {
+	if s.cluster.Spec.NamespaceMapping != v1alpha1.PerNamespaceMapping {
+		return nil
+	}
+
+	var namespaces v1.NamespaceList
+	if err := s.client.List(ctx, &namespaces, HostNamespacesLabels(s.cluster)); err != nil {
+		return err
+	}
+
+	var err error
+
+	for _, namespace := range namespaces.Items {
+		if !namespace.DeletionTimestamp.IsZero() || !OwnsHostNamespace(s.cluster, &namespace) {
+			continue
+		}
+
+		role := &rbacv1.Role{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Role",
+				APIVersion: "rbac.authorization.k8s.io/v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.Name(),
+				Namespace: namespace.Name,
+			},
+			Rules: syncedResourcesRules(),
+		}
+
+		roleBinding := &rbacv1.RoleBinding{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "RoleBinding",
+				APIVersion: "rbac.authorization.k8s.io/v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.Name(),
+				Namespace: namespace.Name,
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     "Role",
+				Name:     s.Name(),
+			},
+			Subjects: []rbacv1.Subject{
+				{
+					Kind:      "ServiceAccount",
+					Name:      s.Name(),
+					Namespace: s.cluster.Namespace,
+				},
+			},
+		}
+
+		err = errors.Join(err, s.ensureUnownedObject(ctx, role), s.ensureUnownedObject(ctx, roleBinding))
+	}
+
+	return err
+}
+
+// ensureUnownedObject creates or updates an object without the owner reference of the Cluster
+func (s *SharedAgent) ensureUnownedObject(ctx context.Context, obj ctrlruntimeclient.Object) error {
+	if err := s.client.Create(ctx, obj); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return s.client.Update(ctx, obj)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// OwnsHostNamespace returns true if the namespace was created by the controller as a dedicated host namespace of the
+// cluster. The labels of the namespace are not enough, since they could be set on any namespace.
+func OwnsHostNamespace(cluster *v1alpha1.Cluster, namespace *v1.Namespace) bool {
+	return cluster.UID != "" && namespace.Annotations[HostNamespaceOwnerAnnotation] == string(cluster.UID)
+}
+
+// HostNamespacesLabels returns the labels of the dedicated host namespaces of the virtual namespaces of the cluster
+func HostNamespacesLabels(cluster *v1alpha1.Cluster) ctrlruntimeclient.MatchingLabels {
+	return ctrlruntimeclient.MatchingLabels{
+		translate.ClusterNameLabel:      cluster.Name,
+		translate.ClusterNamespaceLabel: cluster.Namespace,
+	}
+}
+
 func (s *SharedAgent) webhookTLS(ctx context.Context) error {
 	webhookSecret := &v1.Secret{
 		TypeMeta: metav1.TypeMeta{
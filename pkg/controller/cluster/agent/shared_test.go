@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
 
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
@@ -54,6 +55,7 @@ func Test_sharedAgentData(t *testing.T) {
 				"mirrorHostNodes":  "false",
 				"kubeletPort":      "10250",
 				"webhookPort":      "9443",
+				"metricsPort":      "8083",
 			},
 		},
 		{
@@ -87,6 +89,7 @@ func Test_sharedAgentData(t *testing.T) {
 				"mirrorHostNodes":  "false",
 				"kubeletPort":      "10250",
 				"webhookPort":      "9443",
+				"metricsPort":      "8083",
 			},
 		},
 		{
@@ -117,6 +120,77 @@ func Test_sharedAgentData(t *testing.T) {
 				"mirrorHostNodes":  "false",
 				"kubeletPort":      "10250",
 				"webhookPort":      "9443",
+				"metricsPort":      "8083",
+			},
+		},
+		{
+			name: "mirror host nodes",
+			args: args{
+				cluster: &v1alpha1.Cluster{
+					ObjectMeta: v1.ObjectMeta{
+						Name:      "mycluster",
+						Namespace: "ns-1",
+					},
+					Spec: v1alpha1.ClusterSpec{
+						Version:         "v1.2.3",
+						MirrorHostNodes: true,
+					},
+				},
+				kubeletPort: 10250,
+				webhookPort: 9443,
+				ip:          "10.0.0.21",
+				serviceName: "service-name",
+				token:       "dnjklsdjnksd892389238",
+			},
+			expectedData: map[string]string{
+				"clusterName":      "mycluster",
+				"clusterNamespace": "ns-1",
+				"serverIP":         "10.0.0.21",
+				"serviceName":      "service-name",
+				"token":            "dnjklsdjnksd892389238",
+				"version":          "v1.2.3",
+				"mirrorHostNodes":  "true",
+				"kubeletPort":      "10250",
+				"webhookPort":      "9443",
+				"metricsPort":      "0",
+			},
+		},
+		{
+			name: "audit",
+			args: args{
+				cluster: &v1alpha1.Cluster{
+					ObjectMeta: v1.ObjectMeta{
+						Name:      "mycluster",
+						Namespace: "ns-1",
+					},
+					Spec: v1alpha1.ClusterSpec{
+						Version: "v1.2.3",
+						Audit: &v1alpha1.AuditConfig{
+							LogPath:      "-",
+							RecordingDir: "/var/lib/k3k/recordings",
+						},
+					},
+				},
+				kubeletPort: 10250,
+				webhookPort: 9443,
+				ip:          "10.0.0.21",
+				serviceName: "service-name",
+				token:       "dnjklsdjnksd892389238",
+			},
+			expectedData: map[string]string{
+				"clusterName":       "mycluster",
+				"clusterNamespace":  "ns-1",
+				"serverIP":          "10.0.0.21",
+				"serviceName":       "service-name",
+				"token":             "dnjklsdjnksd892389238",
+				"version":           "v1.2.3",
+				"mirrorHostNodes":   "false",
+				"kubeletPort":       "10250",
+				"webhookPort":       "9443",
+				"metricsPort":       "8083",
+				"auditLogPath":      "-",
+				"auditWebhookURL":   "",
+				"auditRecordingDir": "/var/lib/k3k/recordings",
 			},
 		},
 	}
@@ -133,3 +207,70 @@ func Test_sharedAgentData(t *testing.T) {
 		})
 	}
 }
+
+func Test_configHash(t *testing.T) {
+	cluster := &v1alpha1.Cluster{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "mycluster",
+			Namespace: "ns-1",
+		},
+		Spec: v1alpha1.ClusterSpec{
+			Version: "v1.2.3",
+		},
+	}
+
+	hash := configHash(startupData(cluster, "service-name", "token", "10.0.0.21", 10250, 9443))
+
+	// the version is reloaded by the k3k-kubelet
+	cluster.Spec.Version = "v1.3.0"
+	assert.Equal(t, hash, configHash(startupData(cluster, "service-name", "token", "10.0.0.21", 10250, 9443)))
+
+	cluster.Spec.MirrorHostNodes = true
+	assert.NotEqual(t, hash, configHash(startupData(cluster, "service-name", "token", "10.0.0.21", 10250, 9443)))
+
+	cluster.Spec.MirrorHostNodes = false
+	assert.NotEqual(t, hash, configHash(startupData(cluster, "service-name", "token", "10.0.0.21", 10251, 9443)))
+}
+
+func Test_OwnsHostNamespace(t *testing.T) {
+	cluster := &v1alpha1.Cluster{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "mycluster",
+			Namespace: "ns-1",
+			UID:       "1234",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    bool
+	}{
+		{
+			name:        "namespace created by the controller",
+			annotations: map[string]string{HostNamespaceOwnerAnnotation: "1234"},
+			expected:    true,
+		},
+		{
+			name:        "namespace of another cluster",
+			annotations: map[string]string{HostNamespaceOwnerAnnotation: "5678"},
+		},
+		{
+			name: "namespace with the labels of the cluster only",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace := &corev1.Namespace{
+				ObjectMeta: v1.ObjectMeta{
+					Name:        "mycluster-default",
+					Labels:      HostNamespacesLabels(cluster),
+					Annotations: tt.annotations,
+				},
+			}
+
+			assert.Equal(t, tt.expected, OwnsHostNamespace(cluster, namespace))
+		})
+	}
+}
@@ -22,6 +22,8 @@ const (
 
 type VirtualAgent struct {
 	*Config
+	// Replicas are the replicas of the Deployment of the agents, kept while the removed agents are drained
+	Replicas         *int32
 	serviceIP        string
 	token            string
 	Image            string
@@ -33,6 +35,7 @@ type VirtualAgent struct {
 func NewVirtualAgent(config *Config, serviceIP, token, Image, ImagePullPolicy string, imagePullSecrets []string) *VirtualAgent {
 	return &VirtualAgent{
 		Config:           config,
+		Replicas:         config.cluster.Spec.Agents,
 		serviceIP:        serviceIP,
 		token:            token,
 		Image:            Image,
@@ -42,7 +45,21 @@ func NewVirtualAgent(config *Config, serviceIP, token, Image, ImagePullPolicy st
 }
 
 func (v *VirtualAgent) Name() string {
-	return controller.SafeConcatNameWithPrefix(v.cluster.Name, virtualNodeAgentName)
+	return VirtualAgentName(v.cluster.Name)
+}
+
+// VirtualAgentName returns the name of the Deployment of the agents of a cluster in virtual mode
+func VirtualAgentName(clusterName string) string {
+	return controller.SafeConcatNameWithPrefix(clusterName, virtualNodeAgentName)
+}
+
+// VirtualAgentLabels returns the labels of the pods of the agents of a cluster in virtual mode
+func VirtualAgentLabels(clusterName string) map[string]string {
+	return map[string]string{
+		"cluster": clusterName,
+		"type":    "agent",
+		"mode":    "virtual",
+	}
 }
 
 func (v *VirtualAgent) EnsureResources(ctx context.Context) error {
@@ -87,16 +104,12 @@ with-node-id: true`, serviceIP, token)
 }
 
 func (v *VirtualAgent) deployment(ctx context.Context) error {
-	image := controller.K3SImage(v.cluster, v.Image)
+	image := controller.K3SAgentImage(v.cluster, v.Image)
 
 	const name = "k3k-agent"
 
 	selector := metav1.LabelSelector{
-		MatchLabels: map[string]string{
-			"cluster": v.cluster.Name,
-			"type":    "agent",
-			"mode":    "virtual",
-		},
+		MatchLabels: VirtualAgentLabels(v.cluster.Name),
 	}
 
 	deployment := &apps.Deployment{
@@ -110,7 +123,7 @@ func (v *VirtualAgent) deployment(ctx context.Context) error {
 			Labels:    selector.MatchLabels,
 		},
 		Spec: apps.DeploymentSpec{
-			Replicas: v.cluster.Spec.Agents,
+			Replicas: v.Replicas,
 			Selector: &selector,
 			Template: v1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,629 @@
+package cluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	certutil "github.com/rancher/dynamiclistener/cert"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/backup"
+	k3kcontroller "github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster/server"
+	"github.com/rancher/k3k/pkg/controller/cluster/server/bootstrap"
+)
+
+const (
+	backupController = "k3k-backup-controller"
+
+	// BackupLabel is the label of the jobs of a ClusterBackup, with its name
+	BackupLabel = "k3k.io/backup"
+
+	backupSecretMountPath   = "/etc/k3k/backup"
+	backupVolumeMountPath   = "/var/lib/k3k/backups"
+	backupSecretsKey        = "secrets.json"
+	defaultBackupRetention  = 5
+	etcdClientRenewBefore   = 30 * 24 * time.Hour
+	maxCronJobNameLength    = 52
+	snapshotJobBackoffLimit = 2
+
+	// ConditionSucceeded is the condition of a ClusterBackup reporting the result of the last snapshot
+	ConditionSucceeded = "Succeeded"
+
+	ReasonClusterNotFound    = "ClusterNotFound"
+	ReasonClusterNotReady    = "ClusterNotReady"
+	ReasonScheduled          = "Scheduled"
+	ReasonSnapshotInProgress = "SnapshotInProgress"
+	ReasonSnapshotSaved      = "SnapshotSaved"
+	ReasonSnapshotFailed     = "SnapshotFailed"
+	ReasonUnsupported        = "Unsupported"
+)
+
+type BackupReconciler struct {
+	Client          ctrlruntimeclient.Client
+	Scheme          *runtime.Scheme
+	Image           string
+	ImagePullPolicy string
+}
+
+// AddBackupController adds the controller of the ClusterBackups to the manager. The snapshots are taken by jobs,
+// running the "k3k snapshot save" command of the image.
+func AddBackupController(ctx context.Context, mgr manager.Manager, config *Config, maxConcurrentReconciles int) error {
+	reconciler := BackupReconciler{
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		Image:           config.ControllerImage,
+		ImagePullPolicy: config.ControllerImagePullPolicy,
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ClusterBackup{}).
+		Owns(&batchv1.CronJob{}).
+		// the jobs created by the CronJobs are not owned by the ClusterBackup
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(backupJobHandler)).
+		Watches(&v1alpha1.Cluster{}, handler.EnqueueRequestsFromMapFunc(reconciler.clusterBackupsHandler)).
+		Named(backupController).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles}).
+		Complete(&reconciler)
+}
+
+func backupJobHandler(_ context.Context, object ctrlruntimeclient.Object) []reconcile.Request {
+	backupName, found := object.GetLabels()[BackupLabel]
+	if !found {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: backupName, Namespace: object.GetNamespace()}}}
+}
+
+// clusterBackupsHandler enqueues the backups of a cluster, so that the snapshots start when the cluster is ready
+func (r *BackupReconciler) clusterBackupsHandler(ctx context.Context, object ctrlruntimeclient.Object) []reconcile.Request {
+	var backups v1alpha1.ClusterBackupList
+	if err := r.Client.List(ctx, &backups, ctrlruntimeclient.InNamespace(object.GetNamespace())); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "unable to list the backups of the cluster", "cluster", object.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, backup := range backups.Items {
+		if backup.Spec.ClusterName == object.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: ctrlruntimeclient.ObjectKeyFromObject(&backup)})
+		}
+	}
+
+	return requests
+}
+
+func (r *BackupReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx).WithValues("clusterbackup", req.NamespacedName)
+	ctx = ctrl.LoggerInto(ctx, log)
+
+	var clusterBackup v1alpha1.ClusterBackup
+	if err := r.Client.Get(ctx, req.NamespacedName, &clusterBackup); err != nil {
+		return reconcile.Result{}, ctrlruntimeclient.IgnoreNotFound(err)
+	}
+
+	// the jobs and the secret are deleted with the ClusterBackup, the snapshots are kept in the storage
+	if !clusterBackup.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	orig := clusterBackup.DeepCopy()
+
+	reconcilerErr := r.reconcileBackup(ctx, &clusterBackup)
+
+	if !equality.Semantic.DeepEqual(orig.Status, clusterBackup.Status) {
+		if err := r.Client.Status().Update(ctx, &clusterBackup); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	return reconcile.Result{}, reconcilerErr
+}
+
+func (r *BackupReconciler) reconcileBackup(ctx context.Context, clusterBackup *v1alpha1.ClusterBackup) error {
+	if err := r.updateSnapshotsStatus(ctx, clusterBackup); err != nil {
+		return err
+	}
+
+	var cluster v1alpha1.Cluster
+
+	key := types.NamespacedName{Name: clusterBackup.Spec.ClusterName, Namespace: clusterBackup.Namespace}
+	if err := r.Client.Get(ctx, key, &cluster); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		setBackupReady(clusterBackup, metav1.ConditionFalse, ReasonClusterNotFound, "cluster "+key.Name+" not found")
+
+		return nil
+	}
+
+	if k3kcontroller.ExternalDatastore(&cluster) {
+		setBackupReady(clusterBackup, metav1.ConditionFalse, ReasonUnsupported, "cluster "+key.Name+" has an external datastore, and no etcd to snapshot")
+
+		return nil
+	}
+
+	// the etcd client certificate is signed with the CA of the bootstrap data, available when the cluster is ready
+	if cluster.Status.Phase != v1alpha1.ClusterReady {
+		setBackupReady(clusterBackup, metav1.ConditionFalse, ReasonClusterNotReady, "waiting for cluster "+key.Name+" to be ready")
+
+		return nil
+	}
+
+	if err := r.ensureBackupSecret(ctx, clusterBackup, &cluster); err != nil {
+		setBackupReady(clusterBackup, metav1.ConditionFalse, ReasonProvisioningFailed, err.Error())
+
+		return err
+	}
+
+	if err := r.ensureSnapshotJob(ctx, clusterBackup, &cluster); err != nil {
+		setBackupReady(clusterBackup, metav1.ConditionFalse, ReasonProvisioningFailed, err.Error())
+
+		return err
+	}
+
+	if clusterBackup.Spec.Schedule != "" {
+		setBackupReady(clusterBackup, metav1.ConditionTrue, ReasonScheduled, "snapshots scheduled with "+clusterBackup.Spec.Schedule)
+	} else {
+		setBackupReady(clusterBackup, metav1.ConditionTrue, ReasonProvisioned, "snapshot job created")
+	}
+
+	return nil
+}
+
+func setBackupReady(clusterBackup *v1alpha1.ClusterBackup, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&clusterBackup.Status.Conditions, metav1.Condition{
+		Type:    ConditionReady,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// ensureBackupSecret creates or updates the Secret mounted by the snapshot jobs, with the etcd client certificate
+// and the token and bootstrap Secrets of the cluster stored with the snapshots
+func (r *BackupReconciler) ensureBackupSecret(ctx context.Context, clusterBackup *v1alpha1.ClusterBackup, cluster *v1alpha1.Cluster) error {
+	bootstrapSecret, err := r.secret(ctx, k3kcontroller.SafeConcatNameWithPrefix(cluster.Name, "bootstrap"), cluster.Namespace)
+	if err != nil {
+		return err
+	}
+
+	tokenSecretKey := types.NamespacedName{Name: TokenSecretName(cluster.Name), Namespace: cluster.Namespace}
+	if cluster.Spec.TokenSecretRef != nil {
+		tokenSecretKey = types.NamespacedName{Name: cluster.Spec.TokenSecretRef.Name, Namespace: cluster.Spec.TokenSecretRef.Namespace}
+	}
+
+	tokenSecret, err := r.secret(ctx, tokenSecretKey.Name, tokenSecretKey.Namespace)
+	if err != nil {
+		return err
+	}
+
+	secrets, err := backup.EncodeSecrets(*tokenSecret, *bootstrapSecret)
+	if err != nil {
+		return err
+	}
+
+	backupSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backupResourceName(clusterBackup),
+			Namespace: clusterBackup.Namespace,
+		},
+	}
+
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, backupSecret, func() error {
+		if err := controllerutil.SetControllerReference(clusterBackup, backupSecret, r.Scheme); err != nil {
+			return err
+		}
+
+		if backupSecret.Data == nil {
+			backupSecret.Data = make(map[string][]byte)
+		}
+
+		backupSecret.Data[backupSecretsKey] = secrets
+
+		if etcdClientCertValid(backupSecret.Data[v1.TLSCertKey], time.Now()) {
+			return nil
+		}
+
+		ctrl.LoggerFrom(ctx).Info("generating etcd client certificate of the backup")
+
+		b, err := bootstrap.GetFromSecret(ctx, r.Client, cluster)
+		if err != nil {
+			return err
+		}
+
+		cert, key, err := etcdClientCertKey(b)
+		if err != nil {
+			return err
+		}
+
+		backupSecret.Data[v1.TLSCertKey] = cert
+		backupSecret.Data[v1.TLSPrivateKeyKey] = key
+		backupSecret.Data[v1.ServiceAccountRootCAKey] = []byte(b.ETCDServerCA.Content)
+
+		return nil
+	})
+
+	return err
+}
+
+func (r *BackupReconciler) secret(ctx context.Context, name, namespace string) (*v1.Secret, error) {
+	var secret v1.Secret
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, &secret); err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
+// etcdClientCertValid returns true if the certificate is valid, and doesn't need to be renewed yet
+func etcdClientCertValid(certPEM []byte, now time.Time) bool {
+	if len(certPEM) == 0 {
+		return false
+	}
+
+	certs, err := certutil.ParseCertsPEM(certPEM)
+	if err != nil || len(certs) == 0 {
+		return false
+	}
+
+	return now.Add(etcdClientRenewBefore).Before(certs[0].NotAfter)
+}
+
+// ensureSnapshotJob creates the CronJob of a scheduled backup, or the Job of a single snapshot
+func (r *BackupReconciler) ensureSnapshotJob(ctx context.Context, clusterBackup *v1alpha1.ClusterBackup, cluster *v1alpha1.Cluster) error {
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backupCronJobName(clusterBackup),
+			Namespace: clusterBackup.Namespace,
+		},
+	}
+
+	if clusterBackup.Spec.Schedule == "" {
+		// the schedule was removed
+		if err := r.Client.Delete(ctx, cronJob); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		return r.ensureSingleSnapshotJob(ctx, clusterBackup, cluster)
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, cronJob, func() error {
+		if err := controllerutil.SetControllerReference(clusterBackup, cronJob, r.Scheme); err != nil {
+			return err
+		}
+
+		cronJob.Labels = map[string]string{BackupLabel: clusterBackup.Name}
+		cronJob.Spec.Schedule = clusterBackup.Spec.Schedule
+		cronJob.Spec.ConcurrencyPolicy = batchv1.ForbidConcurrent
+		cronJob.Spec.JobTemplate = batchv1.JobTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{BackupLabel: clusterBackup.Name},
+			},
+			Spec: r.snapshotJobSpec(clusterBackup, cluster),
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// ensureSingleSnapshotJob creates the Job of a backup without schedule, if the snapshot was not taken yet.
+// The Job is not updated, since its spec is immutable.
+func (r *BackupReconciler) ensureSingleSnapshotJob(ctx context.Context, clusterBackup *v1alpha1.ClusterBackup, cluster *v1alpha1.Cluster) error {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backupResourceName(clusterBackup),
+			Namespace: clusterBackup.Namespace,
+			Labels:    map[string]string{BackupLabel: clusterBackup.Name},
+		},
+	}
+
+	if err := r.Client.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(job), job); !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	if clusterBackup.Status.LastSnapshot != "" {
+		return nil
+	}
+
+	if err := controllerutil.SetControllerReference(clusterBackup, job, r.Scheme); err != nil {
+		return err
+	}
+
+	job.Spec = r.snapshotJobSpec(clusterBackup, cluster)
+
+	ctrl.LoggerFrom(ctx).Info("creating snapshot job", "job", job.Name)
+
+	return r.Client.Create(ctx, job)
+}
+
+// snapshotJobSpec returns the spec of the jobs running "k3k snapshot save". The name of the snapshot is the name
+// of the job, and the snapshots of the backup are stored in its own folder.
+func (r *BackupReconciler) snapshotJobSpec(clusterBackup *v1alpha1.ClusterBackup, cluster *v1alpha1.Cluster) batchv1.JobSpec {
+	args := []string{
+		"--name=$(JOB_NAME)",
+		"--etcd-endpoint=" + server.EtcdEndpoint(cluster),
+		"--etcd-tls-dir=" + backupSecretMountPath,
+		"--secrets-file=" + path.Join(backupSecretMountPath, backupSecretsKey),
+		"--retention=" + strconv.Itoa(int(backupRetention(clusterBackup))),
+	}
+
+	env := []v1.EnvVar{
+		{
+			Name: "JOB_NAME",
+			ValueFrom: &v1.EnvVarSource{
+				FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.labels['job-name']"},
+			},
+		},
+	}
+
+	volumes := []v1.Volume{
+		{
+			Name: "backup",
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{SecretName: backupResourceName(clusterBackup)},
+			},
+		},
+	}
+
+	volumeMounts := []v1.VolumeMount{
+		{Name: "backup", MountPath: backupSecretMountPath, ReadOnly: true},
+	}
+
+	var credentialsSecretName string
+	if s3 := clusterBackup.Spec.Storage.S3; s3 != nil {
+		credentialsSecretName = s3.CredentialsSecretName
+	}
+
+	storage := snapshotStorage(clusterBackup, credentialsSecretName)
+
+	args = append(args, storage.args...)
+	env = append(env, storage.env...)
+	volumes = append(volumes, storage.volumes...)
+	volumeMounts = append(volumeMounts, storage.volumeMounts...)
+
+	return batchv1.JobSpec{
+		BackoffLimit: ptr.To[int32](snapshotJobBackoffLimit),
+		Template: v1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{BackupLabel: clusterBackup.Name},
+			},
+			Spec: v1.PodSpec{
+				RestartPolicy:                v1.RestartPolicyNever,
+				AutomountServiceAccountToken: ptr.To(false),
+				Containers: []v1.Container{
+					{
+						Name:            "snapshot",
+						Image:           r.Image,
+						ImagePullPolicy: v1.PullPolicy(r.ImagePullPolicy),
+						Command:         []string{"k3k", "snapshot", "save"},
+						Args:            args,
+						Env:             env,
+						VolumeMounts:    volumeMounts,
+					},
+				},
+				Volumes: volumes,
+			},
+		},
+	}
+}
+
+// snapshotStorageConfig is the configuration of the "k3k snapshot" commands accessing the storage of a backup
+type snapshotStorageConfig struct {
+	args         []string
+	env          []v1.EnvVar
+	volumes      []v1.Volume
+	volumeMounts []v1.VolumeMount
+}
+
+// snapshotStorage returns the configuration of the storage of the backup. The snapshots of the backup are stored
+// in its own folder, and the S3 credentials are read from the Secret with the name.
+func snapshotStorage(clusterBackup *v1alpha1.ClusterBackup, credentialsSecretName string) snapshotStorageConfig {
+	var storage snapshotStorageConfig
+
+	if pvc := clusterBackup.Spec.Storage.PersistentVolumeClaim; pvc != nil {
+		storage.args = append(storage.args, "--dir="+path.Join(backupVolumeMountPath, clusterBackup.Name))
+
+		storage.volumes = append(storage.volumes, v1.Volume{
+			Name: "snapshots",
+			VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: pvc.ClaimName},
+			},
+		})
+
+		storage.volumeMounts = append(storage.volumeMounts, v1.VolumeMount{Name: "snapshots", MountPath: backupVolumeMountPath})
+	}
+
+	if s3 := clusterBackup.Spec.Storage.S3; s3 != nil {
+		storage.args = append(storage.args,
+			"--s3-endpoint="+s3.Endpoint,
+			"--s3-bucket="+s3.Bucket,
+			"--s3-folder="+path.Join(s3.Folder, clusterBackup.Namespace, clusterBackup.Name),
+			"--s3-region="+s3.Region,
+			"--s3-insecure="+strconv.FormatBool(s3.Insecure),
+			"--s3-skip-ssl-verify="+strconv.FormatBool(s3.SkipSSLVerify),
+		)
+
+		// the credentials are read from the environment by the command
+		if credentialsSecretName != "" {
+			storage.env = append(storage.env,
+				secretKeyEnvVar("S3_ACCESS_KEY_ID", credentialsSecretName, "accessKeyID"),
+				secretKeyEnvVar("S3_SECRET_ACCESS_KEY", credentialsSecretName, "secretAccessKey"),
+			)
+		}
+	}
+
+	return storage
+}
+
+func secretKeyEnvVar(name, secretName, key string) v1.EnvVar {
+	return v1.EnvVar{
+		Name: name,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+// updateSnapshotsStatus records the snapshots saved by the jobs of the backup, and the result of the last job
+func (r *BackupReconciler) updateSnapshotsStatus(ctx context.Context, clusterBackup *v1alpha1.ClusterBackup) error {
+	var jobs batchv1.JobList
+	if err := r.Client.List(ctx, &jobs, ctrlruntimeclient.InNamespace(clusterBackup.Namespace), ctrlruntimeclient.MatchingLabels{BackupLabel: clusterBackup.Name}); err != nil {
+		return err
+	}
+
+	recordSnapshots(clusterBackup, jobs.Items)
+
+	return nil
+}
+
+// completedSnapshot is a snapshot saved by a completed job
+type completedSnapshot struct {
+	name        string
+	completedAt metav1.Time
+}
+
+// compareSnapshots orders the snapshots by completion time, and by name if they completed at the same time
+func compareSnapshots(a, b completedSnapshot) int {
+	if c := a.completedAt.Compare(b.completedAt.Time); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a.name, b.name)
+}
+
+// recordSnapshots adds the snapshots of the completed jobs to the status in the order of their completion, keeping
+// the number of snapshots of the retention, and sets the Succeeded condition from the last job. The last recorded
+// snapshot is a high-water mark: the jobs completed before it are skipped, since the CronJob keeps the history of
+// the jobs whose snapshots were removed by the retention.
+func recordSnapshots(clusterBackup *v1alpha1.ClusterBackup, jobs []batchv1.Job) {
+	slices.SortFunc(jobs, func(a, b batchv1.Job) int {
+		return a.CreationTimestamp.Compare(b.CreationTimestamp.Time)
+	})
+
+	status := &clusterBackup.Status
+
+	var snapshots []completedSnapshot
+
+	for _, job := range jobs {
+		if completed := jobCondition(&job, batchv1.JobComplete); completed != nil {
+			snapshots = append(snapshots, completedSnapshot{name: job.Name, completedAt: completed.LastTransitionTime})
+		}
+	}
+
+	slices.SortFunc(snapshots, compareSnapshots)
+
+	for _, snapshot := range snapshots {
+		if slices.Contains(status.Snapshots, snapshot.name) {
+			continue
+		}
+
+		if status.LastSnapshotTime != nil && compareSnapshots(snapshot, completedSnapshot{name: status.LastSnapshot, completedAt: *status.LastSnapshotTime}) <= 0 {
+			continue
+		}
+
+		status.Snapshots = append(status.Snapshots, snapshot.name)
+		status.LastSnapshot = snapshot.name
+		status.LastSnapshotTime = ptr.To(snapshot.completedAt)
+	}
+
+	if retention := int(backupRetention(clusterBackup)); len(status.Snapshots) > retention {
+		status.Snapshots = status.Snapshots[len(status.Snapshots)-retention:]
+	}
+
+	if len(jobs) == 0 {
+		return
+	}
+
+	lastJob := &jobs[len(jobs)-1]
+
+	condition := metav1.Condition{
+		Type:    ConditionSucceeded,
+		Status:  metav1.ConditionUnknown,
+		Reason:  ReasonSnapshotInProgress,
+		Message: "snapshot " + lastJob.Name + " in progress",
+	}
+
+	if jobCondition(lastJob, batchv1.JobComplete) != nil {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ReasonSnapshotSaved
+		condition.Message = "snapshot " + lastJob.Name + " saved"
+	}
+
+	if failed := jobCondition(lastJob, batchv1.JobFailed); failed != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonSnapshotFailed
+		condition.Message = fmt.Sprintf("snapshot %s failed: %s", lastJob.Name, failed.Message)
+	}
+
+	meta.SetStatusCondition(&status.Conditions, condition)
+}
+
+// jobCondition returns the condition of the job if true
+func jobCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) *batchv1.JobCondition {
+	for i, condition := range job.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == v1.ConditionTrue {
+			return &job.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+func backupRetention(clusterBackup *v1alpha1.ClusterBackup) int32 {
+	if clusterBackup.Spec.Retention < 1 {
+		return defaultBackupRetention
+	}
+
+	return clusterBackup.Spec.Retention
+}
+
+// backupResourceName returns the name of the Secret and of the Job of the backup
+func backupResourceName(clusterBackup *v1alpha1.ClusterBackup) string {
+	return k3kcontroller.SafeConcatNameWithPrefix(clusterBackup.Name, "backup")
+}
+
+// backupCronJobName returns the name of the CronJob of the backup, that can't be longer than 52 characters
+// since the names of its jobs have an 11 characters suffix
+func backupCronJobName(clusterBackup *v1alpha1.ClusterBackup) string {
+	name := backupResourceName(clusterBackup)
+	if len(name) <= maxCronJobNameLength {
+		return name
+	}
+
+	digest := sha256.Sum256([]byte(name))
+
+	return name[:maxCronJobNameLength-6] + "-" + hex.EncodeToString(digest[:])[:5]
+}
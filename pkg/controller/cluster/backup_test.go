@@ -0,0 +1,195 @@
+package cluster_test
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/controller/cluster"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClusterBackup Controller", Label("controller"), Label("ClusterBackup"), func() {
+	Context("creating a ClusterBackup", func() {
+		var (
+			namespace string
+			ctx       context.Context
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			createdNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "ns-"}}
+			err := k8sClient.Create(context.Background(), createdNS)
+			Expect(err).To(Not(HaveOccurred()))
+
+			namespace = createdNS.Name
+		})
+
+		newClusterBackup := func(clusterName string) *v1alpha1.ClusterBackup {
+			return &v1alpha1.ClusterBackup{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "backup-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterBackupSpec{
+					ClusterName: clusterName,
+					Storage: v1alpha1.BackupStorage{
+						PersistentVolumeClaim: &v1alpha1.BackupVolumeStorage{ClaimName: "snapshots"},
+					},
+				},
+			}
+		}
+
+		readyCondition := func(clusterBackup *v1alpha1.ClusterBackup) func() *metav1.Condition {
+			return func() *metav1.Condition {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterBackup), clusterBackup)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return meta.FindStatusCondition(clusterBackup.Status.Conditions, cluster.ConditionReady)
+			}
+		}
+
+		It("will be created with the default retention", func() {
+			clusterBackup := newClusterBackup("mycluster")
+
+			err := k8sClient.Create(ctx, clusterBackup)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Expect(clusterBackup.Spec.Retention).To(Equal(int32(5)))
+		})
+
+		It("will not be created with two storages", func() {
+			clusterBackup := newClusterBackup("mycluster")
+			clusterBackup.Spec.Storage.S3 = &v1alpha1.BackupS3Storage{
+				Endpoint: "minio:9000",
+				Bucket:   "snapshots",
+			}
+
+			err := k8sClient.Create(ctx, clusterBackup)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("will wait for the cluster to exist", func() {
+			clusterBackup := newClusterBackup("notfound")
+
+			err := k8sClient.Create(ctx, clusterBackup)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(readyCondition(clusterBackup)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(And(
+					Not(BeNil()),
+					HaveField("Status", metav1.ConditionFalse),
+					HaveField("Reason", cluster.ReasonClusterNotFound),
+				))
+		})
+
+		It("will keep the last snapshots of the retention", func() {
+			clusterBackup := newClusterBackup("notfound")
+			clusterBackup.Spec.Retention = 2
+
+			err := k8sClient.Create(ctx, clusterBackup)
+			Expect(err).To(Not(HaveOccurred()))
+
+			// the CronJob keeps the history of three jobs, more than the retention
+			completedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+			var jobNames []string
+
+			for i := range 3 {
+				job := &batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{
+						GenerateName: clusterBackup.Name + "-",
+						Namespace:    namespace,
+						Labels:       map[string]string{cluster.BackupLabel: clusterBackup.Name},
+					},
+					Spec: batchv1.JobSpec{
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								RestartPolicy: corev1.RestartPolicyNever,
+								Containers:    []corev1.Container{{Name: "snapshot", Image: "rancher/k3k"}},
+							},
+						},
+					},
+				}
+
+				err := k8sClient.Create(ctx, job)
+				Expect(err).To(Not(HaveOccurred()))
+
+				jobCompletedAt := metav1.NewTime(completedAt.Add(time.Duration(i) * time.Minute))
+
+				job.Status = batchv1.JobStatus{
+					StartTime:      &jobCompletedAt,
+					CompletionTime: &jobCompletedAt,
+					Succeeded:      1,
+					Conditions: []batchv1.JobCondition{
+						{Type: batchv1.JobSuccessCriteriaMet, Status: corev1.ConditionTrue, LastTransitionTime: jobCompletedAt},
+						{Type: batchv1.JobComplete, Status: corev1.ConditionTrue, LastTransitionTime: jobCompletedAt},
+					},
+				}
+
+				err = k8sClient.Status().Update(ctx, job)
+				Expect(err).To(Not(HaveOccurred()))
+
+				jobNames = append(jobNames, job.Name)
+			}
+
+			snapshots := func() []string {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterBackup), clusterBackup)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return clusterBackup.Status.Snapshots
+			}
+
+			Eventually(snapshots).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(jobNames[1:]))
+
+			// the snapshot of the first job, removed by the retention, is not recorded again
+			Consistently(snapshots).
+				WithTimeout(time.Second * 5).
+				WithPolling(time.Second).
+				Should(Equal(jobNames[1:]))
+
+			Expect(clusterBackup.Status.LastSnapshot).To(Equal(jobNames[2]))
+		})
+
+		It("will wait for the cluster to be ready", func() {
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			clusterBackup := newClusterBackup(virtualCluster.Name)
+
+			err = k8sClient.Create(ctx, clusterBackup)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(readyCondition(clusterBackup)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(And(
+					Not(BeNil()),
+					HaveField("Status", metav1.ConditionFalse),
+					HaveField("Reason", cluster.ReasonClusterNotReady),
+				))
+		})
+	})
+})
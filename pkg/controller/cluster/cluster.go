@@ -23,6 +23,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
@@ -33,8 +34,10 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
 
+	"github.com/rancher/k3k/k3k-kubelet/translate"
 	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
 	"github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster/activity"
 	"github.com/rancher/k3k/pkg/controller/cluster/agent"
 	"github.com/rancher/k3k/pkg/controller/cluster/server"
 	"github.com/rancher/k3k/pkg/controller/cluster/server/bootstrap"
@@ -54,6 +57,10 @@ const (
 	defaultSharedClusterCIDR  = "10.42.0.0/16"
 	defaultSharedServiceCIDR  = "10.43.0.0/16"
 	memberRemovalTimeout      = time.Minute * 1
+
+	// healthCheckInterval is the period of the health checks of the ready clusters, the components of the virtual
+	// clusters are not watched
+	healthCheckInterval = time.Minute * 1
 )
 
 var (
@@ -71,6 +78,11 @@ type Config struct {
 	K3SServerImagePullPolicy    string
 	ServerImagePullSecrets      []string
 	AgentImagePullSecrets       []string
+	ControllerImage             string
+	ControllerImagePullPolicy   string
+	ActivityAddress             string
+	ActivityURL                 string
+	ControllerNamespace         string
 }
 
 type ClusterReconciler struct {
@@ -78,6 +90,9 @@ type ClusterReconciler struct {
 	Client          client.Client
 	Scheme          *runtime.Scheme
 	PortAllocator   *agent.PortAllocator
+	ActivityTracker *activity.Tracker
+	// ActivityCA is the CA of the activity tracker, pinned by the clusters
+	ActivityCA []byte
 
 	record.EventRecorder
 	Config
@@ -114,22 +129,76 @@ func Add(ctx context.Context, mgr manager.Manager, config *Config, maxConcurrent
 			K3SServerImagePullPolicy:    config.K3SServerImagePullPolicy,
 			ServerImagePullSecrets:      config.ServerImagePullSecrets,
 			AgentImagePullSecrets:       config.AgentImagePullSecrets,
+			ControllerImage:             config.ControllerImage,
+			ControllerImagePullPolicy:   config.ControllerImagePullPolicy,
+			ActivityAddress:             config.ActivityAddress,
+			ActivityURL:                 config.ActivityURL,
+			ControllerNamespace:         config.ControllerNamespace,
 		},
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.Cluster{}).
 		Watches(&v1.Namespace{}, namespaceEventHandler(&reconciler)).
 		Owns(&apps.StatefulSet{}).
+		Owns(&apps.Deployment{}).
+		Owns(&apps.DaemonSet{}).
 		Owns(&v1.Service{}).
-		WithOptions(ctrlcontroller.Options{MaxConcurrentReconciles: maxConcurrentReconciles}).
-		Complete(&reconciler)
+		WithOptions(ctrlcontroller.Options{MaxConcurrentReconciles: maxConcurrentReconciles})
+
+	// the clusters are reconciled when a new activity is recorded, to update their status and to wake them up
+	if config.ActivityURL != "" {
+		// the cache of the manager is not started yet
+		uncachedClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+		if err != nil {
+			return err
+		}
+
+		certificate, err := activity.EnsureCertificate(ctx, uncachedClient, config.ControllerNamespace, config.ActivityURL)
+		if err != nil {
+			return err
+		}
+
+		reconciler.ActivityTracker = activity.NewTracker(reconciler.activityToken)
+		reconciler.ActivityCA = certificate.CA
+
+		if config.ActivityAddress != "" {
+			if err := mgr.Add(reconciler.ActivityTracker.Server(config.ActivityAddress, certificate)); err != nil {
+				return err
+			}
+		}
+
+		builder = builder.WatchesRawSource(source.Channel(reconciler.ActivityTracker.Events(), &handler.EnqueueRequestForObject{}))
+	}
+
+	return builder.Complete(&reconciler)
+}
+
+// activityToken returns the token authenticating the requests of the servers and of the wake proxy of a cluster
+// to the activity tracker
+func (c *ClusterReconciler) activityToken(ctx context.Context, cluster types.NamespacedName) (string, error) {
+	var activitySecret v1.Secret
+
+	key := types.NamespacedName{Name: activitySecretName(cluster.Name), Namespace: cluster.Namespace}
+	if err := c.Client.Get(ctx, key, &activitySecret); err != nil {
+		return "", err
+	}
+
+	return string(activitySecret.Data["token"]), nil
 }
 
 func namespaceEventHandler(r *ClusterReconciler) handler.Funcs {
 	return handler.Funcs{
-		// We don't need to update for create or delete events
-		CreateFunc: func(context.Context, event.CreateEvent, workqueue.TypedRateLimitingInterface[reconcile.Request]) {},
+		// When a dedicated host namespace of a virtual namespace is created by the controller
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			labels := e.Object.GetLabels()
+
+			clusterName, clusterNamespace := labels[translate.ClusterNameLabel], labels[translate.ClusterNamespaceLabel]
+			if clusterName != "" && clusterNamespace != "" {
+				q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Name: clusterName, Namespace: clusterNamespace}})
+			}
+		},
+		// We don't need to update for delete events
 		DeleteFunc: func(context.Context, event.DeleteEvent, workqueue.TypedRateLimitingInterface[reconcile.Request]) {},
 		// When a Namespace is updated, if it has the "policy.k3k.io/policy-name" label
 		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
@@ -203,6 +272,21 @@ func (c *ClusterReconciler) Reconcile(ctx context.Context, req reconcile.Request
 		return reconcile.Result{Requeue: true}, nil
 	}
 
+	// a wake up request is recorded as an activity of the cluster, waking it up on the next reconciliation
+	if _, found := cluster.Annotations[WakeUpAnnotation]; found {
+		if c.ActivityTracker != nil {
+			c.ActivityTracker.Record(req.NamespacedName, time.Now(), true)
+		}
+
+		delete(cluster.Annotations, WakeUpAnnotation)
+
+		if err := c.Client.Update(ctx, &cluster); err != nil {
+			return reconcile.Result{}, err
+		}
+
+		return reconcile.Result{Requeue: true}, nil
+	}
+
 	orig := cluster.DeepCopy()
 
 	reconcilerErr := c.reconcileCluster(ctx, &cluster)
@@ -220,6 +304,18 @@ func (c *ClusterReconciler) Reconcile(ctx context.Context, req reconcile.Request
 			return reconcile.Result{RequeueAfter: time.Second * 10}, nil
 		}
 
+		// the StatefulSet of the servers is created again once deleted
+		if errors.Is(reconcilerErr, ErrRecreatingServers) {
+			log.Info("server statefulset recreated to resize its volumes, requeueing")
+			return reconcile.Result{RequeueAfter: upgradeCheckInterval}, nil
+		}
+
+		// the PersistentVolumes are not watched, and are checked again periodically
+		if errors.Is(reconcilerErr, ErrVolumesUnavailable) {
+			log.Info("persistent volumes not available, requeueing")
+			return reconcile.Result{RequeueAfter: time.Second * 10}, nil
+		}
+
 		return reconcile.Result{}, reconcilerErr
 	}
 
@@ -230,11 +326,32 @@ func (c *ClusterReconciler) Reconcile(ctx context.Context, req reconcile.Request
 		}
 	}
 
-	return reconcile.Result{}, nil
+	// the health of the upgraded servers, the capacity of the resized volumes, the drained agents, the components of
+	// the provisioning and degraded clusters, and the new virtual namespaces with the "PerNamespace" mapping are
+	// checked periodically
+	if upgrading(&cluster) || resizing(&cluster) || scaling(&cluster) ||
+		cluster.Status.Phase == v1alpha1.ClusterProvisioning || cluster.Status.Phase == v1alpha1.ClusterDegraded ||
+		(cluster.Spec.NamespaceMapping == v1alpha1.PerNamespaceMapping && !controller.Suspended(&cluster)) {
+		return reconcile.Result{RequeueAfter: upgradeCheckInterval}, nil
+	}
+
+	// the idle clusters are put to sleep when their idle timeout expires
+	requeueAfter, err := c.sleepRequeue(ctx, &cluster)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// the components of the ready clusters are checked on a fixed interval
+	if cluster.Status.Phase == v1alpha1.ClusterReady && (requeueAfter == 0 || requeueAfter > healthCheckInterval) {
+		requeueAfter = healthCheckInterval
+	}
+
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (c *ClusterReconciler) reconcileCluster(ctx context.Context, cluster *v1alpha1.Cluster) error {
 	err := c.reconcile(ctx, cluster)
+	c.updateHealth(ctx, cluster)
 	c.updateStatus(cluster, err)
 
 	return err
@@ -251,6 +368,8 @@ func (c *ClusterReconciler) reconcile(ctx context.Context, cluster *v1alpha1.Clu
 	policyName, found := ns.Labels[policy.PolicyNameLabelKey]
 	cluster.Status.PolicyName = policyName
 
+	var autoSleep *v1alpha1.AutoSleepConfig
+
 	if found && policyName != "" {
 		var policy v1alpha1.VirtualClusterPolicy
 		if err := c.Client.Get(ctx, client.ObjectKey{Name: policyName}, &policy); err != nil {
@@ -258,10 +377,19 @@ func (c *ClusterReconciler) reconcile(ctx context.Context, cluster *v1alpha1.Clu
 		}
 
 		if err := c.validate(cluster, policy); err != nil {
+			setPolicyCompliant(cluster, metav1.ConditionFalse, ReasonPolicyViolation, err.Error())
 			return err
 		}
+
+		autoSleep = policy.Spec.AutoSleep
+
+		setPolicyCompliant(cluster, metav1.ConditionTrue, ReasonCompliant, "cluster complies with policy "+policyName)
+	} else {
+		setPolicyCompliant(cluster, metav1.ConditionTrue, ReasonNoPolicy, "no policy bound to the namespace")
 	}
 
+	c.reconcileSleep(ctx, cluster, autoSleep)
+
 	// if the Version is not specified we will try to use the same Kubernetes version of the host.
 	// This version is stored in the Status object, and it will not be updated if already set.
 	if cluster.Spec.Version == "" && cluster.Status.HostVersion == "" {
@@ -277,6 +405,21 @@ func (c *ClusterReconciler) reconcile(ctx context.Context, cluster *v1alpha1.Clu
 		cluster.Status.HostVersion = k8sVersion + "-k3s1"
 	}
 
+	c.reconcileVersion(cluster)
+
+	if err := validateDatastore(cluster); err != nil {
+		return err
+	}
+
+	if err := validateVolumes(cluster); err != nil {
+		return err
+	}
+
+	clusterBackup, err := c.restoreBackup(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
 	token, err := c.token(ctx, cluster)
 	if err != nil {
 		return err
@@ -284,6 +427,28 @@ func (c *ClusterReconciler) reconcile(ctx context.Context, cluster *v1alpha1.Clu
 
 	s := server.New(cluster, c.Client, token, c.K3SServerImage, c.K3SServerImagePullPolicy, c.ServerImagePullSecrets)
 
+	if err := c.externalDatastore(ctx, cluster, s); err != nil {
+		return err
+	}
+
+	activitySecret, err := c.ensureActivitySecret(ctx, cluster, autoSleep)
+	if err != nil {
+		return err
+	}
+
+	if activitySecret != "" {
+		s.TrackActivity(activitySecret)
+	}
+
+	if clusterBackup != nil {
+		restoreContainer, restoreVolumes, err := c.restoreContainer(ctx, cluster, clusterBackup)
+		if err != nil {
+			return err
+		}
+
+		s.Restore(restoreContainer, restoreVolumes...)
+	}
+
 	cluster.Status.ClusterCIDR = cluster.Spec.ClusterCIDR
 	if cluster.Status.ClusterCIDR == "" {
 		cluster.Status.ClusterCIDR = defaultVirtualClusterCIDR
@@ -314,11 +479,25 @@ func (c *ClusterReconciler) reconcile(ctx context.Context, cluster *v1alpha1.Clu
 		}
 	}
 
-	if err := c.ensureNetworkPolicy(ctx, cluster); err != nil {
+	hostNamespaces, err := c.hostNamespaces(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	if err := c.ensureHostNamespaces(ctx, cluster, hostNamespaces); err != nil {
+		return err
+	}
+
+	if err := c.ensureNetworkPolicy(ctx, cluster, hostNamespaces); err != nil {
+		return err
+	}
+
+	wakeProxy, err := c.ensureWakeProxy(ctx, cluster, autoSleep)
+	if err != nil {
 		return err
 	}
 
-	service, err := c.ensureClusterService(ctx, cluster)
+	service, err := c.ensureClusterService(ctx, cluster, wakeProxy)
 	if err != nil {
 		return err
 	}
@@ -329,6 +508,18 @@ func (c *ClusterReconciler) reconcile(ctx context.Context, cluster *v1alpha1.Clu
 		return err
 	}
 
+	if err := c.resizeVolumes(ctx, cluster); err != nil {
+		return err
+	}
+
+	if controller.Suspended(cluster) {
+		return c.suspend(ctx, cluster, s)
+	}
+
+	if err := c.ensureStaticVolumes(ctx, cluster); err != nil {
+		return err
+	}
+
 	if err := c.server(ctx, cluster, s); err != nil {
 		return err
 	}
@@ -337,15 +528,35 @@ func (c *ClusterReconciler) reconcile(ctx context.Context, cluster *v1alpha1.Clu
 		return err
 	}
 
+	if err := c.reconcileAgentsUpgrade(ctx, cluster, serviceIP); err != nil {
+		return err
+	}
+
 	if err := c.ensureIngress(ctx, cluster); err != nil {
 		return err
 	}
 
 	if err := c.ensureBootstrapSecret(ctx, cluster, serviceIP, token); err != nil {
+		setComponentCondition(cluster, ConditionBootstrapReady, false, err.Error())
 		return err
 	}
 
+	setComponentCondition(cluster, ConditionBootstrapReady, true, "bootstrap data fetched from the servers")
+
+	// the server answers with the token of the cluster once the snapshot is restored, and the servers
+	// can be scaled up on the next reconciliation
+	if clusterBackup != nil {
+		setRestored(cluster)
+	}
+
 	if err := c.ensureKubeconfigSecret(ctx, cluster, serviceIP, 443); err != nil {
+		setComponentCondition(cluster, ConditionKubeconfigReady, false, err.Error())
+		return err
+	}
+
+	setComponentCondition(cluster, ConditionKubeconfigReady, true, "kubeconfig secret generated")
+
+	if err := c.createHostNamespaces(ctx, cluster, serviceIP, hostNamespaces); err != nil {
 		return err
 	}
 
@@ -401,6 +612,9 @@ func (c *ClusterReconciler) ensureKubeconfigSecret(ctx context.Context, cluster
 		return err
 	}
 
+	currentContext := kubeconfig.Contexts[kubeconfig.CurrentContext]
+	cluster.Status.Endpoint = kubeconfig.Clusters[currentContext.Cluster].Server
+
 	kubeconfigSecret := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      controller.SafeConcatNameWithPrefix(cluster.Name, "kubeconfig"),
@@ -459,8 +673,25 @@ func (c *ClusterReconciler) createClusterConfigs(ctx context.Context, cluster *v
 	return nil
 }
 
-func (c *ClusterReconciler) ensureNetworkPolicy(ctx context.Context, cluster *v1alpha1.Cluster) error {
-	log := ctrl.LoggerFrom(ctx)
+func (c *ClusterReconciler) ensureNetworkPolicy(ctx context.Context, cluster *v1alpha1.Cluster, hostNamespaces []v1.Namespace) error {
+	namespaces := []string{cluster.Namespace}
+	for _, namespace := range hostNamespaces {
+		namespaces = append(namespaces, namespace.Name)
+	}
+
+	for _, namespace := range namespaces {
+		if err := c.ensureNamespaceNetworkPolicy(ctx, cluster, namespace); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureNamespaceNetworkPolicy ensures the network policy of the cluster in the namespace of the Cluster,
+// or in one of its dedicated host namespaces
+func (c *ClusterReconciler) ensureNamespaceNetworkPolicy(ctx context.Context, cluster *v1alpha1.Cluster, namespace string) error {
+	log := ctrl.LoggerFrom(ctx).WithValues("namespace", namespace)
 	log.Info("ensuring network policy")
 
 	networkPolicyName := controller.SafeConcatNameWithPrefix(cluster.Name)
@@ -470,17 +701,70 @@ func (c *ClusterReconciler) ensureNetworkPolicy(ctx context.Context, cluster *v1
 		netpol := &networkingv1.NetworkPolicy{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      networkPolicyName,
-				Namespace: cluster.Namespace,
+				Namespace: namespace,
 			},
 		}
 
 		return client.IgnoreNotFound(c.Client.Delete(ctx, netpol))
 	}
 
+	egressPeers := []networkingv1.NetworkPolicyPeer{
+		{
+			IPBlock: &networkingv1.IPBlock{
+				CIDR:   "0.0.0.0/0",
+				Except: []string{cluster.Status.ClusterCIDR},
+			},
+		},
+		{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"kubernetes.io/metadata.name": cluster.Namespace,
+				},
+			},
+		},
+		{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"kubernetes.io/metadata.name": metav1.NamespaceSystem,
+				},
+			},
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"k8s-app": "kube-dns",
+				},
+			},
+		},
+	}
+
+	// the pods of the virtual cluster can reach each other in all its host namespaces
+	if cluster.Spec.NamespaceMapping == v1alpha1.PerNamespaceMapping {
+		egressPeers = append(egressPeers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: agent.HostNamespacesLabels(cluster),
+			},
+		})
+	}
+
+	egressRules := []networkingv1.NetworkPolicyEgressRule{
+		{
+			To: egressPeers,
+		},
+	}
+
+	// the servers and the wake proxy reach the activity tracker of the controller
+	if c.ActivityURL != "" {
+		activityRule, err := activity.EgressRule(c.ActivityURL, c.ControllerNamespace)
+		if err != nil {
+			return err
+		}
+
+		egressRules = append(egressRules, activityRule)
+	}
+
 	expectedNetworkPolicy := &networkingv1.NetworkPolicy{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      controller.SafeConcatNameWithPrefix(cluster.Name),
-			Namespace: cluster.Namespace,
+			Name:      networkPolicyName,
+			Namespace: namespace,
 		},
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "NetworkPolicy",
@@ -494,45 +778,18 @@ func (c *ClusterReconciler) ensureNetworkPolicy(ctx context.Context, cluster *v1
 			Ingress: []networkingv1.NetworkPolicyIngressRule{
 				{},
 			},
-			Egress: []networkingv1.NetworkPolicyEgressRule{
-				{
-					To: []networkingv1.NetworkPolicyPeer{
-						{
-							IPBlock: &networkingv1.IPBlock{
-								CIDR:   "0.0.0.0/0",
-								Except: []string{cluster.Status.ClusterCIDR},
-							},
-						},
-						{
-							NamespaceSelector: &metav1.LabelSelector{
-								MatchLabels: map[string]string{
-									"kubernetes.io/metadata.name": cluster.Namespace,
-								},
-							},
-						},
-						{
-							NamespaceSelector: &metav1.LabelSelector{
-								MatchLabels: map[string]string{
-									"kubernetes.io/metadata.name": metav1.NamespaceSystem,
-								},
-							},
-							PodSelector: &metav1.LabelSelector{
-								MatchLabels: map[string]string{
-									"k8s-app": "kube-dns",
-								},
-							},
-						},
-					},
-				},
-			},
+			Egress: egressRules,
 		},
 	}
 
 	currentNetworkPolicy := expectedNetworkPolicy.DeepCopy()
 
 	result, err := controllerutil.CreateOrUpdate(ctx, c.Client, currentNetworkPolicy, func() error {
-		if err := controllerutil.SetControllerReference(cluster, currentNetworkPolicy, c.Scheme); err != nil {
-			return err
+		// the network policies in the dedicated host namespaces are deleted with their namespace
+		if namespace == cluster.Namespace {
+			if err := controllerutil.SetControllerReference(cluster, currentNetworkPolicy, c.Scheme); err != nil {
+				return err
+			}
 		}
 
 		currentNetworkPolicy.Spec = expectedNetworkPolicy.Spec
@@ -551,11 +808,15 @@ func (c *ClusterReconciler) ensureNetworkPolicy(ctx context.Context, cluster *v1
 	return nil
 }
 
-func (c *ClusterReconciler) ensureClusterService(ctx context.Context, cluster *v1alpha1.Cluster) (*v1.Service, error) {
+func (c *ClusterReconciler) ensureClusterService(ctx context.Context, cluster *v1alpha1.Cluster, wakeProxy bool) (*v1.Service, error) {
 	log := ctrl.LoggerFrom(ctx)
 	log.Info("ensuring cluster service")
 
 	expectedService := server.Service(cluster)
+	if wakeProxy {
+		expectedService.Spec.Selector = server.WakeProxyLabels(cluster)
+	}
+
 	currentService := expectedService.DeepCopy()
 
 	result, err := controllerutil.CreateOrUpdate(ctx, c.Client, currentService, func() error {
@@ -635,14 +896,37 @@ func (c *ClusterReconciler) server(ctx context.Context, cluster *v1alpha1.Cluste
 		return err
 	}
 
+	partition, err := c.serverPartition(ctx, cluster, expectedServerStatefulSet)
+	if err != nil {
+		return err
+	}
+
+	expectedServerStatefulSet.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{
+		Type:          apps.RollingUpdateStatefulSetStrategyType,
+		RollingUpdate: &apps.RollingUpdateStatefulSetStrategy{Partition: &partition},
+	}
+
+	replicas, err := c.serverReplicas(ctx, cluster, expectedServerStatefulSet)
+	if err != nil {
+		return err
+	}
+
+	expectedServerStatefulSet.Spec.Replicas = &replicas
+
 	currentServerStatefulSet := expectedServerStatefulSet.DeepCopy()
 	result, err := controllerutil.CreateOrUpdate(ctx, c.Client, currentServerStatefulSet, func() error {
 		if err := controllerutil.SetControllerReference(cluster, currentServerStatefulSet, c.Scheme); err != nil {
 			return err
 		}
 
+		volumeClaimTemplates := currentServerStatefulSet.Spec.VolumeClaimTemplates
 		currentServerStatefulSet.Spec = expectedServerStatefulSet.Spec
 
+		// the volumeClaimTemplates can't be updated, and are resized by creating the StatefulSet again
+		if !currentServerStatefulSet.CreationTimestamp.IsZero() {
+			currentServerStatefulSet.Spec.VolumeClaimTemplates = volumeClaimTemplates
+		}
+
 		return nil
 	})
 
@@ -654,9 +938,21 @@ func (c *ClusterReconciler) server(ctx context.Context, cluster *v1alpha1.Cluste
 	return err
 }
 
-func (c *ClusterReconciler) bindClusterRoles(ctx context.Context, cluster *v1alpha1.Cluster) error {
+// kubeletClusterRoles returns the ClusterRoles bound to the ServiceAccount of the k3k-kubelet
+func kubeletClusterRoles(cluster *v1alpha1.Cluster) []string {
 	clusterRoles := []string{"k3k-kubelet-node", "k3k-priorityclass"}
 
+	// the k3k-kubelet lists the dedicated host namespaces of the virtual namespaces
+	if cluster.Spec.NamespaceMapping == v1alpha1.PerNamespaceMapping {
+		clusterRoles = append(clusterRoles, "k3k-kubelet-namespace")
+	}
+
+	return clusterRoles
+}
+
+func (c *ClusterReconciler) bindClusterRoles(ctx context.Context, cluster *v1alpha1.Cluster) error {
+	clusterRoles := kubeletClusterRoles(cluster)
+
 	var err error
 
 	for _, clusterRole := range clusterRoles {
@@ -688,8 +984,17 @@ func (c *ClusterReconciler) ensureAgent(ctx context.Context, cluster *v1alpha1.C
 	config := agent.NewConfig(cluster, c.Client, c.Scheme)
 
 	var agentEnsurer agent.ResourceEnsurer
+
 	if cluster.Spec.Mode == agent.VirtualNodeMode {
-		agentEnsurer = agent.NewVirtualAgent(config, serviceIP, token, c.VirtualAgentImage, c.VirtualAgentImagePullPolicy, c.AgentImagePullSecrets)
+		virtualAgent := agent.NewVirtualAgent(config, serviceIP, token, c.VirtualAgentImage, c.VirtualAgentImagePullPolicy, c.AgentImagePullSecrets)
+
+		replicas, err := c.agentReplicas(ctx, cluster, serviceIP)
+		if err != nil {
+			return err
+		}
+
+		virtualAgent.Replicas = replicas
+		agentEnsurer = virtualAgent
 	} else {
 		// Assign port from pool if shared agent enabled mirroring of host nodes
 		kubeletPort := 10250
@@ -38,6 +38,10 @@ func (c *ClusterReconciler) finalizeCluster(ctx context.Context, cluster *v1alph
 		return reconcile.Result{}, err
 	}
 
+	if err := c.deleteHostNamespaces(ctx, cluster); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	// Deallocate ports for kubelet and webhook if used
 	if cluster.Spec.Mode == v1alpha1.SharedClusterMode && cluster.Spec.MirrorHostNodes {
 		log.Info("dellocating ports for kubelet and webhook")
@@ -51,6 +55,10 @@ func (c *ClusterReconciler) finalizeCluster(ctx context.Context, cluster *v1alph
 		}
 	}
 
+	if c.ActivityTracker != nil {
+		c.ActivityTracker.Forget(types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace})
+	}
+
 	// Remove finalizer from the cluster and update it only when all resources are cleaned up
 	if controllerutil.RemoveFinalizer(cluster, clusterFinalizerName) {
 		if err := c.Client.Update(ctx, cluster); err != nil {
@@ -62,7 +70,7 @@ func (c *ClusterReconciler) finalizeCluster(ctx context.Context, cluster *v1alph
 }
 
 func (c *ClusterReconciler) unbindClusterRoles(ctx context.Context, cluster *v1alpha1.Cluster) error {
-	clusterRoles := []string{"k3k-kubelet-node", "k3k-priorityclass"}
+	clusterRoles := kubeletClusterRoles(cluster)
 
 	var err error
 
@@ -72,13 +72,19 @@ var _ = BeforeSuite(func() {
 	ctx, cancel = context.WithCancel(context.Background())
 
 	clusterConfig := &cluster.Config{
-		SharedAgentImage:  "rancher/k3k-kubelet:latest",
-		K3SServerImage:    "rancher/k3s",
-		VirtualAgentImage: "rancher/k3s",
+		SharedAgentImage:    "rancher/k3k-kubelet:latest",
+		K3SServerImage:      "rancher/k3s",
+		VirtualAgentImage:   "rancher/k3s",
+		ControllerImage:     "rancher/k3k:latest",
+		ActivityURL:         "https://k3k-webhook.default.svc:8082",
+		ControllerNamespace: "default",
 	}
 	err = cluster.Add(ctx, mgr, clusterConfig, 50, portAllocator, &record.FakeRecorder{})
 	Expect(err).NotTo(HaveOccurred())
 
+	err = cluster.AddBackupController(ctx, mgr, clusterConfig, 50)
+	Expect(err).NotTo(HaveOccurred())
+
 	go func() {
 		defer GinkgoRecover()
 		err = mgr.Start(ctx)
@@ -0,0 +1,135 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster/server"
+)
+
+const datastoreDialTimeout = 5 * time.Second
+
+// datastorePorts are the default ports of the datastores supported by kine, by scheme of their endpoint
+var datastorePorts = map[string]string{
+	"postgres":   "5432",
+	"postgresql": "5432",
+	"mysql":      "3306",
+	"http":       "2379",
+	"https":      "2379",
+	"nats":       "4222",
+}
+
+// validateDatastore validates the persistence of a cluster with an external datastore
+func validateDatastore(cluster *v1alpha1.Cluster) error {
+	if !controller.ExternalDatastore(cluster) {
+		return nil
+	}
+
+	if cluster.Spec.RestoreFrom != nil {
+		return fmt.Errorf("%w: a cluster with an external datastore can't be restored from an etcd snapshot", ErrClusterValidation)
+	}
+
+	if cluster.Spec.Persistence.DatastoreSecretName == "" && cluster.Spec.Servers != nil && *cluster.Spec.Servers > 1 {
+		return fmt.Errorf("%w: a cluster storing its data in SQLite can only have one server", ErrClusterValidation)
+	}
+
+	return nil
+}
+
+// externalDatastore configures the servers with the Secret of the external datastore of the cluster.
+// The datastore must be reachable before the servers are created.
+func (c *ClusterReconciler) externalDatastore(ctx context.Context, cluster *v1alpha1.Cluster, s *server.Server) error {
+	if !controller.ExternalDatastore(cluster) || cluster.Spec.Persistence.DatastoreSecretName == "" {
+		return nil
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+
+	secretName := cluster.Spec.Persistence.DatastoreSecretName
+
+	var secret v1.Secret
+	if err := c.Client.Get(ctx, client.ObjectKey{Name: secretName, Namespace: cluster.Namespace}, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("%w: datastore secret %q not found", ErrClusterValidation, secretName)
+		}
+
+		return err
+	}
+
+	address, err := datastoreAddress(string(secret.Data[server.DatastoreEndpointKey]), cluster.Namespace)
+	if err != nil {
+		return fmt.Errorf("%w: invalid datastore endpoint in secret %q: %w", ErrClusterValidation, secretName, err)
+	}
+
+	s.ExternalDatastore(&secret)
+
+	// once the servers are created, an unavailable datastore is reported by their readiness
+	key := client.ObjectKey{Name: controller.SafeConcatNameWithPrefix(cluster.Name, "server"), Namespace: cluster.Namespace}
+	if err := c.Client.Get(ctx, key, &apps.StatefulSet{}); !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	log.Info("checking the connection to the datastore", "address", address)
+
+	dialer := net.Dialer{Timeout: datastoreDialTimeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("%w: datastore %s is not reachable: %w", ErrClusterValidation, address, err)
+	}
+
+	return conn.Close()
+}
+
+// datastoreAddress returns the address of the datastore of an endpoint, or of its first server for the etcd endpoints.
+// The hostnames of the Services of the namespace of the cluster are qualified with the namespace.
+func datastoreAddress(endpoint, namespace string) (string, error) {
+	scheme, address, found := strings.Cut(endpoint, "://")
+	if !found {
+		return "", errors.New("missing scheme")
+	}
+
+	port, supported := datastorePorts[scheme]
+	if !supported {
+		return "", fmt.Errorf("unsupported scheme %q", scheme)
+	}
+
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		address = address[i+1:]
+	}
+
+	address, _, _ = strings.Cut(address, "/")
+	address, _, _ = strings.Cut(address, ",")
+	address, _, _ = strings.Cut(address, "?")
+
+	// the MySQL endpoints have the address in the "tcp(host:port)" format
+	address = strings.TrimSuffix(strings.TrimPrefix(address, "tcp("), ")")
+
+	host, hostPort, err := net.SplitHostPort(address)
+	if err != nil {
+		host, hostPort = strings.Trim(address, "[]"), port
+	}
+
+	if host == "" {
+		return "", errors.New("missing host")
+	}
+
+	if !strings.Contains(host, ".") && !strings.Contains(host, ":") && host != "localhost" {
+		host = host + "." + namespace
+	}
+
+	return net.JoinHostPort(host, hostPort), nil
+}
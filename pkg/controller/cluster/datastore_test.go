@@ -0,0 +1,217 @@
+package cluster_test
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	k3kcontroller "github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster/server"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster Controller", Label("controller"), Label("Cluster"), Label("Datastore"), func() {
+	Context("creating a Cluster with an external datastore", func() {
+		var (
+			namespace string
+			ctx       context.Context
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			createdNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "ns-"}}
+			err := k8sClient.Create(context.Background(), createdNS)
+			Expect(err).To(Not(HaveOccurred()))
+
+			namespace = createdNS.Name
+		})
+
+		externalCluster := func(datastoreSecretName string, servers int32) *v1alpha1.Cluster {
+			return &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					Servers: ptr.To(servers),
+					Persistence: v1alpha1.PersistenceConfig{
+						Type:                v1alpha1.ExternalPersistenceMode,
+						DatastoreSecretName: datastoreSecretName,
+					},
+				},
+			}
+		}
+
+		createDatastoreSecret := func(data map[string][]byte) string {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "datastore-",
+					Namespace:    namespace,
+				},
+				Data: data,
+			}
+
+			err := k8sClient.Create(ctx, secret)
+			Expect(err).To(Not(HaveOccurred()))
+
+			return secret.Name
+		}
+
+		clusterPhase := func(virtualCluster *v1alpha1.Cluster) func() v1alpha1.ClusterPhase {
+			return func() v1alpha1.ClusterPhase {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return virtualCluster.Status.Phase
+			}
+		}
+
+		serverStatefulSet := func(virtualCluster *v1alpha1.Cluster) *appsv1.StatefulSet {
+			var statefulSet appsv1.StatefulSet
+
+			key := client.ObjectKey{
+				Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, "server"),
+				Namespace: namespace,
+			}
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, key, &statefulSet)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			return &statefulSet
+		}
+
+		serverConfig := func(virtualCluster *v1alpha1.Cluster) string {
+			var secret corev1.Secret
+
+			key := client.ObjectKey{
+				Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, "init-server-config"),
+				Namespace: namespace,
+			}
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, key, &secret)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			return string(secret.Data["config.yaml"])
+		}
+
+		It("will store the data in SQLite on the PVC of a single server", func() {
+			virtualCluster := externalCluster("", 1)
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			statefulSet := serverStatefulSet(virtualCluster)
+			Expect(statefulSet.Spec.VolumeClaimTemplates).To(HaveLen(1))
+
+			command := statefulSet.Spec.Template.Spec.Containers[0].Command
+			Expect(command[len(command)-1]).To(Not(ContainSubstring("--cluster-reset")))
+
+			config := serverConfig(virtualCluster)
+			Expect(config).To(Not(ContainSubstring("cluster-init")))
+			Expect(config).To(Not(ContainSubstring("datastore-endpoint")))
+		})
+
+		It("will not create a SQLite cluster with several servers", func() {
+			virtualCluster := externalCluster("", 3)
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(clusterPhase(virtualCluster)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(v1alpha1.ClusterPending))
+		})
+
+		It("will not create the servers without the datastore secret", func() {
+			virtualCluster := externalCluster("missing-datastore", 1)
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(clusterPhase(virtualCluster)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(v1alpha1.ClusterPending))
+		})
+
+		It("will not create the servers if the datastore is not reachable", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).To(Not(HaveOccurred()))
+
+			address := listener.Addr().String()
+			Expect(listener.Close()).To(Succeed())
+
+			secretName := createDatastoreSecret(map[string][]byte{
+				server.DatastoreEndpointKey: []byte("postgres://k3s:password@" + address + "/k3s"),
+			})
+
+			virtualCluster := externalCluster(secretName, 1)
+
+			err = k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(clusterPhase(virtualCluster)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(v1alpha1.ClusterPending))
+		})
+
+		It("will configure the servers with the endpoint and the certificates of the datastore", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).To(Not(HaveOccurred()))
+
+			DeferCleanup(listener.Close)
+
+			endpoint := "postgres://k3s:password@" + listener.Addr().String() + "/k3s"
+
+			secretName := createDatastoreSecret(map[string][]byte{
+				server.DatastoreEndpointKey: []byte(endpoint),
+				"ca.crt":                    []byte("ca"),
+			})
+
+			virtualCluster := externalCluster(secretName, 2)
+
+			err = k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			statefulSet := serverStatefulSet(virtualCluster)
+			Expect(statefulSet.Spec.VolumeClaimTemplates).To(BeEmpty())
+
+			var secretNames []string
+
+			for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+				if volume.Secret != nil {
+					secretNames = append(secretNames, volume.Secret.SecretName)
+				}
+			}
+
+			Expect(secretNames).To(ContainElement(secretName))
+
+			config := serverConfig(virtualCluster)
+			Expect(config).To(ContainSubstring(`datastore-endpoint: "` + endpoint + `"`))
+			Expect(config).To(ContainSubstring("datastore-cafile: " + server.DatastoreDir + "/ca.crt"))
+			Expect(config).To(Not(ContainSubstring("datastore-certfile")))
+			Expect(config).To(Not(ContainSubstring("cluster-init")))
+		})
+	})
+})
@@ -0,0 +1,238 @@
+package cluster
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/certs"
+	"github.com/rancher/k3k/pkg/controller/cluster/agent"
+	"github.com/rancher/k3k/pkg/controller/cluster/server"
+	"github.com/rancher/k3k/pkg/controller/cluster/server/bootstrap"
+)
+
+const readyzTimeout = 5 * time.Second
+
+// updateHealth sets the conditions of the servers, the agents, the datastore and the sync of the cluster, from the
+// status of their workloads and from the /readyz endpoint of the apiserver of the cluster.
+// The health is unknown on errors, and the conditions keep their previous status.
+func (c *ClusterReconciler) updateHealth(ctx context.Context, cluster *v1alpha1.Cluster) {
+	if !cluster.DeletionTimestamp.IsZero() {
+		return
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if controller.Suspended(cluster) {
+		cluster.Status.ReadyServers = 0
+		cluster.Status.ReadyAgents = 0
+
+		for _, conditionType := range []string{ConditionDatastoreReady, ConditionServersReady, ConditionAgentsReady} {
+			setComponentCondition(cluster, conditionType, false, "cluster is suspended")
+		}
+
+		if cluster.Spec.Mode != v1alpha1.VirtualClusterMode {
+			setComponentCondition(cluster, ConditionSyncHealthy, false, "cluster is suspended")
+		}
+
+		return
+	}
+
+	if err := c.agentsHealth(ctx, cluster); err != nil {
+		log.Error(err, "failed to check the health of the agents")
+	}
+
+	if err := c.serversHealth(ctx, cluster); err != nil {
+		log.Error(err, "failed to check the health of the servers")
+	}
+}
+
+// serversHealth checks the readiness of the pods of the servers, and of the apiserver and the datastore of the cluster
+func (c *ClusterReconciler) serversHealth(ctx context.Context, cluster *v1alpha1.Cluster) error {
+	var statefulSet apps.StatefulSet
+
+	key := client.ObjectKey{Name: controller.SafeConcatNameWithPrefix(cluster.Name, "server"), Namespace: cluster.Namespace}
+	if err := c.Client.Get(ctx, key, &statefulSet); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	desired := ptr.Deref(cluster.Spec.Servers, 1)
+	cluster.Status.ReadyServers = statefulSet.Status.ReadyReplicas
+	message := fmt.Sprintf("%d/%d servers ready", cluster.Status.ReadyServers, desired)
+
+	if cluster.Status.ReadyServers == 0 {
+		setComponentCondition(cluster, ConditionServersReady, false, message)
+		setComponentCondition(cluster, ConditionDatastoreReady, false, "waiting for the servers")
+
+		if cluster.Spec.Mode != v1alpha1.VirtualClusterMode {
+			setComponentCondition(cluster, ConditionSyncHealthy, false, "waiting for the servers")
+		}
+
+		return nil
+	}
+
+	var service v1.Service
+	if err := c.Client.Get(ctx, client.ObjectKey{Name: server.ServiceName(cluster.Name), Namespace: cluster.Namespace}, &service); err != nil {
+		return err
+	}
+
+	clientset, err := c.virtualClientset(ctx, cluster, net.JoinHostPort(service.Spec.ClusterIP, "443"))
+	if err != nil {
+		return err
+	}
+
+	if err := readyz(ctx, clientset, "/readyz"); err != nil {
+		setComponentCondition(cluster, ConditionServersReady, false, message+", apiserver not ready: "+err.Error())
+	} else {
+		setComponentCondition(cluster, ConditionServersReady, cluster.Status.ReadyServers >= desired, message)
+	}
+
+	// the embedded etcd, or the etcd API of kine for the external datastores
+	if err := readyz(ctx, clientset, "/readyz/etcd"); err != nil {
+		setComponentCondition(cluster, ConditionDatastoreReady, false, "datastore not ready: "+err.Error())
+	} else {
+		setComponentCondition(cluster, ConditionDatastoreReady, true, "datastore ready")
+	}
+
+	if cluster.Spec.Mode == v1alpha1.VirtualClusterMode {
+		return nil
+	}
+
+	return syncHealth(ctx, cluster, clientset)
+}
+
+// syncHealth checks the readiness of the virtual nodes of the k3k-kubelet in shared mode, which are not ready when the
+// k3k-kubelet stops syncing their status
+func syncHealth(ctx context.Context, cluster *v1alpha1.Cluster, clientset kubernetes.Interface) error {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		setComponentCondition(cluster, ConditionSyncHealthy, false, "failed to list the virtual nodes: "+err.Error())
+		return nil
+	}
+
+	ready := 0
+
+	for _, node := range nodes.Items {
+		if nodeReady(&node) {
+			ready++
+		}
+	}
+
+	if len(nodes.Items) == 0 {
+		setComponentCondition(cluster, ConditionSyncHealthy, false, "no virtual node registered by the k3k-kubelet")
+		return nil
+	}
+
+	setComponentCondition(cluster, ConditionSyncHealthy, ready == len(nodes.Items), fmt.Sprintf("%d/%d virtual nodes ready", ready, len(nodes.Items)))
+
+	return nil
+}
+
+// agentsHealth checks the readiness of the K3s agents in virtual mode, or of the k3k-kubelet pods in shared mode
+func (c *ClusterReconciler) agentsHealth(ctx context.Context, cluster *v1alpha1.Cluster) error {
+	if cluster.Spec.Mode == v1alpha1.VirtualClusterMode {
+		var deployment apps.Deployment
+
+		key := client.ObjectKey{Name: agent.VirtualAgentName(cluster.Name), Namespace: cluster.Namespace}
+		if err := c.Client.Get(ctx, key, &deployment); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+
+		desired := ptr.Deref(cluster.Spec.Agents, 0)
+		cluster.Status.AgentSelector = labels.SelectorFromSet(agent.VirtualAgentLabels(cluster.Name)).String()
+		cluster.Status.ReadyAgents = deployment.Status.ReadyReplicas
+
+		setComponentCondition(cluster, ConditionAgentsReady, cluster.Status.ReadyAgents >= desired, fmt.Sprintf("%d/%d agents ready", cluster.Status.ReadyAgents, desired))
+
+		return nil
+	}
+
+	var daemonSet apps.DaemonSet
+
+	key := client.ObjectKey{Name: controller.SafeConcatNameWithPrefix(cluster.Name, agent.SharedNodeAgentName), Namespace: cluster.Namespace}
+	if err := c.Client.Get(ctx, key, &daemonSet); err != nil {
+		if apierrors.IsNotFound(err) {
+			cluster.Status.ReadyAgents = 0
+			setComponentCondition(cluster, ConditionAgentsReady, false, "k3k-kubelet not created")
+
+			return nil
+		}
+
+		return err
+	}
+
+	cluster.Status.ReadyAgents = daemonSet.Status.NumberReady
+	desired := daemonSet.Status.DesiredNumberScheduled
+
+	setComponentCondition(cluster, ConditionAgentsReady, desired > 0 && cluster.Status.ReadyAgents >= desired, fmt.Sprintf("%d/%d k3k-kubelet pods ready", cluster.Status.ReadyAgents, desired))
+
+	return nil
+}
+
+// virtualClientset returns a clientset of the apiserver of the cluster at the address, authenticated with an admin
+// certificate signed with the client CA of the cluster
+func (c *ClusterReconciler) virtualClientset(ctx context.Context, cluster *v1alpha1.Cluster, address string) (*kubernetes.Clientset, error) {
+	bootstrapData, err := bootstrap.GetFromSecret(ctx, c.Client, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	adminCert, adminKey, err := certs.CreateClientCertKey(
+		controller.AdminCommonName,
+		[]string{user.SystemPrivilegedGroup},
+		nil,
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		time.Hour,
+		bootstrapData.ClientCA.Content,
+		bootstrapData.ClientCAKey.Content,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig := &rest.Config{
+		Host:    "https://" + address,
+		Timeout: readyzTimeout,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData:   []byte(bootstrapData.ServerCA.Content),
+			CertData: adminCert,
+			KeyData:  adminKey,
+			// the service name is a SAN of the certificates of all the servers
+			ServerName: server.ServiceName(cluster.Name),
+		},
+	}
+
+	return kubernetes.NewForConfig(restConfig)
+}
+
+func readyz(ctx context.Context, clientset kubernetes.Interface, path string) error {
+	_, err := clientset.Discovery().RESTClient().Get().AbsPath(path).DoRaw(ctx)
+	return err
+}
+
+func nodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+
+	return false
+}
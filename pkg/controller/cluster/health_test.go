@@ -0,0 +1,132 @@
+package cluster_test
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/controller/cluster"
+	"github.com/rancher/k3k/pkg/controller/cluster/agent"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster Controller", Label("controller"), Label("Cluster"), Label("Health"), func() {
+	Context("tracking the health of a Cluster", func() {
+		var (
+			namespace string
+			ctx       context.Context
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			createdNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "ns-"}}
+			err := k8sClient.Create(context.Background(), createdNS)
+			Expect(err).To(Not(HaveOccurred()))
+
+			namespace = createdNS.Name
+		})
+
+		condition := func(virtualCluster *v1alpha1.Cluster, conditionType string) func() *metav1.Condition {
+			return func() *metav1.Condition {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return meta.FindStatusCondition(virtualCluster.Status.Conditions, conditionType)
+			}
+		}
+
+		It("will not be ready until its servers are ready", func() {
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(condition(virtualCluster, cluster.ConditionServersReady)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(And(
+					Not(BeNil()),
+					HaveField("Status", metav1.ConditionFalse),
+					HaveField("Message", "0/1 servers ready"),
+				))
+
+			Expect(virtualCluster.Status.Phase).To(Not(Equal(v1alpha1.ClusterReady)))
+			Expect(virtualCluster.Status.ReadyServers).To(BeZero())
+			Expect(meta.IsStatusConditionFalse(virtualCluster.Status.Conditions, cluster.ConditionDatastoreReady)).To(BeTrue())
+
+			policyCondition := meta.FindStatusCondition(virtualCluster.Status.Conditions, cluster.ConditionPolicyCompliant)
+			Expect(policyCondition).To(Not(BeNil()))
+			Expect(policyCondition.Reason).To(Equal(cluster.ReasonNoPolicy))
+		})
+
+		It("will count the ready agents in virtual mode", func() {
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					Mode:   v1alpha1.VirtualClusterMode,
+					Agents: ptr.To[int32](2),
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			// the agents are created once the servers are ready, and their status is set by the test
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agent.VirtualAgentName(virtualCluster.Name),
+					Namespace: namespace,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "agent"}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "agent"}},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "agent", Image: "rancher/k3s"}},
+						},
+					},
+				},
+			}
+
+			err = k8sClient.Create(ctx, deployment)
+			Expect(err).To(Not(HaveOccurred()))
+
+			deployment.Status.Replicas = 2
+			deployment.Status.ReadyReplicas = 1
+
+			err = k8sClient.Status().Update(ctx, deployment)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(condition(virtualCluster, cluster.ConditionAgentsReady)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(And(
+					Not(BeNil()),
+					HaveField("Status", metav1.ConditionFalse),
+					HaveField("Message", "1/2 agents ready"),
+				))
+
+			Expect(virtualCluster.Status.ReadyAgents).To(Equal(int32(1)))
+			Expect(meta.FindStatusCondition(virtualCluster.Status.Conditions, cluster.ConditionSyncHealthy)).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,158 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rancher/k3k/k3k-kubelet/translate"
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/controller/cluster/agent"
+	"github.com/rancher/k3k/pkg/controller/policy"
+)
+
+// hostNamespaces returns the dedicated host namespaces of the virtual namespaces, created by the controller
+// with the "PerNamespace" namespace mapping. The namespaces with the labels of the cluster not owned by it are skipped.
+func (c *ClusterReconciler) hostNamespaces(ctx context.Context, cluster *v1alpha1.Cluster) ([]v1.Namespace, error) {
+	if cluster.Spec.NamespaceMapping != v1alpha1.PerNamespaceMapping {
+		return nil, nil
+	}
+
+	var namespaces v1.NamespaceList
+	if err := c.Client.List(ctx, &namespaces, agent.HostNamespacesLabels(cluster)); err != nil {
+		return nil, err
+	}
+
+	var hostNamespaces []v1.Namespace
+
+	for _, namespace := range namespaces.Items {
+		if agent.OwnsHostNamespace(cluster, &namespace) {
+			hostNamespaces = append(hostNamespaces, namespace)
+		}
+	}
+
+	return hostNamespaces, nil
+}
+
+// createHostNamespaces creates the dedicated host namespaces of the virtual namespaces, with the "PerNamespace"
+// namespace mapping. The namespaces are created with the VirtualClusterPolicy of the cluster, so that its quotas,
+// limits and pod security levels apply before the k3k-kubelet is bound to them. The host namespaces are not deleted
+// with the virtual namespaces, but with the cluster.
+func (c *ClusterReconciler) createHostNamespaces(ctx context.Context, cluster *v1alpha1.Cluster, serviceIP string, hostNamespaces []v1.Namespace) error {
+	if cluster.Spec.NamespaceMapping != v1alpha1.PerNamespaceMapping {
+		return nil
+	}
+
+	clientset, err := c.virtualClientset(ctx, cluster, net.JoinHostPort(serviceIP, "443"))
+	if err != nil {
+		return err
+	}
+
+	virtualNamespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	translator := translate.ToHostTranslator{
+		ClusterName:      cluster.Name,
+		ClusterNamespace: cluster.Namespace,
+		NamespaceMapping: cluster.Spec.NamespaceMapping,
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+
+	for _, virtualNamespace := range virtualNamespaces.Items {
+		name := translator.HostNamespace(virtualNamespace.Name)
+
+		if !virtualNamespace.DeletionTimestamp.IsZero() || name == cluster.Namespace ||
+			slices.ContainsFunc(hostNamespaces, func(namespace v1.Namespace) bool { return namespace.Name == name }) {
+			continue
+		}
+
+		namespace := &v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: agent.HostNamespacesLabels(cluster),
+				Annotations: map[string]string{
+					agent.HostNamespaceOwnerAnnotation: string(cluster.UID),
+				},
+			},
+		}
+
+		namespace.Labels[translate.ResourceNamespaceLabel] = virtualNamespace.Name
+
+		if cluster.Status.PolicyName != "" {
+			namespace.Labels[policy.PolicyNameLabelKey] = cluster.Status.PolicyName
+		}
+
+		log.Info("creating host namespace", "namespace", name, "virtualNamespace", virtualNamespace.Name)
+
+		createErr := c.Client.Create(ctx, namespace)
+		if apierrors.IsAlreadyExists(createErr) {
+			// the namespace could belong to another cluster, or be a namespace not managed by k3k
+			createErr = fmt.Errorf("host namespace %s of virtual namespace %s is not owned by the cluster", name, virtualNamespace.Name)
+		}
+
+		err = errors.Join(err, createErr)
+	}
+
+	return err
+}
+
+// ensureHostNamespaces binds the dedicated host namespaces to the VirtualClusterPolicy of the namespace of the Cluster,
+// so that the same quotas, limits, network policies and pod security levels are applied to them
+func (c *ClusterReconciler) ensureHostNamespaces(ctx context.Context, cluster *v1alpha1.Cluster, hostNamespaces []v1.Namespace) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	var err error
+
+	for _, namespace := range hostNamespaces {
+		if !namespace.DeletionTimestamp.IsZero() || namespace.Labels[policy.PolicyNameLabelKey] == cluster.Status.PolicyName {
+			continue
+		}
+
+		if cluster.Status.PolicyName == "" {
+			delete(namespace.Labels, policy.PolicyNameLabelKey)
+		} else {
+			namespace.Labels[policy.PolicyNameLabelKey] = cluster.Status.PolicyName
+		}
+
+		log.Info("updating policy of host namespace", "namespace", namespace.Name, "policy", cluster.Status.PolicyName)
+
+		err = errors.Join(err, c.Client.Update(ctx, &namespace))
+	}
+
+	return err
+}
+
+// deleteHostNamespaces deletes the dedicated host namespaces of the virtual namespaces, with their resources.
+// The namespaces are deleted one by one, since they don't support the deletion of a collection.
+func (c *ClusterReconciler) deleteHostNamespaces(ctx context.Context, cluster *v1alpha1.Cluster) error {
+	hostNamespaces, err := c.hostNamespaces(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+
+	for _, namespace := range hostNamespaces {
+		if !namespace.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		log.Info("deleting host namespace", "namespace", namespace.Name)
+
+		err = errors.Join(err, client.IgnoreNotFound(c.Client.Delete(ctx, &namespace)))
+	}
+
+	return err
+}
@@ -116,6 +116,15 @@ func (p *PodReconciler) handleServerPod(ctx context.Context, cluster v1alpha1.Cl
 		return nil
 	}
 
+	// the servers of a cluster with an external datastore are not etcd members
+	if k3kcontroller.ExternalDatastore(&cluster) {
+		if controllerutil.RemoveFinalizer(pod, etcdPodFinalizerName) {
+			return p.Client.Update(ctx, pod)
+		}
+
+		return nil
+	}
+
 	// if etcd pod is marked for deletion then we need to remove it from the etcd member list before deletion
 	if !pod.DeletionTimestamp.IsZero() {
 		// check if cluster is deleted then remove the finalizer from the pod
@@ -131,23 +140,8 @@ func (p *PodReconciler) handleServerPod(ctx context.Context, cluster v1alpha1.Cl
 			return nil
 		}
 
-		tlsConfig, err := p.getETCDTLS(ctx, &cluster)
-		if err != nil {
-			return err
-		}
-
 		// remove server from etcd
-		client, err := clientv3.New(clientv3.Config{
-			Endpoints: []string{
-				fmt.Sprintf("https://%s.%s:2379", server.ServiceName(cluster.Name), pod.Namespace),
-			},
-			TLS: tlsConfig,
-		})
-		if err != nil {
-			return err
-		}
-
-		if err := removePeer(ctx, client, pod.Name, pod.Status.PodIP); err != nil {
+		if err := removeServerPeer(ctx, p.Client, &cluster, pod); err != nil {
 			return err
 		}
 
@@ -166,11 +160,31 @@ func (p *PodReconciler) handleServerPod(ctx context.Context, cluster v1alpha1.Cl
 	return nil
 }
 
-func (p *PodReconciler) getETCDTLS(ctx context.Context, cluster *v1alpha1.Cluster) (*tls.Config, error) {
+// removeServerPeer removes the server pod from the etcd members of the cluster
+func removeServerPeer(ctx context.Context, c ctrlruntimeclient.Client, cluster *v1alpha1.Cluster, pod *v1.Pod) error {
+	tlsConfig, err := getETCDTLS(ctx, c, cluster)
+	if err != nil {
+		return err
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints: []string{server.EtcdEndpoint(cluster)},
+		TLS:       tlsConfig,
+	})
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	return removePeer(ctx, client, pod.Name, pod.Status.PodIP)
+}
+
+func getETCDTLS(ctx context.Context, c ctrlruntimeclient.Client, cluster *v1alpha1.Cluster) (*tls.Config, error) {
 	log := ctrl.LoggerFrom(ctx)
 	log.Info("generating etcd TLS client certificate", "cluster", cluster)
 
-	token, err := p.clusterToken(ctx, cluster)
+	token, err := clusterToken(ctx, c, cluster)
 	if err != nil {
 		return nil, err
 	}
@@ -189,7 +203,7 @@ func (p *PodReconciler) getETCDTLS(ctx context.Context, cluster *v1alpha1.Cluste
 		return nil, err
 	}
 
-	etcdCert, etcdKey, err := certs.CreateClientCertKey("etcd-client", nil, nil, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, 0, b.ETCDServerCA.Content, b.ETCDServerCAKey.Content)
+	etcdCert, etcdKey, err := etcdClientCertKey(b)
 	if err != nil {
 		return nil, err
 	}
@@ -213,6 +227,11 @@ func (p *PodReconciler) getETCDTLS(ctx context.Context, cluster *v1alpha1.Cluste
 	}, nil
 }
 
+// etcdClientCertKey returns a client certificate and key of the etcd of the cluster, signed by its etcd server CA
+func etcdClientCertKey(b *bootstrap.ControlRuntimeBootstrap) ([]byte, []byte, error) {
+	return certs.CreateClientCertKey("etcd-client", nil, nil, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, 0, b.ETCDServerCA.Content, b.ETCDServerCAKey.Content)
+}
+
 // removePeer removes a peer from the cluster. The peer name and IP address must both match.
 func removePeer(ctx context.Context, client *clientv3.Client, name, address string) error {
 	log := ctrl.LoggerFrom(ctx)
@@ -253,7 +272,7 @@ func removePeer(ctx context.Context, client *clientv3.Client, name, address stri
 	return nil
 }
 
-func (p *PodReconciler) clusterToken(ctx context.Context, cluster *v1alpha1.Cluster) (string, error) {
+func clusterToken(ctx context.Context, c ctrlruntimeclient.Client, cluster *v1alpha1.Cluster) (string, error) {
 	var tokenSecret v1.Secret
 
 	nn := types.NamespacedName{
@@ -265,7 +284,7 @@ func (p *PodReconciler) clusterToken(ctx context.Context, cluster *v1alpha1.Clus
 		nn.Name = TokenSecretName(cluster.Name)
 	}
 
-	if err := p.Client.Get(ctx, nn, &tokenSecret); err != nil {
+	if err := c.Get(ctx, nn, &tokenSecret); err != nil {
 		return "", err
 	}
 
@@ -0,0 +1,224 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster/server"
+)
+
+const (
+	// ConditionResizing is the condition of a cluster with the volumes of its servers resized to a new storage size
+	ConditionResizing = "Resizing"
+
+	ReasonResizingVolumes = "ResizingVolumes"
+	ReasonResized         = "Resized"
+	ReasonResizeRejected  = "ResizeRejected"
+)
+
+// ErrRecreatingServers is returned while the StatefulSet of the servers is recreated with the new size of their
+// volumes. The progress is reported by the Resizing condition, and the cluster is reconciled again.
+var ErrRecreatingServers = errors.New("recreating the server statefulset")
+
+// resizing returns true if the volumes of the servers of the cluster are being resized
+func resizing(cluster *v1alpha1.Cluster) bool {
+	return resizeReason(cluster) == ReasonResizingVolumes
+}
+
+// resizeReason returns the reason of the Resizing condition of the cluster
+func resizeReason(cluster *v1alpha1.Cluster) string {
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionResizing)
+	if condition == nil {
+		return ""
+	}
+
+	return condition.Reason
+}
+
+func setResizing(cluster *v1alpha1.Cluster, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		Type:    ConditionResizing,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// resizeVolumes expands the PersistentVolumeClaims of the servers when the storage size of the cluster is increased.
+// The volumeClaimTemplates of the StatefulSet of the servers can't be updated: the StatefulSet is deleted leaving its
+// pods running, and created again with the new size. A rejected size is not applied, and the servers keep their
+// current volumes.
+func (c *ClusterReconciler) resizeVolumes(ctx context.Context, cluster *v1alpha1.Cluster) error {
+	if cluster.Spec.Persistence.Type == v1alpha1.StaticPersistenceMode || cluster.Spec.Persistence.StorageRequestSize == "" {
+		return nil
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+
+	var statefulSet apps.StatefulSet
+
+	key := client.ObjectKey{Name: controller.SafeConcatNameWithPrefix(cluster.Name, "server"), Namespace: cluster.Namespace}
+	if err := c.Client.Get(ctx, key, &statefulSet); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	// the StatefulSet is created again once deleted
+	if !statefulSet.DeletionTimestamp.IsZero() {
+		setResizing(cluster, metav1.ConditionTrue, ReasonResizingVolumes, "waiting for the deletion of the server statefulset to resize its volumes")
+		return ErrRecreatingServers
+	}
+
+	if len(statefulSet.Spec.VolumeClaimTemplates) == 0 {
+		return nil
+	}
+
+	claims, err := c.serverClaims(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	current := statefulSet.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[v1.ResourceStorage]
+
+	requested, err := resource.ParseQuantity(cluster.Spec.Persistence.StorageRequestSize)
+	if err == nil && requested.Cmp(current) == 0 {
+		c.resizeStatus(cluster, claims, requested)
+		return nil
+	}
+
+	if err == nil {
+		err = c.validateResize(ctx, claims, current, requested)
+	}
+
+	if err != nil {
+		if resizeReason(cluster) != ReasonResizeRejected {
+			c.Eventf(cluster, v1.EventTypeWarning, ReasonResizeRejected, err.Error())
+		}
+
+		setResizing(cluster, metav1.ConditionFalse, ReasonResizeRejected, err.Error())
+
+		return nil
+	}
+
+	log.Info("resizing server volumes", "from", current.String(), "to", requested.String())
+
+	for i := range claims {
+		claim := &claims[i]
+
+		if claim.Spec.Resources.Requests.Storage().Cmp(requested) >= 0 {
+			continue
+		}
+
+		patch := client.MergeFrom(claim.DeepCopy())
+
+		if claim.Spec.Resources.Requests == nil {
+			claim.Spec.Resources.Requests = v1.ResourceList{}
+		}
+
+		claim.Spec.Resources.Requests[v1.ResourceStorage] = requested
+
+		if err := c.Client.Patch(ctx, claim, patch); err != nil {
+			return err
+		}
+	}
+
+	c.Eventf(cluster, v1.EventTypeNormal, ReasonResizingVolumes, "resizing server volumes from %s to %s", current.String(), requested.String())
+	setResizing(cluster, metav1.ConditionTrue, ReasonResizingVolumes, "recreating the server statefulset to resize its volumes to "+requested.String())
+
+	if err := c.Client.Delete(ctx, &statefulSet, client.PropagationPolicy(metav1.DeletePropagationOrphan)); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	return ErrRecreatingServers
+}
+
+// validateResize returns an error if the volumes of the servers can't be resized: the volumes can't be shrunk, and
+// their StorageClass must allow volume expansion.
+func (c *ClusterReconciler) validateResize(ctx context.Context, claims []v1.PersistentVolumeClaim, current, requested resource.Quantity) error {
+	if requested.Cmp(current) < 0 {
+		return fmt.Errorf("server volumes can't be shrunk from %s to %s", current.String(), requested.String())
+	}
+
+	for _, claim := range claims {
+		storageClassName := ptr.Deref(claim.Spec.StorageClassName, "")
+		if storageClassName == "" {
+			return fmt.Errorf("volume %s has no StorageClass allowing volume expansion", claim.Name)
+		}
+
+		var storageClass storagev1.StorageClass
+		if err := c.Client.Get(ctx, client.ObjectKey{Name: storageClassName}, &storageClass); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Errorf("StorageClass %q of volume %s not found", storageClassName, claim.Name)
+			}
+
+			return err
+		}
+
+		if !ptr.Deref(storageClass.AllowVolumeExpansion, false) {
+			return fmt.Errorf("StorageClass %q of volume %s does not allow volume expansion", storageClassName, claim.Name)
+		}
+	}
+
+	return nil
+}
+
+// resizeStatus updates the progress of the resize of the volumes, resized once their capacity reaches the requested
+// size. A rejected size was reverted.
+func (c *ClusterReconciler) resizeStatus(cluster *v1alpha1.Cluster, claims []v1.PersistentVolumeClaim, requested resource.Quantity) {
+	switch resizeReason(cluster) {
+	case ReasonResizeRejected:
+		setResizing(cluster, metav1.ConditionFalse, ReasonResized, "server volumes of "+requested.String())
+	case ReasonResizingVolumes:
+		resized := 0
+
+		for _, claim := range claims {
+			if claim.Status.Capacity.Storage().Cmp(requested) >= 0 {
+				resized++
+			}
+		}
+
+		if resized < len(claims) {
+			setResizing(cluster, metav1.ConditionTrue, ReasonResizingVolumes, fmt.Sprintf("resized %d of %d server volumes to %s", resized, len(claims), requested.String()))
+			return
+		}
+
+		c.Eventf(cluster, v1.EventTypeNormal, ReasonResized, "server volumes resized to %s", requested.String())
+		setResizing(cluster, metav1.ConditionFalse, ReasonResized, "server volumes resized to "+requested.String())
+	}
+}
+
+// serverClaims returns the PersistentVolumeClaims of the servers of the cluster
+func (c *ClusterReconciler) serverClaims(ctx context.Context, cluster *v1alpha1.Cluster) ([]v1.PersistentVolumeClaim, error) {
+	var claims []v1.PersistentVolumeClaim
+
+	for ordinal := range int(*cluster.Spec.Servers) {
+		var claim v1.PersistentVolumeClaim
+
+		key := client.ObjectKey{Name: server.PVCName(cluster.Name, ordinal), Namespace: cluster.Namespace}
+		if err := c.Client.Get(ctx, key, &claim); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		claims = append(claims, claim)
+	}
+
+	return claims, nil
+}
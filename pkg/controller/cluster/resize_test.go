@@ -0,0 +1,170 @@
+package cluster_test
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	k3kcontroller "github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster"
+	"github.com/rancher/k3k/pkg/controller/cluster/server"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster Controller", Label("controller"), Label("Cluster"), Label("Resize"), func() {
+	Context("increasing the storage size of a Cluster", func() {
+		var (
+			namespace string
+			ctx       context.Context
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			createdNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "ns-"}}
+			err := k8sClient.Create(context.Background(), createdNS)
+			Expect(err).To(Not(HaveOccurred()))
+
+			namespace = createdNS.Name
+		})
+
+		// createResizableCluster creates a cluster with a bound claim for its server, as the claims are not created
+		// by the StatefulSets in the test environment
+		createResizableCluster := func(allowVolumeExpansion bool) (*v1alpha1.Cluster, *appsv1.StatefulSet) {
+			storageClass := &storagev1.StorageClass{
+				ObjectMeta:           metav1.ObjectMeta{GenerateName: "storage-"},
+				Provisioner:          "k3k.io/test",
+				AllowVolumeExpansion: ptr.To(allowVolumeExpansion),
+			}
+
+			err := k8sClient.Create(ctx, storageClass)
+			Expect(err).To(Not(HaveOccurred()))
+
+			DeferCleanup(k8sClient.Delete, context.Background(), storageClass)
+
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					Persistence: v1alpha1.PersistenceConfig{
+						Type:               v1alpha1.DynamicPersistenceMode,
+						StorageClassName:   ptr.To(storageClass.Name),
+						StorageRequestSize: "1Gi",
+					},
+				},
+			}
+
+			err = k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			var statefulSet appsv1.StatefulSet
+
+			key := client.ObjectKey{
+				Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, "server"),
+				Namespace: namespace,
+			}
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, key, &statefulSet)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			claim := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      server.PVCName(virtualCluster.Name, 0),
+					Namespace: namespace,
+				},
+				Spec: statefulSet.Spec.VolumeClaimTemplates[0].Spec,
+			}
+
+			err = k8sClient.Create(ctx, claim)
+			Expect(err).To(Not(HaveOccurred()))
+
+			claim.Status.Phase = corev1.ClaimBound
+			claim.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")}
+
+			err = k8sClient.Status().Update(ctx, claim)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster); err != nil {
+					return err
+				}
+
+				virtualCluster.Spec.Persistence.StorageRequestSize = "2Gi"
+
+				return k8sClient.Update(ctx, virtualCluster)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			return virtualCluster, &statefulSet
+		}
+
+		resizeReason := func(virtualCluster *v1alpha1.Cluster) func() string {
+			return func() string {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				condition := meta.FindStatusCondition(virtualCluster.Status.Conditions, cluster.ConditionResizing)
+				if condition == nil {
+					return ""
+				}
+
+				return condition.Reason
+			}
+		}
+
+		It("will expand the volumes of the servers and recreate their statefulset", func() {
+			virtualCluster, statefulSet := createResizableCluster(true)
+
+			Eventually(resizeReason(virtualCluster)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(cluster.ReasonResizingVolumes))
+
+			var claim corev1.PersistentVolumeClaim
+
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: server.PVCName(virtualCluster.Name, 0), Namespace: namespace}, &claim)
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(claim.Spec.Resources.Requests.Storage().String()).To(Equal("2Gi"))
+
+			// the pods are orphaned, and the statefulset is deleted by the garbage collector
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(statefulSet), statefulSet)
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(statefulSet.DeletionTimestamp).To(Not(BeNil()))
+			Expect(statefulSet.Finalizers).To(ContainElement(metav1.FinalizerOrphanDependents))
+		})
+
+		It("will reject the resize if the StorageClass does not allow volume expansion", func() {
+			virtualCluster, statefulSet := createResizableCluster(false)
+
+			Eventually(resizeReason(virtualCluster)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(cluster.ReasonResizeRejected))
+
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(statefulSet), statefulSet)
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(statefulSet.DeletionTimestamp).To(BeNil())
+			Expect(statefulSet.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests.Storage().String()).To(Equal("1Gi"))
+		})
+	})
+})
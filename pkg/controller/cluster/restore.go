@@ -0,0 +1,209 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/backup"
+	"github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster/server"
+)
+
+const (
+	// ConditionRestored is the condition of a cluster restored from a snapshot
+	ConditionRestored = "Restored"
+
+	ReasonRestoring = "Restoring"
+	ReasonRestored  = "Restored"
+)
+
+// restoring returns true if the cluster is restored from a snapshot, and the restore is not completed yet
+func restoring(cluster *v1alpha1.Cluster) bool {
+	return cluster.Spec.RestoreFrom != nil && !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionRestored)
+}
+
+// restoreBackup returns the ClusterBackup of the snapshot restored by the cluster, or nil if the cluster is not
+// restoring a snapshot. The snapshot is recorded in the status, so that a newer snapshot is not restored later.
+func (c *ClusterReconciler) restoreBackup(ctx context.Context, cluster *v1alpha1.Cluster) (*v1alpha1.ClusterBackup, error) {
+	if !restoring(cluster) {
+		return nil, nil
+	}
+
+	clusterBackup, err := c.clusterBackup(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	// the PVC can only be mounted by the servers in the namespace of the backup
+	if clusterBackup.Spec.Storage.PersistentVolumeClaim != nil && clusterBackup.Namespace != cluster.Namespace {
+		return nil, fmt.Errorf("%w: the snapshots of backup %s/%s are stored in a PVC of another namespace", ErrClusterValidation, clusterBackup.Namespace, clusterBackup.Name)
+	}
+
+	if cluster.Status.RestoredSnapshot == "" {
+		snapshot := cluster.Spec.RestoreFrom.Snapshot
+		if snapshot == "" {
+			snapshot = clusterBackup.Status.LastSnapshot
+		}
+
+		if snapshot == "" {
+			return nil, fmt.Errorf("backup %s/%s has no snapshot", clusterBackup.Namespace, clusterBackup.Name)
+		}
+
+		cluster.Status.RestoredSnapshot = snapshot
+	}
+
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		Type:    ConditionRestored,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonRestoring,
+		Message: fmt.Sprintf("restoring snapshot %s of backup %s/%s", cluster.Status.RestoredSnapshot, clusterBackup.Namespace, clusterBackup.Name),
+	})
+
+	return clusterBackup, nil
+}
+
+// setRestored sets the Restored condition, once the first server is started with the token of the cluster
+func setRestored(cluster *v1alpha1.Cluster) {
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		Type:    ConditionRestored,
+		Status:  metav1.ConditionTrue,
+		Reason:  ReasonRestored,
+		Message: "snapshot " + cluster.Status.RestoredSnapshot + " restored",
+	})
+}
+
+// clusterBackup returns the ClusterBackup restored by the cluster. A ClusterBackup of another namespace must list
+// the namespace of the cluster in its restoreNamespaces.
+func (c *ClusterReconciler) clusterBackup(ctx context.Context, cluster *v1alpha1.Cluster) (*v1alpha1.ClusterBackup, error) {
+	key := types.NamespacedName{
+		Name:      cluster.Spec.RestoreFrom.BackupName,
+		Namespace: cluster.Spec.RestoreFrom.BackupNamespace,
+	}
+
+	if key.Namespace == "" {
+		key.Namespace = cluster.Namespace
+	}
+
+	var clusterBackup v1alpha1.ClusterBackup
+	if err := c.Client.Get(ctx, key, &clusterBackup); err != nil {
+		return nil, err
+	}
+
+	// the snapshots of another namespace, with its token and credentials, are only restored if the backup allows it
+	if clusterBackup.Namespace != cluster.Namespace && !slices.Contains(clusterBackup.Spec.RestoreNamespaces, cluster.Namespace) {
+		return nil, fmt.Errorf("%w: backup %s/%s does not allow restores in namespace %s", ErrClusterValidation, clusterBackup.Namespace, clusterBackup.Name, cluster.Namespace)
+	}
+
+	return &clusterBackup, nil
+}
+
+// newToken returns the token of a new cluster. A restored cluster reuses the token of the backed up cluster,
+// stored in the Secret of its ClusterBackup, unless a new token is requested.
+func (c *ClusterReconciler) newToken(ctx context.Context, cluster *v1alpha1.Cluster) (string, error) {
+	if !restoring(cluster) || cluster.Spec.RestoreFrom.RegenerateToken {
+		return random(16)
+	}
+
+	clusterBackup, err := c.clusterBackup(ctx, cluster)
+	if err != nil {
+		return "", err
+	}
+
+	var backupSecret v1.Secret
+
+	key := types.NamespacedName{Name: backupResourceName(clusterBackup), Namespace: clusterBackup.Namespace}
+	if err := c.Client.Get(ctx, key, &backupSecret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return "", err
+		}
+
+		// the token of the snapshot is rotated by the server
+		ctrl.LoggerFrom(ctx).Info("backup secret not found, creating a random token", "key", key)
+
+		return random(16)
+	}
+
+	token, err := backup.SecretsToken(backupSecret.Data[backupSecretsKey])
+	if err != nil {
+		return "", err
+	}
+
+	return string(token), nil
+}
+
+// restoreContainer returns the init container of the servers fetching the restored snapshot, with its volumes.
+// The S3 credentials of the backup are copied in the namespace of the cluster.
+func (c *ClusterReconciler) restoreContainer(ctx context.Context, cluster *v1alpha1.Cluster, clusterBackup *v1alpha1.ClusterBackup) (v1.Container, []v1.Volume, error) {
+	var credentialsSecretName string
+
+	if s3 := clusterBackup.Spec.Storage.S3; s3 != nil && s3.CredentialsSecretName != "" {
+		credentialsSecretName = controller.SafeConcatNameWithPrefix(cluster.Name, "restore")
+
+		if err := c.ensureRestoreCredentials(ctx, cluster, clusterBackup, credentialsSecretName); err != nil {
+			return v1.Container{}, nil, err
+		}
+	}
+
+	storage := snapshotStorage(clusterBackup, credentialsSecretName)
+
+	args := []string{
+		"--name=" + cluster.Status.RestoredSnapshot,
+		"--restore-dir=" + server.RestoreDir,
+	}
+
+	// the new CAs are rotated by the server once the snapshot is restored
+	if cluster.Spec.RestoreFrom.RegenerateCA {
+		args = append(args, "--regenerate-ca")
+	}
+
+	container := v1.Container{
+		Name:            "restore",
+		Image:           c.ControllerImage,
+		ImagePullPolicy: v1.PullPolicy(c.ControllerImagePullPolicy),
+		Command:         []string{"k3k", "snapshot", "restore"},
+		Args:            append(args, storage.args...),
+		Env:             storage.env,
+		VolumeMounts:    storage.volumeMounts,
+	}
+
+	return container, storage.volumes, nil
+}
+
+func (c *ClusterReconciler) ensureRestoreCredentials(ctx context.Context, cluster *v1alpha1.Cluster, clusterBackup *v1alpha1.ClusterBackup, name string) error {
+	var credentials v1.Secret
+
+	key := types.NamespacedName{Name: clusterBackup.Spec.Storage.S3.CredentialsSecretName, Namespace: clusterBackup.Namespace}
+	if err := c.Client.Get(ctx, key, &credentials); err != nil {
+		return err
+	}
+
+	restoreCredentials := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, c.Client, restoreCredentials, func() error {
+		if err := controllerutil.SetControllerReference(cluster, restoreCredentials, c.Scheme); err != nil {
+			return err
+		}
+
+		restoreCredentials.Data = credentials.Data
+
+		return nil
+	})
+
+	return err
+}
@@ -0,0 +1,244 @@
+package cluster_test
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/backup"
+	k3kcontroller "github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster Controller", Label("controller"), Label("Cluster"), Label("Restore"), func() {
+	Context("restoring a Cluster", func() {
+		var (
+			namespace     string
+			ctx           context.Context
+			clusterBackup *v1alpha1.ClusterBackup
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			createdNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "ns-"}}
+			err := k8sClient.Create(context.Background(), createdNS)
+			Expect(err).To(Not(HaveOccurred()))
+
+			namespace = createdNS.Name
+
+			clusterBackup = &v1alpha1.ClusterBackup{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "backup-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterBackupSpec{
+					ClusterName: "mycluster",
+					Storage: v1alpha1.BackupStorage{
+						PersistentVolumeClaim: &v1alpha1.BackupVolumeStorage{ClaimName: "snapshots"},
+					},
+				},
+			}
+
+			err = k8sClient.Create(ctx, clusterBackup)
+			Expect(err).To(Not(HaveOccurred()))
+
+			// the status is also updated by the backup controller
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterBackup), clusterBackup); err != nil {
+					return err
+				}
+
+				clusterBackup.Status.LastSnapshot = "snapshot-1"
+
+				return k8sClient.Status().Update(ctx, clusterBackup)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			secrets, err := backup.EncodeSecrets(corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "k3k-mycluster-token"},
+				Data:       map[string][]byte{"token": []byte("mytoken")},
+			})
+			Expect(err).To(Not(HaveOccurred()))
+
+			backupSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      k3kcontroller.SafeConcatNameWithPrefix(clusterBackup.Name, "backup"),
+					Namespace: namespace,
+				},
+				Data: map[string][]byte{"secrets.json": secrets},
+			}
+
+			err = k8sClient.Create(ctx, backupSecret)
+			Expect(err).To(Not(HaveOccurred()))
+		})
+
+		It("will restore the last snapshot with a single server and the token of the backup", func() {
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					Servers:     ptr.To[int32](3),
+					RestoreFrom: &v1alpha1.ClusterRestoreSource{BackupName: clusterBackup.Name},
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			var statefulSet appsv1.StatefulSet
+
+			Eventually(func() error {
+				key := client.ObjectKey{
+					Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, "server"),
+					Namespace: namespace,
+				}
+
+				return k8sClient.Get(ctx, key, &statefulSet)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			Expect(statefulSet.Spec.Replicas).To(Equal(ptr.To[int32](1)))
+
+			initContainers := statefulSet.Spec.Template.Spec.InitContainers
+			Expect(initContainers).To(HaveLen(1))
+			Expect(initContainers[0].Args).To(ContainElement("--name=snapshot-1"))
+
+			var tokenSecret corev1.Secret
+
+			key := client.ObjectKey{Name: cluster.TokenSecretName(virtualCluster.Name), Namespace: namespace}
+			err = k8sClient.Get(ctx, key, &tokenSecret)
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(tokenSecret.Data["token"]).To(Equal([]byte("mytoken")))
+
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Expect(virtualCluster.Status.RestoredSnapshot).To(Equal("snapshot-1"))
+
+			restored := meta.FindStatusCondition(virtualCluster.Status.Conditions, cluster.ConditionRestored)
+			Expect(restored).To(Not(BeNil()))
+			Expect(restored.Status).To(Equal(metav1.ConditionFalse))
+			Expect(restored.Reason).To(Equal(cluster.ReasonRestoring))
+		})
+
+		It("will not restore the backup of another namespace not allowing it", func() {
+			otherNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "ns-"}}
+			err := k8sClient.Create(ctx, otherNS)
+			Expect(err).To(Not(HaveOccurred()))
+
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    otherNS.Name,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					RestoreFrom: &v1alpha1.ClusterRestoreSource{
+						BackupName:      clusterBackup.Name,
+						BackupNamespace: namespace,
+					},
+				},
+			}
+
+			err = k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(func() *metav1.Condition {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return meta.FindStatusCondition(virtualCluster.Status.Conditions, cluster.ConditionReady)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(And(
+					Not(BeNil()),
+					HaveField("Reason", cluster.ReasonValidationFailed),
+					HaveField("Message", ContainSubstring("does not allow restores in namespace "+otherNS.Name)),
+				))
+
+			Expect(virtualCluster.Status.Phase).To(Equal(v1alpha1.ClusterPending))
+
+			var secrets corev1.SecretList
+
+			err = k8sClient.List(ctx, &secrets, client.InNamespace(otherNS.Name))
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(secrets.Items).To(BeEmpty())
+		})
+
+		It("will not restore a snapshot stored in a PVC of another namespace", func() {
+			otherNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "ns-"}}
+			err := k8sClient.Create(ctx, otherNS)
+			Expect(err).To(Not(HaveOccurred()))
+
+			clusterBackup.Spec.RestoreNamespaces = []string{otherNS.Name}
+
+			err = k8sClient.Update(ctx, clusterBackup)
+			Expect(err).To(Not(HaveOccurred()))
+
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    otherNS.Name,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					RestoreFrom: &v1alpha1.ClusterRestoreSource{
+						BackupName:      clusterBackup.Name,
+						BackupNamespace: namespace,
+					},
+				},
+			}
+
+			err = k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(func() *metav1.Condition {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return meta.FindStatusCondition(virtualCluster.Status.Conditions, cluster.ConditionReady)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(And(
+					Not(BeNil()),
+					HaveField("Reason", cluster.ReasonValidationFailed),
+					HaveField("Message", ContainSubstring("stored in a PVC of another namespace")),
+				))
+		})
+
+		It("will not restore an existing cluster", func() {
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			virtualCluster.Spec.RestoreFrom = &v1alpha1.ClusterRestoreSource{BackupName: clusterBackup.Name}
+
+			err = k8sClient.Update(ctx, virtualCluster)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,327 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/kubectl/pkg/drain"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster/agent"
+)
+
+const (
+	// ConditionScaling is the condition of a cluster with its servers or its agents scaled down
+	ConditionScaling = "Scaling"
+
+	ReasonRemovingServers = "RemovingServers"
+	ReasonDrainingAgents  = "DrainingAgents"
+	ReasonScaled          = "Scaled"
+
+	// drainedNodeAnnotation marks the virtual nodes of the agents drained before being removed
+	drainedNodeAnnotation = "k3k.io/drained"
+
+	// the hostname of the nodes is the name of the pods of the agents
+	hostnameAnnotation        = "k3s.io/hostname"
+	podDeletionCostAnnotation = "controller.kubernetes.io/pod-deletion-cost"
+)
+
+// scaling returns true if the servers or the agents of the cluster are being scaled down
+func scaling(cluster *v1alpha1.Cluster) bool {
+	return meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionScaling)
+}
+
+// scalingReason returns the reason of the Scaling condition of the cluster
+func scalingReason(cluster *v1alpha1.Cluster) string {
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionScaling)
+	if condition == nil {
+		return ""
+	}
+
+	return condition.Reason
+}
+
+func setScaling(cluster *v1alpha1.Cluster, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		Type:    ConditionScaling,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// serverReplicas returns the replicas of the StatefulSet of the servers. The servers are scaled down one at a time,
+// from the last one, which is removed from the etcd members before its pod is deleted.
+func (c *ClusterReconciler) serverReplicas(ctx context.Context, cluster *v1alpha1.Cluster, statefulSet *apps.StatefulSet) (int32, error) {
+	desired := ptr.Deref(statefulSet.Spec.Replicas, 1)
+
+	// the servers of the suspended and restored clusters are not removed from etcd, and the servers of the clusters
+	// with an external datastore are not etcd members
+	if controller.Suspended(cluster) || desired != ptr.Deref(cluster.Spec.Servers, 1) || controller.ExternalDatastore(cluster) {
+		return desired, nil
+	}
+
+	var current apps.StatefulSet
+	if err := c.Client.Get(ctx, client.ObjectKeyFromObject(statefulSet), &current); err != nil {
+		if apierrors.IsNotFound(err) {
+			return desired, nil
+		}
+
+		return desired, err
+	}
+
+	replicas := ptr.Deref(current.Spec.Replicas, 1)
+
+	if desired >= replicas {
+		if scalingReason(cluster) == ReasonRemovingServers {
+			c.Eventf(cluster, v1.EventTypeNormal, ReasonScaled, "servers scaled down to %d", desired)
+			setScaling(cluster, metav1.ConditionFalse, ReasonScaled, fmt.Sprintf("servers scaled down to %d", desired))
+		}
+
+		return desired, nil
+	}
+
+	podName := fmt.Sprintf("%s-%d", current.Name, replicas-1)
+
+	setScaling(cluster, metav1.ConditionTrue, ReasonRemovingServers, fmt.Sprintf("removing server %s, scaling down from %d to %d servers", podName, replicas, desired))
+
+	var pod v1.Pod
+	if err := c.Client.Get(ctx, client.ObjectKey{Name: podName, Namespace: cluster.Namespace}, &pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return replicas - 1, nil
+		}
+
+		return replicas, err
+	}
+
+	if pod.Status.PodIP != "" && pod.DeletionTimestamp.IsZero() {
+		if err := removeServerPeer(ctx, c.Client, cluster, &pod); err != nil {
+			return replicas, err
+		}
+	}
+
+	return replicas - 1, nil
+}
+
+// agentReplicas returns the replicas of the Deployment of the agents of a cluster in virtual mode. When the agents are
+// scaled down, the virtual nodes of the removed agents are cordoned and drained, and the agents keep their replicas
+// until the nodes are drained. The drained nodes are deleted once their agents are removed.
+func (c *ClusterReconciler) agentReplicas(ctx context.Context, cluster *v1alpha1.Cluster, serviceIP string) (*int32, error) {
+	desired := cluster.Spec.Agents
+
+	var deployment apps.Deployment
+
+	key := client.ObjectKey{Name: agent.VirtualAgentName(cluster.Name), Namespace: cluster.Namespace}
+	if err := c.Client.Get(ctx, key, &deployment); err != nil {
+		if apierrors.IsNotFound(err) {
+			return desired, nil
+		}
+
+		return desired, err
+	}
+
+	replicas := ptr.Deref(deployment.Spec.Replicas, 1)
+
+	if ptr.Deref(desired, 0) >= replicas {
+		if scalingReason(cluster) != ReasonDrainingAgents {
+			return desired, nil
+		}
+
+		return desired, c.deleteDrainedNodes(ctx, cluster, serviceIP)
+	}
+
+	var pods v1.PodList
+	if err := c.Client.List(ctx, &pods, client.InNamespace(cluster.Namespace), client.MatchingLabels(agent.VirtualAgentLabels(cluster.Name))); err != nil {
+		return &replicas, err
+	}
+
+	removed := removedAgents(pods.Items, int(ptr.Deref(desired, 0)))
+	if len(removed) == 0 {
+		return desired, nil
+	}
+
+	setScaling(cluster, metav1.ConditionTrue, ReasonDrainingAgents, fmt.Sprintf("draining %d agents, scaling down from %d to %d agents", len(removed), replicas, ptr.Deref(desired, 0)))
+
+	clientset, err := c.virtualClientset(ctx, cluster, net.JoinHostPort(serviceIP, "443"))
+	if err != nil {
+		return &replicas, err
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return &replicas, err
+	}
+
+	drained := true
+
+	for _, pod := range removed {
+		for _, node := range nodes.Items {
+			if node.Annotations[hostnameAnnotation] != pod.Name {
+				continue
+			}
+
+			nodeDrained, err := drainNode(ctx, clientset, &node)
+			if err != nil {
+				return &replicas, err
+			}
+
+			drained = drained && nodeDrained
+		}
+	}
+
+	if !drained {
+		return &replicas, nil
+	}
+
+	// the ReplicaSet of the agents deletes the drained agents first
+	for _, pod := range removed {
+		patch := client.MergeFrom(pod.DeepCopy())
+
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+
+		pod.Annotations[podDeletionCostAnnotation] = strconv.Itoa(math.MinInt32)
+
+		if err := c.Client.Patch(ctx, &pod, patch); client.IgnoreNotFound(err) != nil {
+			return &replicas, err
+		}
+	}
+
+	return desired, nil
+}
+
+// removedAgents returns the agent pods removed when the agents are scaled down to the replicas: the pods which are
+// not ready, and then the newest ones
+func removedAgents(pods []v1.Pod, replicas int) []v1.Pod {
+	pods = slices.DeleteFunc(pods, func(pod v1.Pod) bool {
+		return !pod.DeletionTimestamp.IsZero()
+	})
+
+	if len(pods) <= replicas {
+		return nil
+	}
+
+	slices.SortFunc(pods, func(a, b v1.Pod) int {
+		if podReady(&a) != podReady(&b) {
+			if podReady(&b) {
+				return -1
+			}
+
+			return 1
+		}
+
+		return b.CreationTimestamp.Compare(a.CreationTimestamp.Time)
+	})
+
+	return pods[:len(pods)-replicas]
+}
+
+// drainNode cordons the node, and evicts its pods. It returns true once the pods are evicted.
+func drainNode(ctx context.Context, clientset kubernetes.Interface, node *v1.Node) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if !node.Spec.Unschedulable || node.Annotations[drainedNodeAnnotation] == "" {
+		log.Info("cordoning agent node", "node", node.Name)
+
+		patch := []byte(`{"metadata":{"annotations":{"` + drainedNodeAnnotation + `":"true"}},"spec":{"unschedulable":true}}`)
+		if _, err := clientset.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return false, err
+		}
+	}
+
+	helper := &drain.Helper{
+		Ctx:                 ctx,
+		Client:              clientset,
+		Force:               true,
+		IgnoreAllDaemonSets: true,
+		DeleteEmptyDirData:  true,
+		GracePeriodSeconds:  -1,
+		Out:                 io.Discard,
+		ErrOut:              io.Discard,
+	}
+
+	podList, errs := helper.GetPodsForDeletion(node.Name)
+	if len(errs) > 0 {
+		return false, errors.Join(errs...)
+	}
+
+	pods := podList.Pods()
+	if len(pods) == 0 {
+		return true, nil
+	}
+
+	log.Info("draining agent node", "node", node.Name, "pods", len(pods))
+
+	for _, pod := range pods {
+		// the evictions blocked by a PodDisruptionBudget are retried
+		if err := helper.EvictPod(pod, policyv1.SchemeGroupVersion); err != nil && !apierrors.IsNotFound(err) && !apierrors.IsTooManyRequests(err) {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// deleteDrainedNodes deletes the drained virtual nodes of the removed agents, once their pods are deleted
+func (c *ClusterReconciler) deleteDrainedNodes(ctx context.Context, cluster *v1alpha1.Cluster, serviceIP string) error {
+	clientset, err := c.virtualClientset(ctx, cluster, net.JoinHostPort(serviceIP, "443"))
+	if err != nil {
+		return err
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	remaining := 0
+
+	for _, node := range nodes.Items {
+		if node.Annotations[drainedNodeAnnotation] == "" {
+			continue
+		}
+
+		err := c.Client.Get(ctx, client.ObjectKey{Name: node.Annotations[hostnameAnnotation], Namespace: cluster.Namespace}, &v1.Pod{})
+		if err == nil {
+			remaining++
+			continue
+		}
+
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		if err := clientset.CoreV1().Nodes().Delete(ctx, node.Name, metav1.DeleteOptions{}); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	if remaining > 0 {
+		return nil
+	}
+
+	c.Eventf(cluster, v1.EventTypeNormal, ReasonScaled, "agents scaled down to %d", ptr.Deref(cluster.Spec.Agents, 0))
+	setScaling(cluster, metav1.ConditionFalse, ReasonScaled, fmt.Sprintf("agents scaled down to %d", ptr.Deref(cluster.Spec.Agents, 0)))
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package cluster_test
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	k3kcontroller "github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster"
+	"github.com/rancher/k3k/pkg/controller/cluster/agent"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster Controller", Label("controller"), Label("Cluster"), Label("Scale"), func() {
+	Context("scaling a Cluster", func() {
+		var (
+			namespace string
+			ctx       context.Context
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			createdNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "ns-"}}
+			err := k8sClient.Create(context.Background(), createdNS)
+			Expect(err).To(Not(HaveOccurred()))
+
+			namespace = createdNS.Name
+		})
+
+		It("will scale the agents with the scale subresource", func() {
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					Mode: v1alpha1.VirtualClusterMode,
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			scale := &autoscalingv1.Scale{}
+
+			err = k8sClient.SubResource("scale").Get(ctx, virtualCluster, scale)
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(scale.Spec.Replicas).To(BeZero())
+
+			scale.Spec.Replicas = 3
+
+			err = k8sClient.SubResource("scale").Update(ctx, virtualCluster, client.WithSubResourceBody(scale))
+			Expect(err).To(Not(HaveOccurred()))
+
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(virtualCluster.Spec.Agents).To(Equal(ptr.To[int32](3)))
+
+			Eventually(func() string {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return virtualCluster.Status.AgentSelector
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal("cluster=" + virtualCluster.Name + ",mode=virtual,type=agent"))
+		})
+
+		It("will ignore the agents of a shared cluster", func() {
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					Mode:   v1alpha1.SharedClusterMode,
+					Agents: ptr.To[int32](3),
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			scale := &autoscalingv1.Scale{}
+
+			err = k8sClient.SubResource("scale").Get(ctx, virtualCluster, scale)
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(scale.Spec.Replicas).To(Equal(int32(3)))
+
+			scale.Spec.Replicas = 5
+
+			err = k8sClient.SubResource("scale").Update(ctx, virtualCluster, client.WithSubResourceBody(scale))
+			Expect(err).To(Not(HaveOccurred()))
+
+			sharedAgentKey := client.ObjectKey{
+				Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, agent.SharedNodeAgentName),
+				Namespace: namespace,
+			}
+
+			// the k3k-kubelet DaemonSet is created in shared mode, and the agents are not
+			Eventually(func() error {
+				return k8sClient.Get(ctx, sharedAgentKey, &appsv1.DaemonSet{})
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			virtualAgentKey := client.ObjectKey{
+				Name:      agent.VirtualAgentName(virtualCluster.Name),
+				Namespace: namespace,
+			}
+
+			err = k8sClient.Get(ctx, virtualAgentKey, &appsv1.Deployment{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("will scale down the servers one at a time", func() {
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					Servers: ptr.To[int32](3),
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			var statefulSet appsv1.StatefulSet
+
+			key := client.ObjectKey{
+				Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, "server"),
+				Namespace: namespace,
+			}
+
+			Eventually(func() int32 {
+				if err := k8sClient.Get(ctx, key, &statefulSet); err != nil {
+					return 0
+				}
+
+				return ptr.Deref(statefulSet.Spec.Replicas, 0)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(int32(3)))
+
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster); err != nil {
+					return err
+				}
+
+				virtualCluster.Spec.Servers = ptr.To[int32](1)
+
+				return k8sClient.Update(ctx, virtualCluster)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			// the pods of the servers are not created in the test environment, and the servers are removed
+			// without removing their etcd members
+			Eventually(func() int32 {
+				err := k8sClient.Get(ctx, key, &statefulSet)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return ptr.Deref(statefulSet.Spec.Replicas, 0)
+			}).
+				WithTimeout(time.Second * 60).
+				WithPolling(time.Second).
+				Should(Equal(int32(1)))
+
+			Eventually(func() *metav1.Condition {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return meta.FindStatusCondition(virtualCluster.Status.Conditions, cluster.ConditionScaling)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(And(
+					Not(BeNil()),
+					HaveField("Status", metav1.ConditionFalse),
+					HaveField("Reason", cluster.ReasonScaled),
+				))
+		})
+	})
+})
@@ -2,6 +2,8 @@ package server
 
 import (
 	"fmt"
+	"path"
+	"strconv"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 
@@ -21,6 +23,8 @@ func (s *Server) Config(init bool, serviceIP string) (*v1.Secret, error) {
 		serviceIP,
 		ServiceName(s.cluster.Name),
 		fmt.Sprintf("%s.%s", ServiceName(s.cluster.Name), s.cluster.Namespace),
+		// the etcd of the servers is reached with the headless service
+		fmt.Sprintf("%s.%s", headlessServiceName(s.cluster.Name), s.cluster.Namespace),
 	)
 
 	s.cluster.Status.TLSSANs = sans.List()
@@ -30,6 +34,11 @@ func (s *Server) Config(init bool, serviceIP string) (*v1.Secret, error) {
 		config = initConfigData(s.cluster, s.token)
 	}
 
+	// the servers with an external datastore don't join the embedded etcd of the first server
+	if controller.ExternalDatastore(s.cluster) {
+		config = s.datastoreOptions() + serverOptions(s.cluster, s.token)
+	}
+
 	return &v1.Secret{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Secret",
@@ -88,6 +97,26 @@ func serverOptions(cluster *v1alpha1.Cluster, token string) string {
 	return opts
 }
 
+// datastoreOptions returns the options of the external datastore. Without a datastore, k3s stores the data in SQLite.
+func (s *Server) datastoreOptions() string {
+	if s.datastore == nil {
+		return ""
+	}
+
+	opts := "datastore-endpoint: " + strconv.Quote(s.datastore.endpoint) + "\n"
+
+	if s.datastore.ca {
+		opts = opts + "datastore-cafile: " + path.Join(DatastoreDir, v1.ServiceAccountRootCAKey) + "\n"
+	}
+
+	if s.datastore.clientCert {
+		opts = opts + "datastore-certfile: " + path.Join(DatastoreDir, v1.TLSCertKey) + "\n"
+		opts = opts + "datastore-keyfile: " + path.Join(DatastoreDir, v1.TLSPrivateKeyKey) + "\n"
+	}
+
+	return opts
+}
+
 func configSecretName(clusterName string, init bool) string {
 	if !init {
 		return controller.SafeConcatNameWithPrefix(clusterName, configName)
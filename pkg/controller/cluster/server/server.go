@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"path"
+	"slices"
 	"sort"
 	"strings"
 	"text/template"
@@ -19,6 +21,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/backup"
 	"github.com/rancher/k3k/pkg/controller"
 	"github.com/rancher/k3k/pkg/controller/cluster/agent"
 )
@@ -28,6 +31,24 @@ const (
 	serverName         = "server"
 	configName         = "server-config"
 	initConfigName     = "init-server-config"
+	pvcTemplateName    = "varlibrancherk3s"
+
+	// RestoreDir is the directory where the snapshot restored by the first server is fetched
+	RestoreDir = "/var/lib/rancher/k3s/restore"
+
+	// ActivityDir is the directory of the audit policy and of the audit webhook configuration of the servers
+	ActivityDir = "/etc/rancher/k3k/activity"
+
+	AuditPolicyFile    = "policy.yaml"
+	AuditWebhookFile   = "webhook.yaml"
+	activityVolumeName = "activity"
+
+	// DatastoreDir is the directory of the certificates of the external datastore of the servers
+	DatastoreDir = "/etc/rancher/k3k/datastore"
+
+	// DatastoreEndpointKey is the key of the endpoint in the Secret of an external datastore
+	DatastoreEndpointKey = "endpoint"
+	datastoreVolumeName  = "datastore"
 )
 
 // Server
@@ -39,6 +60,21 @@ type Server struct {
 	image            string
 	imagePullPolicy  string
 	imagePullSecrets []string
+
+	restoreContainer *v1.Container
+	restoreVolumes   []v1.Volume
+
+	activitySecret string
+
+	datastore *datastore
+}
+
+// datastore is the external datastore of the servers, with the certificates found in its Secret
+type datastore struct {
+	endpoint   string
+	secretName string
+	ca         bool
+	clientCert bool
 }
 
 func New(cluster *v1alpha1.Cluster, client client.Client, token, image, imagePullPolicy string, imagePullSecrets []string) *Server {
@@ -53,6 +89,39 @@ func New(cluster *v1alpha1.Cluster, client client.Client, token, image, imagePul
 	}
 }
 
+// Restore adds the init container fetching the snapshot in the RestoreDir, and its volumes, to the servers.
+// The servers are scaled down to the first one, that restores the snapshot on its first start.
+func (s *Server) Restore(initContainer v1.Container, volumes ...v1.Volume) {
+	initContainer.VolumeMounts = append(initContainer.VolumeMounts, v1.VolumeMount{
+		Name:      "varlibrancherk3s",
+		MountPath: "/var/lib/rancher/k3s",
+	})
+
+	s.restoreContainer = &initContainer
+	s.restoreVolumes = volumes
+}
+
+// TrackActivity mounts the Secret with the audit policy and the audit webhook configuration in the ActivityDir,
+// and configures the API server to send its audit events to the activity tracker.
+func (s *Server) TrackActivity(secretName string) {
+	s.activitySecret = secretName
+}
+
+// ExternalDatastore configures the servers to store the data of the cluster in the datastore of the Secret.
+// The Secret is mounted in the DatastoreDir, for the certificates of the datastore.
+func (s *Server) ExternalDatastore(secret *v1.Secret) {
+	_, ca := secret.Data[v1.ServiceAccountRootCAKey]
+	_, cert := secret.Data[v1.TLSCertKey]
+	_, key := secret.Data[v1.TLSPrivateKeyKey]
+
+	s.datastore = &datastore{
+		endpoint:   string(secret.Data[DatastoreEndpointKey]),
+		secretName: secret.Name,
+		ca:         ca,
+		clientCert: cert && key,
+	}
+}
+
 func (s *Server) podSpec(image, name string, persistent bool, startupCmd string) v1.PodSpec {
 	podSpec := v1.PodSpec{
 		NodeSelector:      s.cluster.Spec.NodeSelector,
@@ -264,12 +333,28 @@ func (s *Server) StatefulServer(ctx context.Context) (*apps.StatefulSet, error)
 	name := controller.SafeConcatNameWithPrefix(s.cluster.Name, serverName)
 
 	replicas = *s.cluster.Spec.Servers
+	if s.restoreContainer != nil {
+		replicas = 1
+	}
+
+	if controller.Suspended(s.cluster) {
+		replicas = 0
+	}
 
-	if s.cluster.Spec.Persistence.Type == v1alpha1.DynamicPersistenceMode {
+	// without the Secret of an external datastore, the data is stored in SQLite on the PVC of the server
+	if s.cluster.Spec.Persistence.Type == v1alpha1.DynamicPersistenceMode || (controller.ExternalDatastore(s.cluster) && s.datastore == nil) {
 		persistent = true
 		pvClaim = s.setupDynamicPersistence()
 	}
 
+	// the claims of the servers are bound to the pre-provisioned volumes by the controller before the servers are
+	// created, and the template must not provision new volumes for them
+	if s.cluster.Spec.Persistence.Type == v1alpha1.StaticPersistenceMode {
+		persistent = true
+		pvClaim = s.setupDynamicPersistence()
+		pvClaim.Spec.StorageClassName = ptr.To("")
+	}
+
 	var (
 		volumes      []v1.Volume
 		volumeMounts []v1.VolumeMount
@@ -357,6 +442,43 @@ func (s *Server) StatefulServer(ctx context.Context) (*apps.StatefulSet, error)
 	podSpec.Volumes = append(podSpec.Volumes, volumes...)
 	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, volumeMounts...)
 
+	if s.restoreContainer != nil {
+		podSpec.InitContainers = append(podSpec.InitContainers, *s.restoreContainer)
+		podSpec.Volumes = append(podSpec.Volumes, s.restoreVolumes...)
+	}
+
+	if s.datastore != nil {
+		podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+			Name: datastoreVolumeName,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName: s.datastore.secretName,
+				},
+			},
+		})
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, v1.VolumeMount{
+			Name:      datastoreVolumeName,
+			MountPath: DatastoreDir,
+			ReadOnly:  true,
+		})
+	}
+
+	if s.activitySecret != "" {
+		podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+			Name: activityVolumeName,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName: s.activitySecret,
+				},
+			},
+		})
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, v1.VolumeMount{
+			Name:      activityVolumeName,
+			MountPath: ActivityDir,
+			ReadOnly:  true,
+		})
+	}
+
 	ss := &apps.StatefulSet{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "StatefulSet",
@@ -379,13 +501,18 @@ func (s *Server) StatefulServer(ctx context.Context) (*apps.StatefulSet, error)
 			},
 		},
 	}
-	if s.cluster.Spec.Persistence.Type == v1alpha1.DynamicPersistenceMode {
+	if persistent {
 		ss.Spec.VolumeClaimTemplates = []v1.PersistentVolumeClaim{pvClaim}
 	}
 
 	return ss, nil
 }
 
+// PVCName returns the name of the PersistentVolumeClaim of the server with the given ordinal
+func PVCName(clusterName string, ordinal int) string {
+	return fmt.Sprintf("%s-%s-%d", pvcTemplateName, controller.SafeConcatNameWithPrefix(clusterName, serverName), ordinal)
+}
+
 func (s *Server) setupDynamicPersistence() v1.PersistentVolumeClaim {
 	return v1.PersistentVolumeClaim{
 		TypeMeta: metav1.TypeMeta{
@@ -393,7 +520,7 @@ func (s *Server) setupDynamicPersistence() v1.PersistentVolumeClaim {
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "varlibrancherk3s",
+			Name:      pvcTemplateName,
 			Namespace: s.cluster.Namespace,
 		},
 		Spec: v1.PersistentVolumeClaimSpec{
@@ -416,16 +543,37 @@ func (s *Server) setupStartCommand() (string, error) {
 		tmpl = HAServerTemplate
 	}
 
+	if s.restoreContainer != nil {
+		tmpl = restoreServerTemplate
+	}
+
+	// the servers share the datastore, and there is no etcd to reset
+	if controller.ExternalDatastore(s.cluster) {
+		tmpl = externalDatastoreServerTemplate
+	}
+
 	tmplCmd, err := template.New("").Parse(tmpl)
 	if err != nil {
 		return "", err
 	}
 
+	extraArgs := s.cluster.Spec.ServerArgs
+	if s.activitySecret != "" {
+		extraArgs = append(slices.Clone(extraArgs),
+			"--kube-apiserver-arg=audit-policy-file="+path.Join(ActivityDir, AuditPolicyFile),
+			"--kube-apiserver-arg=audit-webhook-config-file="+path.Join(ActivityDir, AuditWebhookFile),
+			"--kube-apiserver-arg=audit-webhook-batch-max-wait=5s",
+		)
+	}
+
 	if err := tmplCmd.Execute(&output, map[string]string{
 		"ETCD_DIR":      "/var/lib/rancher/k3s/server/db/etcd",
 		"INIT_CONFIG":   "/opt/rancher/k3s/init/config.yaml",
 		"SERVER_CONFIG": "/opt/rancher/k3s/server/config.yaml",
-		"EXTRA_ARGS":    strings.Join(s.cluster.Spec.ServerArgs, " "),
+		"RESTORE_PATH":  path.Join(RestoreDir, backup.RestoreSnapshotFile),
+		"RESTORE_TOKEN": path.Join(RestoreDir, backup.RestoreTokenFile),
+		"RESTORE_CA":    path.Join(RestoreDir, backup.RestoreCADir),
+		"EXTRA_ARGS":    strings.Join(extraArgs, " "),
 	}); err != nil {
 		return "", err
 	}
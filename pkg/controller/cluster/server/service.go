@@ -1,6 +1,9 @@
 package server
 
 import (
+	"net"
+	"strconv"
+
 	"k8s.io/apimachinery/pkg/util/intstr"
 
 	v1 "k8s.io/api/core/v1"
@@ -154,10 +157,25 @@ func (s *Server) StatefulServerService() *v1.Service {
 	}
 }
 
+// WakeProxyLabels returns the labels of the wake proxy of a sleeping cluster, selected by the Service of the cluster
+// instead of the servers
+func WakeProxyLabels(cluster *v1alpha1.Cluster) map[string]string {
+	return map[string]string{
+		"cluster": cluster.Name,
+		"role":    "wake-proxy",
+	}
+}
+
 func ServiceName(clusterName string) string {
 	return controller.SafeConcatNameWithPrefix(clusterName, "service")
 }
 
+// EtcdEndpoint returns the endpoint of the etcd of the servers of the cluster, with the headless service of the servers
+func EtcdEndpoint(cluster *v1alpha1.Cluster) string {
+	host := headlessServiceName(cluster.Name) + "." + cluster.Namespace
+	return "https://" + net.JoinHostPort(host, strconv.Itoa(etcdPort))
+}
+
 func headlessServiceName(clusterName string) string {
 	return controller.SafeConcatNameWithPrefix(clusterName, "service", "headless")
 }
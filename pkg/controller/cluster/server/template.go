@@ -14,3 +14,32 @@ if [ ${POD_NAME: -1} == 0 ] && [ ! -d "{{.ETCD_DIR}}" ]; then
 else 
 	/bin/k3s server --config {{.SERVER_CONFIG}} {{.EXTRA_ARGS}} 2>&1 | tee /var/log/k3s.log 
 fi`
+
+// externalDatastoreServerTemplate starts the servers of a cluster with an external datastore, all with the same configuration
+var externalDatastoreServerTemplate string = `
+/bin/k3s server --config {{.INIT_CONFIG}} {{.EXTRA_ARGS}} 2>&1 | tee /var/log/k3s.log`
+
+// restoreServerTemplate restores the snapshot fetched by the init container with the token of its cluster, rotates
+// the CAs if new ones were generated, and rotates the token if the cluster has a different one, before starting the
+// first server. The server keeps its service account key, so that the tokens of the service accounts stay valid.
+var restoreServerTemplate string = `
+if [ -f "{{.RESTORE_PATH}}" ]; then
+	/bin/k3s server --cluster-reset --cluster-reset-restore-path={{.RESTORE_PATH}} --config {{.INIT_CONFIG}} --token="$(cat {{.RESTORE_TOKEN}})" {{.EXTRA_ARGS}} 2>&1 | tee /var/log/k3s.log
+	rm -f {{.RESTORE_PATH}} /var/lib/rancher/k3s/server/db/reset-flag
+fi
+if [ -f "{{.RESTORE_TOKEN}}" ]; then
+	RESTORE_TOKEN=$(cat {{.RESTORE_TOKEN}})
+	TOKEN=$(sed -n 's/^token: //p' {{.INIT_CONFIG}})
+	if [ "$RESTORE_TOKEN" != "$TOKEN" ] || [ -d "{{.RESTORE_CA}}" ]; then
+		/bin/k3s server --config {{.INIT_CONFIG}} --token="$RESTORE_TOKEN" {{.EXTRA_ARGS}} > /var/log/k3s.log 2>&1 &
+		if [ -d "{{.RESTORE_CA}}" ]; then
+			until cp /var/lib/rancher/k3s/server/tls/service.key {{.RESTORE_CA}}/service.key && /bin/k3s certificate rotate-ca --token="$RESTORE_TOKEN" --path={{.RESTORE_CA}} --force; do sleep 5; done
+		fi
+		if [ "$RESTORE_TOKEN" != "$TOKEN" ]; then
+			until /bin/k3s token rotate --token="$RESTORE_TOKEN" --new-token="$TOKEN"; do sleep 5; done
+		fi
+		kill $! && wait $!
+	fi
+	rm -rf {{.RESTORE_TOKEN}} {{.RESTORE_CA}}
+fi
+/bin/k3s server --config {{.INIT_CONFIG}} {{.EXTRA_ARGS}} 2>&1 | tee /var/log/k3s.log`
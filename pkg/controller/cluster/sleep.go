@@ -0,0 +1,337 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster/activity"
+	"github.com/rancher/k3k/pkg/controller/cluster/server"
+)
+
+const (
+	// WakeUpAnnotation wakes a sleeping cluster up when it is added to the Cluster
+	WakeUpAnnotation = "k3k.io/wake-up"
+
+	// ConditionActivityTracked is the condition of a cluster with automatic sleep, true when its activity is tracked
+	ConditionActivityTracked = "ActivityTracked"
+
+	ReasonActivityTracked = "Tracked"
+	ReasonAuditConflict   = "AuditConflict"
+	ReasonTrackerDisabled = "TrackerDisabled"
+
+	wakeProxyRole = "wake-proxy"
+	wakeProxyPort = 6443
+
+	// activityResolution is the minimum difference between the last activity recorded by the tracker
+	// and the last activity time of the status updating the status
+	activityResolution = time.Minute
+)
+
+// trackActivity returns true if the audit events of the API server of the cluster are sent to the activity tracker.
+// The activity is only tracked for the clusters with automatic sleep enabled by their VirtualClusterPolicy, and not
+// for the clusters with their own audit configuration.
+func (c *ClusterReconciler) trackActivity(cluster *v1alpha1.Cluster, autoSleep *v1alpha1.AutoSleepConfig) bool {
+	return autoSleep != nil && c.ActivityTracker != nil && c.ActivityURL != "" && activity.AuditConflict(cluster.Spec.ServerArgs) == ""
+}
+
+// setActivityTracked sets the ActivityTracked condition of a cluster with automatic sleep, false when its activity
+// can't be tracked
+func (c *ClusterReconciler) setActivityTracked(cluster *v1alpha1.Cluster, autoSleep *v1alpha1.AutoSleepConfig) {
+	if autoSleep == nil {
+		meta.RemoveStatusCondition(&cluster.Status.Conditions, ConditionActivityTracked)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:    ConditionActivityTracked,
+		Status:  metav1.ConditionTrue,
+		Reason:  ReasonActivityTracked,
+		Message: "the audit events of the servers are sent to the activity tracker",
+	}
+
+	if c.ActivityTracker == nil || c.ActivityURL == "" {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonTrackerDisabled
+		condition.Message = "the activity tracker of the controller is disabled, the cluster is never put to sleep"
+	}
+
+	if flag := activity.AuditConflict(cluster.Spec.ServerArgs); flag != "" {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonAuditConflict
+		condition.Message = fmt.Sprintf("the servers set their own %s, the cluster is never put to sleep", flag)
+	}
+
+	if condition.Status == metav1.ConditionFalse && !meta.IsStatusConditionFalse(cluster.Status.Conditions, ConditionActivityTracked) {
+		c.Eventf(cluster, v1.EventTypeWarning, condition.Reason, condition.Message)
+	}
+
+	meta.SetStatusCondition(&cluster.Status.Conditions, condition)
+}
+
+// autoSleep returns the automatic sleep configuration of the VirtualClusterPolicy of the cluster, or nil
+func (c *ClusterReconciler) autoSleep(ctx context.Context, cluster *v1alpha1.Cluster) (*v1alpha1.AutoSleepConfig, error) {
+	if cluster.Status.PolicyName == "" {
+		return nil, nil
+	}
+
+	var policy v1alpha1.VirtualClusterPolicy
+	if err := c.Client.Get(ctx, client.ObjectKey{Name: cluster.Status.PolicyName}, &policy); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	return policy.Spec.AutoSleep, nil
+}
+
+// reconcileSleep records the last activity of the cluster in its status, puts the cluster to sleep once it has been idle
+// for the period of the policy, and wakes it up on a new activity
+func (c *ClusterReconciler) reconcileSleep(ctx context.Context, cluster *v1alpha1.Cluster, autoSleep *v1alpha1.AutoSleepConfig) {
+	log := ctrl.LoggerFrom(ctx)
+
+	now := time.Now().Truncate(time.Second)
+
+	c.setActivityTracked(cluster, autoSleep)
+
+	if !c.trackActivity(cluster, autoSleep) {
+		if cluster.Status.Sleeping {
+			c.wakeUp(cluster, now, "the activity of the cluster is not tracked")
+		}
+
+		// the activity is tracked again from the time automatic sleep is enabled
+		cluster.Status.LastActivityTime = nil
+
+		return
+	}
+
+	// the times of the status are stored with a precision of a second
+	lastActivity := c.ActivityTracker.LastActivity(client.ObjectKeyFromObject(cluster)).Truncate(time.Second)
+
+	if cluster.Status.LastActivityTime == nil {
+		cluster.Status.LastActivityTime = &metav1.Time{Time: now}
+	}
+
+	statusActivity := cluster.Status.LastActivityTime.Time
+
+	if cluster.Status.Sleeping {
+		if lastActivity.After(statusActivity) {
+			c.wakeUp(cluster, lastActivity, "a new activity was recorded")
+		}
+
+		return
+	}
+
+	// the status is not updated on every request, to avoid reconciling the cluster too often
+	if lastActivity.Sub(statusActivity) >= activityResolution {
+		cluster.Status.LastActivityTime = &metav1.Time{Time: lastActivity}
+	}
+
+	if cluster.Spec.Suspend || cluster.Spec.Persistence.Type == v1alpha1.EphemeralPersistenceMode {
+		return
+	}
+
+	if lastActivity.After(statusActivity) {
+		statusActivity = lastActivity
+	}
+
+	if now.Sub(statusActivity) < autoSleep.IdleTimeout.Duration {
+		return
+	}
+
+	log.Info("putting idle cluster to sleep", "lastActivityTime", statusActivity)
+
+	cluster.Status.LastActivityTime = &metav1.Time{Time: statusActivity}
+	cluster.Status.Sleeping = true
+
+	c.Eventf(cluster, v1.EventTypeNormal, ReasonSleeping, "Cluster idle since %s, putting it to sleep", statusActivity.Format(time.RFC3339))
+}
+
+func (c *ClusterReconciler) wakeUp(cluster *v1alpha1.Cluster, at time.Time, reason string) {
+	cluster.Status.Sleeping = false
+	cluster.Status.LastActivityTime = &metav1.Time{Time: at}
+
+	c.Eventf(cluster, v1.EventTypeNormal, ReasonSleeping, "Cluster woken up: %s", reason)
+}
+
+// sleepRequeue returns the time remaining before an idle cluster is put to sleep, or zero
+func (c *ClusterReconciler) sleepRequeue(ctx context.Context, cluster *v1alpha1.Cluster) (time.Duration, error) {
+	if controller.Suspended(cluster) || cluster.Status.LastActivityTime == nil {
+		return 0, nil
+	}
+
+	autoSleep, err := c.autoSleep(ctx, cluster)
+	if err != nil || !c.trackActivity(cluster, autoSleep) {
+		return 0, err
+	}
+
+	remaining := autoSleep.IdleTimeout.Duration - time.Since(cluster.Status.LastActivityTime.Time)
+
+	return max(remaining, time.Second), nil
+}
+
+// ensureActivitySecret creates the Secret with the audit policy and the audit webhook configuration of the servers.
+// It also stores the activity token and the CA of the tracker, read by the wake proxy.
+func (c *ClusterReconciler) ensureActivitySecret(ctx context.Context, cluster *v1alpha1.Cluster, autoSleep *v1alpha1.AutoSleepConfig) (string, error) {
+	activitySecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      activitySecretName(cluster.Name),
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	if !c.trackActivity(cluster, autoSleep) {
+		return "", client.IgnoreNotFound(c.Client.Delete(ctx, activitySecret))
+	}
+
+	policy, err := activity.AuditPolicy()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = controllerutil.CreateOrUpdate(ctx, c.Client, activitySecret, func() error {
+		if err := controllerutil.SetControllerReference(cluster, activitySecret, c.Scheme); err != nil {
+			return err
+		}
+
+		// the activity token is generated once, and only authenticates the requests of the cluster to the tracker
+		token := string(activitySecret.Data["token"])
+		if token == "" {
+			var err error
+
+			if token, err = random(32); err != nil {
+				return err
+			}
+		}
+
+		webhook, err := activity.WebhookConfig(c.ActivityURL, client.ObjectKeyFromObject(cluster), token, c.ActivityCA)
+		if err != nil {
+			return err
+		}
+
+		activitySecret.Data = map[string][]byte{
+			server.AuditPolicyFile:     policy,
+			server.AuditWebhookFile:    webhook,
+			"token":                    []byte(token),
+			v1.ServiceAccountRootCAKey: c.ActivityCA,
+		}
+
+		return nil
+	})
+
+	return activitySecret.Name, err
+}
+
+// ensureWakeProxy runs the wake proxy of a sleeping cluster, replacing its servers behind the Service of the cluster.
+// The proxy closes the connections to the API server, and asks the activity tracker to wake the cluster up.
+func (c *ClusterReconciler) ensureWakeProxy(ctx context.Context, cluster *v1alpha1.Cluster, autoSleep *v1alpha1.AutoSleepConfig) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	deployment := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controller.SafeConcatNameWithPrefix(cluster.Name, wakeProxyRole),
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	if !cluster.Status.Sleeping || !c.trackActivity(cluster, autoSleep) || !autoSleep.WakeOnRequest {
+		return false, client.IgnoreNotFound(c.Client.Delete(ctx, deployment))
+	}
+
+	log.Info("ensuring wake proxy")
+
+	labels := server.WakeProxyLabels(cluster)
+
+	result, err := controllerutil.CreateOrUpdate(ctx, c.Client, deployment, func() error {
+		if err := controllerutil.SetControllerReference(cluster, deployment, c.Scheme); err != nil {
+			return err
+		}
+
+		deployment.Labels = labels
+		deployment.Spec = apps.DeploymentSpec{
+			Replicas: ptr.To[int32](1),
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       c.wakeProxyPodSpec(cluster),
+			},
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if result != controllerutil.OperationResultNone {
+		log.Info("wake proxy updated", "key", client.ObjectKeyFromObject(deployment), "result", result)
+	}
+
+	return true, nil
+}
+
+func (c *ClusterReconciler) wakeProxyPodSpec(cluster *v1alpha1.Cluster) v1.PodSpec {
+	wakeUpURL := activity.Endpoint(c.ActivityURL, activity.WakeUpPath, types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace})
+
+	// the proxy has no readiness probe, as any connection wakes the cluster up
+	return v1.PodSpec{
+		AutomountServiceAccountToken: ptr.To(false),
+		NodeSelector:                 cluster.Spec.NodeSelector,
+		PriorityClassName:            cluster.Spec.PriorityClass,
+		Containers: []v1.Container{
+			{
+				Name:            wakeProxyRole,
+				Image:           c.ControllerImage,
+				ImagePullPolicy: v1.PullPolicy(c.ControllerImagePullPolicy),
+				Command:         []string{"k3k", "wake-proxy"},
+				Args: []string{
+					"--url=" + wakeUpURL,
+					"--port=" + strconv.Itoa(wakeProxyPort),
+				},
+				Env: []v1.EnvVar{
+					{
+						Name: "TOKEN",
+						ValueFrom: &v1.EnvVarSource{
+							SecretKeyRef: &v1.SecretKeySelector{
+								LocalObjectReference: v1.LocalObjectReference{Name: activitySecretName(cluster.Name)},
+								Key:                  "token",
+							},
+						},
+					},
+					{
+						Name: "CA_CERT",
+						ValueFrom: &v1.EnvVarSource{
+							SecretKeyRef: &v1.SecretKeySelector{
+								LocalObjectReference: v1.LocalObjectReference{Name: activitySecretName(cluster.Name)},
+								Key:                  v1.ServiceAccountRootCAKey,
+							},
+						},
+					},
+				},
+				Ports: []v1.ContainerPort{
+					{
+						Name:          "https",
+						ContainerPort: wakeProxyPort,
+						Protocol:      v1.ProtocolTCP,
+					},
+				},
+			},
+		},
+	}
+}
+
+func activitySecretName(clusterName string) string {
+	return controller.SafeConcatNameWithPrefix(clusterName, "activity")
+}
@@ -0,0 +1,295 @@
+package cluster_test
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	k3kcontroller "github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster"
+	"github.com/rancher/k3k/pkg/controller/cluster/server"
+	"github.com/rancher/k3k/pkg/controller/policy"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster Controller", Label("controller"), Label("Cluster"), Label("Sleep"), func() {
+	Context("putting an idle Cluster to sleep", func() {
+		var (
+			namespace string
+			ctx       context.Context
+			vcp       *v1alpha1.VirtualClusterPolicy
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			vcp = &v1alpha1.VirtualClusterPolicy{
+				ObjectMeta: metav1.ObjectMeta{GenerateName: "policy-"},
+				Spec: v1alpha1.VirtualClusterPolicySpec{
+					AutoSleep: &v1alpha1.AutoSleepConfig{
+						IdleTimeout:   metav1.Duration{Duration: time.Second},
+						WakeOnRequest: true,
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, vcp)
+			Expect(err).To(Not(HaveOccurred()))
+
+			createdNS := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "ns-",
+					Labels:       map[string]string{policy.PolicyNameLabelKey: vcp.Name},
+				},
+			}
+			err = k8sClient.Create(ctx, createdNS)
+			Expect(err).To(Not(HaveOccurred()))
+
+			namespace = createdNS.Name
+		})
+
+		serverReplicas := func(virtualCluster *v1alpha1.Cluster) func() *int32 {
+			return func() *int32 {
+				var statefulSet appsv1.StatefulSet
+
+				key := client.ObjectKey{
+					Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, "server"),
+					Namespace: namespace,
+				}
+
+				if err := k8sClient.Get(ctx, key, &statefulSet); err != nil {
+					return nil
+				}
+
+				return statefulSet.Spec.Replicas
+			}
+		}
+
+		serviceSelector := func(virtualCluster *v1alpha1.Cluster) func() map[string]string {
+			return func() map[string]string {
+				var service corev1.Service
+
+				key := client.ObjectKey{Name: server.ServiceName(virtualCluster.Name), Namespace: namespace}
+				if err := k8sClient.Get(ctx, key, &service); err != nil {
+					return nil
+				}
+
+				return service.Spec.Selector
+			}
+		}
+
+		It("will scale down the servers and run the wake proxy until it is woken up", func() {
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return virtualCluster.Status.Sleeping
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(BeTrue())
+
+			Expect(virtualCluster.Status.LastActivityTime).To(Not(BeNil()))
+
+			Eventually(serverReplicas(virtualCluster)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(ptr.To[int32](0)))
+
+			wakeProxyKey := client.ObjectKey{
+				Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, "wake-proxy"),
+				Namespace: namespace,
+			}
+
+			var wakeProxy appsv1.Deployment
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, wakeProxyKey, &wakeProxy)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			container := wakeProxy.Spec.Template.Spec.Containers[0]
+			Expect(container.Image).To(Equal("rancher/k3k:latest"))
+			Expect(container.Command).To(Equal([]string{"k3k", "wake-proxy"}))
+			Expect(container.Args).To(ContainElement("--url=https://k3k-webhook.default.svc:8082/wakeup/" + namespace + "/" + virtualCluster.Name))
+
+			Eventually(serviceSelector(virtualCluster)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(server.WakeProxyLabels(virtualCluster)))
+
+			Eventually(func() v1alpha1.ClusterPhase {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return virtualCluster.Status.Phase
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(v1alpha1.ClusterSuspended))
+
+			By("waking the cluster up")
+
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(vcp), vcp); err != nil {
+					return err
+				}
+
+				vcp.Spec.AutoSleep.IdleTimeout = metav1.Duration{Duration: time.Hour}
+
+				return k8sClient.Update(ctx, vcp)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster); err != nil {
+					return err
+				}
+
+				if virtualCluster.Annotations == nil {
+					virtualCluster.Annotations = map[string]string{}
+				}
+
+				virtualCluster.Annotations[cluster.WakeUpAnnotation] = "true"
+
+				return k8sClient.Update(ctx, virtualCluster)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return virtualCluster.Status.Sleeping
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(BeFalse())
+
+			Eventually(serverReplicas(virtualCluster)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(ptr.To[int32](1)))
+
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, wakeProxyKey, &appsv1.Deployment{})
+				return apierrors.IsNotFound(err)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(BeTrue())
+
+			Eventually(serviceSelector(virtualCluster)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(HaveKeyWithValue("role", "server"))
+		})
+
+		It("will not track the activity of a cluster with its own audit policy", func() {
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					ServerArgs: []string{"--kube-apiserver-arg", "audit-policy-file=/etc/audit.yaml"},
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(func() *metav1.Condition {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return meta.FindStatusCondition(virtualCluster.Status.Conditions, cluster.ConditionActivityTracked)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(And(
+					Not(BeNil()),
+					HaveField("Status", metav1.ConditionFalse),
+					HaveField("Reason", cluster.ReasonAuditConflict),
+				))
+
+			Expect(virtualCluster.Status.Sleeping).To(BeFalse())
+			Expect(virtualCluster.Status.LastActivityTime).To(BeNil())
+		})
+	})
+
+	Context("creating a Cluster without automatic sleep", func() {
+		It("will not track the activity of the cluster", func() {
+			ctx := context.Background()
+
+			createdNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "ns-"}}
+			err := k8sClient.Create(ctx, createdNS)
+			Expect(err).To(Not(HaveOccurred()))
+
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    createdNS.Name,
+				},
+			}
+
+			err = k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			var statefulSet appsv1.StatefulSet
+
+			key := client.ObjectKey{
+				Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, "server"),
+				Namespace: createdNS.Name,
+			}
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, key, &statefulSet)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].Args).To(Not(ContainElement(ContainSubstring("audit-webhook"))))
+
+			activitySecretKey := client.ObjectKey{
+				Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, "activity"),
+				Namespace: createdNS.Name,
+			}
+
+			err = k8sClient.Get(ctx, activitySecretKey, &corev1.Secret{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(virtualCluster.Status.LastActivityTime).To(BeNil())
+		})
+	})
+})
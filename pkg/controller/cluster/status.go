@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"errors"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/api/meta"
 
@@ -16,14 +17,77 @@ const (
 	// Condition Types
 	ConditionReady = "Ready"
 
+	// Condition Types of the components of the cluster
+	ConditionDatastoreReady  = "DatastoreReady"
+	ConditionServersReady    = "ServersReady"
+	ConditionAgentsReady     = "AgentsReady"
+	ConditionBootstrapReady  = "BootstrapReady"
+	ConditionKubeconfigReady = "KubeconfigReady"
+	ConditionPolicyCompliant = "PolicyCompliant"
+	ConditionSyncHealthy     = "SyncHealthy"
+
 	// Condition Reasons
 	ReasonValidationFailed   = "ValidationFailed"
+	ReasonVolumesUnavailable = "VolumesUnavailable"
 	ReasonProvisioning       = "Provisioning"
 	ReasonProvisioned        = "Provisioned"
 	ReasonProvisioningFailed = "ProvisioningFailed"
+	ReasonDegraded           = "Degraded"
 	ReasonTerminating        = "Terminating"
+	ReasonSuspended          = "Suspended"
+	ReasonSleeping           = "Sleeping"
+
+	// Condition Reasons of the components of the cluster
+	ReasonComponentReady    = "ComponentReady"
+	ReasonComponentNotReady = "ComponentNotReady"
+	ReasonCompliant         = "Compliant"
+	ReasonPolicyViolation   = "PolicyViolation"
+	ReasonNoPolicy          = "NoPolicy"
 )
 
+// componentConditions returns the conditions of the components of the cluster, all true when the cluster is ready.
+// The k3k-kubelet only syncs the resources of the clusters in shared mode.
+func componentConditions(cluster *v1alpha1.Cluster) []string {
+	conditions := []string{
+		ConditionDatastoreReady,
+		ConditionServersReady,
+		ConditionAgentsReady,
+		ConditionBootstrapReady,
+		ConditionKubeconfigReady,
+	}
+
+	if cluster.Spec.Mode != v1alpha1.VirtualClusterMode {
+		conditions = append(conditions, ConditionSyncHealthy)
+	}
+
+	return conditions
+}
+
+// setPolicyCompliant sets the PolicyCompliant condition, false when the cluster is rejected by its policy
+func setPolicyCompliant(cluster *v1alpha1.Cluster, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		Type:    ConditionPolicyCompliant,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// setComponentCondition sets the condition of a component of the cluster
+func setComponentCondition(cluster *v1alpha1.Cluster, conditionType string, ready bool, message string) {
+	status, reason := metav1.ConditionTrue, ReasonComponentReady
+	if !ready {
+		status, reason = metav1.ConditionFalse, ReasonComponentNotReady
+	}
+
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		Type:    conditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
 func (c *ClusterReconciler) updateStatus(cluster *v1alpha1.Cluster, reconcileErr error) {
 	if !cluster.DeletionTimestamp.IsZero() {
 		cluster.Status.Phase = v1alpha1.ClusterTerminating
@@ -52,15 +116,28 @@ func (c *ClusterReconciler) updateStatus(cluster *v1alpha1.Cluster, reconcileErr
 		return
 	}
 
-	if errors.Is(reconcileErr, bootstrap.ErrServerNotReady) {
-		cluster.Status.Phase = v1alpha1.ClusterProvisioning
+	// the servers keep running while their StatefulSet is recreated, the progress is reported by the Resizing condition
+	if errors.Is(reconcileErr, ErrRecreatingServers) {
+		return
+	}
+
+	// the servers wait for their pre-provisioned volumes
+	if errors.Is(reconcileErr, ErrVolumesUnavailable) {
+		cluster.Status.Phase = v1alpha1.ClusterPending
 		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
 			Type:    ConditionReady,
 			Status:  metav1.ConditionFalse,
-			Reason:  ReasonProvisioning,
+			Reason:  ReasonVolumesUnavailable,
 			Message: reconcileErr.Error(),
 		})
 
+		c.Eventf(cluster, v1.EventTypeWarning, ReasonVolumesUnavailable, reconcileErr.Error())
+
+		return
+	}
+
+	if errors.Is(reconcileErr, bootstrap.ErrServerNotReady) {
+		c.setNotReady(cluster, reconcileErr.Error())
 		return
 	}
 
@@ -79,6 +156,50 @@ func (c *ClusterReconciler) updateStatus(cluster *v1alpha1.Cluster, reconcileErr
 		return
 	}
 
+	if cluster.Spec.Suspend {
+		// Only emit event on transition to Suspended
+		if cluster.Status.Phase != v1alpha1.ClusterSuspended {
+			c.Eventf(cluster, v1.EventTypeNormal, ReasonSuspended, "Cluster suspended")
+		}
+
+		cluster.Status.Phase = v1alpha1.ClusterSuspended
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			Type:    ConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonSuspended,
+			Message: "Cluster is suspended",
+		})
+
+		return
+	}
+
+	// the events of the sleeping clusters are emitted when they are put to sleep and woken up
+	if cluster.Status.Sleeping {
+		cluster.Status.Phase = v1alpha1.ClusterSuspended
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			Type:    ConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonSleeping,
+			Message: "Cluster is sleeping after being idle",
+		})
+
+		return
+	}
+
+	// the cluster is provisioned, and ready once all its components are
+	var notReady []string
+
+	for _, conditionType := range componentConditions(cluster) {
+		if !meta.IsStatusConditionTrue(cluster.Status.Conditions, conditionType) {
+			notReady = append(notReady, conditionType)
+		}
+	}
+
+	if len(notReady) > 0 {
+		c.setNotReady(cluster, "waiting for "+strings.Join(notReady, ", "))
+		return
+	}
+
 	// If we reach here, everything is successful.
 	cluster.Status.Phase = v1alpha1.ClusterReady
 	newCondition := metav1.Condition{
@@ -93,5 +214,39 @@ func (c *ClusterReconciler) updateStatus(cluster *v1alpha1.Cluster, reconcileErr
 		c.Eventf(cluster, v1.EventTypeNormal, ReasonProvisioned, newCondition.Message)
 	}
 
+	if cluster.Status.ProvisionedTime == nil {
+		now := metav1.Now()
+		cluster.Status.ProvisionedTime = &now
+	}
+
 	meta.SetStatusCondition(&cluster.Status.Conditions, newCondition)
 }
+
+// setNotReady sets the Ready condition of a cluster with components not ready. The clusters that have never been
+// ready are provisioning, the others are degraded.
+func (c *ClusterReconciler) setNotReady(cluster *v1alpha1.Cluster, message string) {
+	if cluster.Status.ProvisionedTime == nil {
+		cluster.Status.Phase = v1alpha1.ClusterProvisioning
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			Type:    ConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonProvisioning,
+			Message: message,
+		})
+
+		return
+	}
+
+	// Only emit event on transition to Degraded
+	if cluster.Status.Phase != v1alpha1.ClusterDegraded {
+		c.Eventf(cluster, v1.EventTypeWarning, ReasonDegraded, message)
+	}
+
+	cluster.Status.Phase = v1alpha1.ClusterDegraded
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		Type:    ConditionReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonDegraded,
+		Message: message,
+	})
+}
@@ -0,0 +1,117 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rancher/k3k/k3k-kubelet/translate"
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster/agent"
+	"github.com/rancher/k3k/pkg/controller/cluster/server"
+)
+
+// suspend scales down the servers and the agents of the cluster, and deletes the pods of its workloads from the host
+// cluster. The PersistentVolumeClaims of the servers are retained: the virtual pods are still stored in the datastore
+// of the cluster, and the k3k-kubelet creates their host pods again when the cluster is resumed.
+func (c *ClusterReconciler) suspend(ctx context.Context, cluster *v1alpha1.Cluster, s *server.Server) error {
+	log := ctrl.LoggerFrom(ctx)
+	log.Info("suspending cluster")
+
+	if cluster.Spec.Persistence.Type == v1alpha1.EphemeralPersistenceMode {
+		return fmt.Errorf("%w: a cluster with ephemeral persistence can't be suspended", ErrClusterValidation)
+	}
+
+	if err := c.server(ctx, cluster, s); err != nil {
+		return err
+	}
+
+	if cluster.Spec.Mode == v1alpha1.VirtualClusterMode {
+		return c.scaleDownVirtualAgents(ctx, cluster)
+	}
+
+	// the k3k-kubelet is stopped first, so that the deleted pods are not created again
+	sharedAgent := &apps.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controller.SafeConcatNameWithPrefix(cluster.Name, agent.SharedNodeAgentName),
+			Namespace: cluster.Namespace,
+		},
+	}
+
+	if err := c.Client.Delete(ctx, sharedAgent); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	return c.deleteWorkloadPods(ctx, cluster)
+}
+
+func (c *ClusterReconciler) scaleDownVirtualAgents(ctx context.Context, cluster *v1alpha1.Cluster) error {
+	var deployment apps.Deployment
+
+	key := client.ObjectKey{Name: agent.VirtualAgentName(cluster.Name), Namespace: cluster.Namespace}
+	if err := c.Client.Get(ctx, key, &deployment); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if ptr.Deref(deployment.Spec.Replicas, 1) == 0 {
+		return nil
+	}
+
+	deployment.Spec.Replicas = ptr.To[int32](0)
+
+	return c.Client.Update(ctx, &deployment)
+}
+
+// deleteWorkloadPods deletes the host pods of the workloads of a cluster in shared mode, from the namespace of the
+// cluster and from its dedicated host namespaces
+func (c *ClusterReconciler) deleteWorkloadPods(ctx context.Context, cluster *v1alpha1.Cluster) error {
+	hostNamespaces, err := c.hostNamespaces(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	namespaces := []string{cluster.Namespace}
+	for _, namespace := range hostNamespaces {
+		namespaces = append(namespaces, namespace.Name)
+	}
+
+	var deleted int
+
+	for _, namespace := range namespaces {
+		var pods v1.PodList
+		if listErr := c.Client.List(ctx, &pods, client.InNamespace(namespace), client.MatchingLabels{translate.ClusterNameLabel: cluster.Name}); listErr != nil {
+			err = errors.Join(err, listErr)
+			continue
+		}
+
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if !pod.DeletionTimestamp.IsZero() {
+				continue
+			}
+
+			if deleteErr := c.Client.Delete(ctx, pod); deleteErr != nil && !apierrors.IsNotFound(deleteErr) {
+				err = errors.Join(err, deleteErr)
+				continue
+			}
+
+			deleted++
+		}
+	}
+
+	if deleted > 0 {
+		c.Eventf(cluster, v1.EventTypeNormal, ReasonSuspended, "stopped %d pods of the workloads of the cluster", deleted)
+	}
+
+	return err
+}
@@ -0,0 +1,230 @@
+package cluster_test
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/k3k-kubelet/translate"
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	k3kcontroller "github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster/agent"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster Controller", Label("controller"), Label("Cluster"), Label("Suspend"), func() {
+	Context("suspending a Cluster", func() {
+		var (
+			namespace string
+			ctx       context.Context
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			createdNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "ns-"}}
+			err := k8sClient.Create(context.Background(), createdNS)
+			Expect(err).To(Not(HaveOccurred()))
+
+			namespace = createdNS.Name
+		})
+
+		setSuspend := func(virtualCluster *v1alpha1.Cluster, suspend bool) {
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster); err != nil {
+					return err
+				}
+
+				virtualCluster.Spec.Suspend = suspend
+
+				return k8sClient.Update(ctx, virtualCluster)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+		}
+
+		serverReplicas := func(virtualCluster *v1alpha1.Cluster) func() *int32 {
+			return func() *int32 {
+				var statefulSet appsv1.StatefulSet
+
+				key := client.ObjectKey{
+					Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, "server"),
+					Namespace: namespace,
+				}
+
+				if err := k8sClient.Get(ctx, key, &statefulSet); err != nil {
+					return nil
+				}
+
+				return statefulSet.Spec.Replicas
+			}
+		}
+
+		It("will scale down the servers, stop the shared agent and delete the pods of the workloads", func() {
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			sharedAgentKey := client.ObjectKey{
+				Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, agent.SharedNodeAgentName),
+				Namespace: namespace,
+			}
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, sharedAgentKey, &appsv1.DaemonSet{})
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			workloadPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "workload-",
+					Namespace:    namespace,
+					Labels:       map[string]string{translate.ClusterNameLabel: virtualCluster.Name},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}},
+				},
+			}
+
+			err = k8sClient.Create(ctx, workloadPod)
+			Expect(err).To(Not(HaveOccurred()))
+
+			setSuspend(virtualCluster, true)
+
+			Eventually(serverReplicas(virtualCluster)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(ptr.To[int32](0)))
+
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, sharedAgentKey, &appsv1.DaemonSet{})
+				return apierrors.IsNotFound(err)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(BeTrue())
+
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(workloadPod), &corev1.Pod{})
+				return apierrors.IsNotFound(err)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(BeTrue())
+
+			Eventually(func() v1alpha1.ClusterPhase {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return virtualCluster.Status.Phase
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(v1alpha1.ClusterSuspended))
+
+			By("resuming the cluster")
+
+			setSuspend(virtualCluster, false)
+
+			Eventually(serverReplicas(virtualCluster)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(ptr.To[int32](1)))
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, sharedAgentKey, &appsv1.DaemonSet{})
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+		})
+
+		It("will scale down the agents in virtual mode", func() {
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					Mode:   v1alpha1.VirtualClusterMode,
+					Agents: ptr.To[int32](2),
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			agentReplicas := func() *int32 {
+				var deployment appsv1.Deployment
+
+				key := client.ObjectKey{Name: agent.VirtualAgentName(virtualCluster.Name), Namespace: namespace}
+				if err := k8sClient.Get(ctx, key, &deployment); err != nil {
+					return nil
+				}
+
+				return deployment.Spec.Replicas
+			}
+
+			Eventually(agentReplicas).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(ptr.To[int32](2)))
+
+			setSuspend(virtualCluster, true)
+
+			Eventually(agentReplicas).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(ptr.To[int32](0)))
+
+			Eventually(serverReplicas(virtualCluster)).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(ptr.To[int32](0)))
+		})
+
+		It("will not suspend a cluster with ephemeral persistence", func() {
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					Persistence: v1alpha1.PersistenceConfig{Type: v1alpha1.EphemeralPersistenceMode},
+					Suspend:     true,
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(func() v1alpha1.ClusterPhase {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return virtualCluster.Status.Phase
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(v1alpha1.ClusterPending))
+		})
+	})
+})
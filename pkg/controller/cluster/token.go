@@ -62,9 +62,9 @@ func (c *ClusterReconciler) ensureTokenSecret(ctx context.Context, cluster *v1al
 		return string(tokenSecret.Data["token"]), nil
 	}
 
-	log.Info("Token secret is not specified, creating a random token")
+	log.Info("Token secret is not specified, creating a token")
 
-	token, err := random(16)
+	token, err := c.newToken(ctx, cluster)
 	if err != nil {
 		return "", err
 	}
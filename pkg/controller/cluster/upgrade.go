@@ -0,0 +1,335 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster/agent"
+)
+
+const (
+	// ConditionUpgrading is the condition of a cluster upgraded to a new K3s version
+	ConditionUpgrading = "Upgrading"
+
+	ReasonUpgradingServers = "UpgradingServers"
+	ReasonUpgradingAgents  = "UpgradingAgents"
+	ReasonUpgraded         = "Upgraded"
+	ReasonUpgradeRejected  = "UpgradeRejected"
+	ReasonUpgradePaused    = "UpgradePaused"
+
+	upgradeCheckInterval = 10 * time.Second
+)
+
+// upgradeFailureReasons are the reasons of the waiting containers of an upgraded server pausing the upgrade
+var upgradeFailureReasons = []string{"CrashLoopBackOff", "ErrImagePull", "ImagePullBackOff", "CreateContainerConfigError"}
+
+// upgrading returns true if the servers or the agents of the cluster are upgraded
+func upgrading(cluster *v1alpha1.Cluster) bool {
+	if cluster.Status.CurrentVersion != cluster.Status.TargetVersion {
+		return true
+	}
+
+	return upgradeReason(cluster) == ReasonUpgradingAgents
+}
+
+// upgradeReason returns the reason of the Upgrading condition of the cluster
+func upgradeReason(cluster *v1alpha1.Cluster) string {
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionUpgrading)
+	if condition == nil {
+		return ""
+	}
+
+	return condition.Reason
+}
+
+// reconcileVersion validates the version requested for the cluster, and starts the upgrade of the servers.
+// A rejected version is not rolled out, and the cluster keeps running its current version.
+func (c *ClusterReconciler) reconcileVersion(cluster *v1alpha1.Cluster) {
+	requested := controller.K3SVersion(cluster)
+
+	// the new clusters, and the ones created before the versions were recorded, run the requested version
+	if cluster.Status.CurrentVersion == "" {
+		cluster.Status.CurrentVersion = requested
+		cluster.Status.TargetVersion = requested
+
+		return
+	}
+
+	if requested == cluster.Status.TargetVersion {
+		// the rejected version was reverted
+		if upgradeReason(cluster) == ReasonUpgradeRejected {
+			setUpgrading(cluster, metav1.ConditionFalse, ReasonUpgraded, "cluster running version "+cluster.Status.CurrentVersion)
+		}
+
+		return
+	}
+
+	// the upgrade of a suspended cluster starts when it is resumed
+	if controller.Suspended(cluster) {
+		return
+	}
+
+	if err := validateUpgrade(cluster.Status.CurrentVersion, requested); err != nil {
+		if upgradeReason(cluster) != ReasonUpgradeRejected {
+			c.Eventf(cluster, v1.EventTypeWarning, ReasonUpgradeRejected, err.Error())
+		}
+
+		setUpgrading(cluster, metav1.ConditionFalse, ReasonUpgradeRejected, err.Error())
+
+		return
+	}
+
+	cluster.Status.TargetVersion = requested
+
+	c.Eventf(cluster, v1.EventTypeNormal, ReasonUpgradingServers, "upgrading cluster from %s to %s", cluster.Status.CurrentVersion, requested)
+	setUpgrading(cluster, metav1.ConditionTrue, ReasonUpgradingServers, "upgrading servers to "+requested)
+}
+
+// validateUpgrade returns an error if the cluster can't be upgraded to the version: the downgrades are not
+// supported, and the cluster can only be upgraded to the next minor version.
+func validateUpgrade(currentVersion, targetVersion string) error {
+	current, err := version.ParseGeneric(currentVersion)
+	if err != nil {
+		// the upgrade from a version that can't be compared, e.g. "latest", can't be validated
+		return fmt.Errorf("invalid current version %q: the upgrade to %s can't be validated", currentVersion, targetVersion)
+	}
+
+	target, err := version.ParseGeneric(targetVersion)
+	if err != nil {
+		return fmt.Errorf("invalid version %q: %w", targetVersion, err)
+	}
+
+	if target.LessThan(current) {
+		return fmt.Errorf("downgrade from %s to %s is not supported", currentVersion, targetVersion)
+	}
+
+	if target.Major() != current.Major() || target.Minor() > current.Minor()+1 {
+		return fmt.Errorf("upgrade from %s to %s skips a minor version", currentVersion, targetVersion)
+	}
+
+	return nil
+}
+
+// setUpgrading sets the Upgrading condition. The condition of a rejected version is kept until the version is
+// reverted, or a valid version is requested.
+func setUpgrading(cluster *v1alpha1.Cluster, status metav1.ConditionStatus, reason, message string) {
+	if reason != ReasonUpgradeRejected && controller.K3SVersion(cluster) != cluster.Status.TargetVersion {
+		return
+	}
+
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		Type:    ConditionUpgrading,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// serverPartition returns the partition of the rolling update of the servers. During an upgrade the servers
+// are upgraded one at a time, from the last one: the next server is upgraded once the upgraded servers are
+// ready, and their apiserver and etcd are healthy. The upgrade is paused if an upgraded server fails.
+// When all the servers are upgraded the agents are upgraded to the target version.
+func (c *ClusterReconciler) serverPartition(ctx context.Context, cluster *v1alpha1.Cluster, statefulSet *apps.StatefulSet) (int32, error) {
+	if cluster.Status.CurrentVersion == cluster.Status.TargetVersion {
+		return 0, nil
+	}
+
+	var current apps.StatefulSet
+	if err := c.Client.Get(ctx, client.ObjectKeyFromObject(statefulSet), &current); err != nil {
+		if apierrors.IsNotFound(err) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	partition := int32(0)
+	if rollingUpdate := current.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil {
+		partition = ptr.Deref(rollingUpdate.Partition, 0)
+	}
+
+	// the upgrade of the servers is resumed with the cluster
+	if controller.Suspended(cluster) {
+		return partition, nil
+	}
+
+	replicas := ptr.Deref(statefulSet.Spec.Replicas, 1)
+
+	// the upgrade starts from the last server
+	if current.Spec.Template.Spec.Containers[0].Image != statefulSet.Spec.Template.Spec.Containers[0].Image {
+		return replicas - 1, nil
+	}
+
+	if current.Status.ObservedGeneration < current.Generation || current.Status.UpdateRevision == "" {
+		return partition, nil
+	}
+
+	var pods v1.PodList
+	if err := c.Client.List(ctx, &pods, client.InNamespace(cluster.Namespace), client.MatchingLabels(statefulSet.Spec.Selector.MatchLabels)); err != nil {
+		return partition, err
+	}
+
+	upgraded := make(map[int32]*v1.Pod)
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+
+		ordinal, err := strconv.Atoi(strings.TrimPrefix(pod.Name, current.Name+"-"))
+		if err != nil || pod.Labels[apps.StatefulSetRevisionLabel] != current.Status.UpdateRevision {
+			continue
+		}
+
+		upgraded[int32(ordinal)] = pod
+	}
+
+	for ordinal := partition; ordinal < replicas; ordinal++ {
+		pod, found := upgraded[ordinal]
+		if !found {
+			setUpgrading(cluster, metav1.ConditionTrue, ReasonUpgradingServers, fmt.Sprintf("upgrading server %d of %d to %s", replicas-ordinal, replicas, cluster.Status.TargetVersion))
+			return partition, nil
+		}
+
+		if reason := podFailure(pod); reason != "" {
+			message := fmt.Sprintf("upgrade paused, server %s failed: %s", pod.Name, reason)
+			if upgradeReason(cluster) != ReasonUpgradePaused {
+				c.Eventf(cluster, v1.EventTypeWarning, ReasonUpgradePaused, message)
+			}
+
+			setUpgrading(cluster, metav1.ConditionFalse, ReasonUpgradePaused, message)
+
+			return partition, nil
+		}
+
+		if !podReady(pod) {
+			return partition, nil
+		}
+
+		if err := c.serverReadyz(ctx, cluster, pod); err != nil {
+			ctrl.LoggerFrom(ctx).Info("waiting for the upgraded server to be healthy", "pod", pod.Name, "error", err.Error())
+			return partition, nil
+		}
+	}
+
+	if partition > 0 {
+		setUpgrading(cluster, metav1.ConditionTrue, ReasonUpgradingServers, fmt.Sprintf("upgrading server %d of %d to %s", replicas-partition+1, replicas, cluster.Status.TargetVersion))
+		return partition - 1, nil
+	}
+
+	cluster.Status.CurrentVersion = cluster.Status.TargetVersion
+
+	setUpgrading(cluster, metav1.ConditionTrue, ReasonUpgradingAgents, "upgrading agents to "+cluster.Status.TargetVersion)
+
+	return 0, nil
+}
+
+// podFailure returns the reason of the failure of a container of the pod, if any
+func podFailure(pod *v1.Pod) string {
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if status.State.Waiting != nil && slices.Contains(upgradeFailureReasons, status.State.Waiting.Reason) {
+			return status.State.Waiting.Reason
+		}
+	}
+
+	return ""
+}
+
+func podReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// serverReadyz checks the /readyz endpoint of the apiserver of the server pod, including the health of etcd
+func (c *ClusterReconciler) serverReadyz(ctx context.Context, cluster *v1alpha1.Cluster, pod *v1.Pod) error {
+	clientset, err := c.virtualClientset(ctx, cluster, net.JoinHostPort(pod.Status.PodIP, "6443"))
+	if err != nil {
+		return err
+	}
+
+	return readyz(ctx, clientset, "/readyz")
+}
+
+// reconcileAgentsUpgrade completes the upgrade when the agents run the version of the servers
+func (c *ClusterReconciler) reconcileAgentsUpgrade(ctx context.Context, cluster *v1alpha1.Cluster, serviceIP string) error {
+	if upgradeReason(cluster) != ReasonUpgradingAgents {
+		return nil
+	}
+
+	if cluster.Spec.Mode == v1alpha1.VirtualClusterMode {
+		var deployment apps.Deployment
+
+		key := types.NamespacedName{Name: agent.VirtualAgentName(cluster.Name), Namespace: cluster.Namespace}
+		if err := c.Client.Get(ctx, key, &deployment); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		replicas := ptr.Deref(deployment.Spec.Replicas, 1)
+		status := deployment.Status
+
+		if status.ObservedGeneration < deployment.Generation || status.UpdatedReplicas < replicas ||
+			status.ReadyReplicas < replicas || status.Replicas > replicas {
+			return nil
+		}
+	} else {
+		upgraded, err := c.sharedAgentsUpgraded(ctx, cluster, serviceIP)
+		if err != nil || !upgraded {
+			return err
+		}
+	}
+
+	c.Eventf(cluster, v1.EventTypeNormal, ReasonUpgraded, "cluster upgraded to %s", cluster.Status.CurrentVersion)
+	setUpgrading(cluster, metav1.ConditionFalse, ReasonUpgraded, "cluster upgraded to "+cluster.Status.CurrentVersion)
+
+	return nil
+}
+
+// sharedAgentsUpgraded returns true when the nodes of the k3k-kubelets report the version of the servers. The
+// k3k-kubelets reload the version from their configuration in shared mode. The mirrored host nodes report the
+// version of the host kubelets, and their k3k-kubelets are not waited for.
+func (c *ClusterReconciler) sharedAgentsUpgraded(ctx context.Context, cluster *v1alpha1.Cluster, serviceIP string) (bool, error) {
+	if cluster.Spec.MirrorHostNodes {
+		return true, nil
+	}
+
+	clientset, err := c.virtualClientset(ctx, cluster, net.JoinHostPort(serviceIP, "443"))
+	if err != nil {
+		return false, err
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	for _, node := range nodes.Items {
+		if node.Status.NodeInfo.KubeletVersion != cluster.Status.CurrentVersion {
+			ctrl.LoggerFrom(ctx).Info("waiting for the k3k-kubelet to be upgraded", "node", node.Name, "version", node.Status.NodeInfo.KubeletVersion)
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,148 @@
+package cluster_test
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	k3kcontroller "github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster Controller", Label("controller"), Label("Cluster"), Label("Upgrade"), func() {
+	Context("upgrading a Cluster", func() {
+		var (
+			namespace      string
+			ctx            context.Context
+			virtualCluster *v1alpha1.Cluster
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			createdNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "ns-"}}
+			err := k8sClient.Create(context.Background(), createdNS)
+			Expect(err).To(Not(HaveOccurred()))
+
+			namespace = createdNS.Name
+
+			virtualCluster = &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					Version: "v1.31.4-k3s1",
+				},
+			}
+
+			err = k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(func() string {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return virtualCluster.Status.CurrentVersion
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal("v1.31.4-k3s1"))
+
+			Expect(virtualCluster.Status.TargetVersion).To(Equal("v1.31.4-k3s1"))
+		})
+
+		updateVersion := func(version string) {
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster); err != nil {
+					return err
+				}
+
+				virtualCluster.Spec.Version = version
+
+				return k8sClient.Update(ctx, virtualCluster)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+		}
+
+		upgradeReason := func() string {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			condition := meta.FindStatusCondition(virtualCluster.Status.Conditions, cluster.ConditionUpgrading)
+			if condition == nil {
+				return ""
+			}
+
+			return condition.Reason
+		}
+
+		It("will reject a downgrade", func() {
+			updateVersion("v1.30.8-k3s1")
+
+			Eventually(upgradeReason).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(cluster.ReasonUpgradeRejected))
+
+			Expect(virtualCluster.Status.CurrentVersion).To(Equal("v1.31.4-k3s1"))
+			Expect(virtualCluster.Status.TargetVersion).To(Equal("v1.31.4-k3s1"))
+		})
+
+		It("will reject an upgrade skipping a minor version", func() {
+			updateVersion("v1.33.1-k3s1")
+
+			Eventually(upgradeReason).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(cluster.ReasonUpgradeRejected))
+
+			Expect(virtualCluster.Status.TargetVersion).To(Equal("v1.31.4-k3s1"))
+		})
+
+		It("will upgrade the servers to the next minor version", func() {
+			updateVersion("v1.32.1-k3s1")
+
+			Eventually(upgradeReason).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(cluster.ReasonUpgradingServers))
+
+			Expect(virtualCluster.Status.CurrentVersion).To(Equal("v1.31.4-k3s1"))
+			Expect(virtualCluster.Status.TargetVersion).To(Equal("v1.32.1-k3s1"))
+
+			var statefulSet appsv1.StatefulSet
+
+			Eventually(func() string {
+				key := client.ObjectKey{
+					Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, "server"),
+					Namespace: namespace,
+				}
+
+				err := k8sClient.Get(ctx, key, &statefulSet)
+				Expect(err).To(Not(HaveOccurred()))
+
+				return statefulSet.Spec.Template.Spec.Containers[0].Image
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(HaveSuffix(":v1.32.1-k3s1"))
+
+			Expect(statefulSet.Spec.UpdateStrategy.RollingUpdate).To(Not(BeNil()))
+			Expect(statefulSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(ptr.To[int32](0)))
+		})
+	})
+})
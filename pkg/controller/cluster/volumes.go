@@ -0,0 +1,193 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	"github.com/rancher/k3k/pkg/controller/cluster/server"
+)
+
+var ErrVolumesUnavailable = errors.New("persistent volumes unavailable")
+
+// validateVolumes validates the persistence of a cluster with pre-provisioned volumes
+func validateVolumes(cluster *v1alpha1.Cluster) error {
+	persistence := cluster.Spec.Persistence
+	if persistence.Type != v1alpha1.StaticPersistenceMode {
+		return nil
+	}
+
+	if len(persistence.VolumeNames) == 0 && persistence.VolumeSelector == nil {
+		return fmt.Errorf("%w: a cluster with static persistence needs volumeNames or a volumeSelector", ErrClusterValidation)
+	}
+
+	if _, err := metav1.LabelSelectorAsSelector(persistence.VolumeSelector); err != nil {
+		return fmt.Errorf("%w: invalid volumeSelector: %w", ErrClusterValidation, err)
+	}
+
+	return nil
+}
+
+// ensureStaticVolumes binds a pre-provisioned PersistentVolume to each server of a cluster with static persistence.
+// The claims are created with the names of the claims of the StatefulSet of the servers, which uses them instead of
+// creating new ones, and are retained like the claims of the StatefulSet.
+func (c *ClusterReconciler) ensureStaticVolumes(ctx context.Context, cluster *v1alpha1.Cluster) error {
+	if cluster.Spec.Persistence.Type != v1alpha1.StaticPersistenceMode {
+		return nil
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+
+	servers := int(*cluster.Spec.Servers)
+
+	// the volumes already bound to the servers, or selected for them
+	taken := make(map[string]bool)
+
+	var missing []int
+
+	for ordinal := range servers {
+		var claim v1.PersistentVolumeClaim
+
+		key := client.ObjectKey{Name: server.PVCName(cluster.Name, ordinal), Namespace: cluster.Namespace}
+		if err := c.Client.Get(ctx, key, &claim); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+
+			missing = append(missing, ordinal)
+
+			continue
+		}
+
+		taken[claim.Spec.VolumeName] = true
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var candidates v1.PersistentVolumeList
+
+	if selector := cluster.Spec.Persistence.VolumeSelector; len(cluster.Spec.Persistence.VolumeNames) == 0 && selector != nil {
+		labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+		if err != nil {
+			return err
+		}
+
+		// the volumes are listed in the order of their names
+		if err := c.Client.List(ctx, &candidates, client.MatchingLabelsSelector{Selector: labelSelector}); err != nil {
+			return err
+		}
+	}
+
+	unavailable := 0
+
+	for _, ordinal := range missing {
+		volume, err := c.staticVolume(ctx, cluster, ordinal, candidates.Items, taken)
+		if err != nil {
+			return err
+		}
+
+		if volume == nil {
+			unavailable++
+			continue
+		}
+
+		taken[volume.Name] = true
+
+		log.Info("binding persistent volume to server", "volume", volume.Name, "ordinal", ordinal)
+
+		if err := c.Client.Create(ctx, staticClaim(cluster, ordinal, volume)); err != nil {
+			return err
+		}
+	}
+
+	if unavailable > 0 {
+		return fmt.Errorf("%w: %d of the %d servers have no available PersistentVolume", ErrVolumesUnavailable, unavailable, servers)
+	}
+
+	return nil
+}
+
+// staticVolume returns the volume of the server with the given ordinal: the volume with the name of its ordinal, or the
+// first available candidate. It returns nil if no volume is available.
+func (c *ClusterReconciler) staticVolume(ctx context.Context, cluster *v1alpha1.Cluster, ordinal int, candidates []v1.PersistentVolume, taken map[string]bool) (*v1.PersistentVolume, error) {
+	claimName := server.PVCName(cluster.Name, ordinal)
+
+	if volumeNames := cluster.Spec.Persistence.VolumeNames; len(volumeNames) > 0 {
+		if ordinal >= len(volumeNames) || taken[volumeNames[ordinal]] {
+			return nil, nil
+		}
+
+		var volume v1.PersistentVolume
+		if err := c.Client.Get(ctx, client.ObjectKey{Name: volumeNames[ordinal]}, &volume); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+
+		if !volumeAvailable(&volume, cluster.Namespace, claimName) {
+			return nil, nil
+		}
+
+		return &volume, nil
+	}
+
+	for i := range candidates {
+		if !taken[candidates[i].Name] && volumeAvailable(&candidates[i], cluster.Namespace, claimName) {
+			return &candidates[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// volumeAvailable returns true if the volume is not claimed, or is reserved for the given claim
+func volumeAvailable(volume *v1.PersistentVolume, namespace, claimName string) bool {
+	if !volume.DeletionTimestamp.IsZero() {
+		return false
+	}
+
+	claimRef := volume.Spec.ClaimRef
+	if claimRef == nil {
+		return volume.Status.Phase != v1.VolumeReleased && volume.Status.Phase != v1.VolumeFailed
+	}
+
+	return claimRef.Namespace == namespace && claimRef.Name == claimName
+}
+
+func staticClaim(cluster *v1alpha1.Cluster, ordinal int, volume *v1.PersistentVolume) *v1.PersistentVolumeClaim {
+	accessModes := volume.Spec.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce}
+	}
+
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      server.PVCName(cluster.Name, ordinal),
+			Namespace: cluster.Namespace,
+			Labels: map[string]string{
+				"cluster": cluster.Name,
+				"role":    "server",
+			},
+		},
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes:      accessModes,
+			StorageClassName: ptr.To(volume.Spec.StorageClassName),
+			VolumeName:       volume.Name,
+			VolumeMode:       volume.Spec.VolumeMode,
+			Resources: v1.VolumeResourceRequirements{
+				Requests: v1.ResourceList{
+					v1.ResourceStorage: volume.Spec.Capacity[v1.ResourceStorage],
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,167 @@
+package cluster_test
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
+	k3kcontroller "github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster"
+	"github.com/rancher/k3k/pkg/controller/cluster/server"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster Controller", Label("controller"), Label("Cluster"), Label("Volumes"), func() {
+	Context("creating a Cluster with static persistence", func() {
+		var (
+			namespace string
+			ctx       context.Context
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			createdNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "ns-"}}
+			err := k8sClient.Create(context.Background(), createdNS)
+			Expect(err).To(Not(HaveOccurred()))
+
+			namespace = createdNS.Name
+		})
+
+		createVolume := func() string {
+			volume := &corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "pv-",
+					Labels:       map[string]string{"namespace": namespace},
+				},
+				Spec: corev1.PersistentVolumeSpec{
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+					Capacity: corev1.ResourceList{
+						corev1.ResourceStorage: resource.MustParse("10Gi"),
+					},
+					PersistentVolumeSource: corev1.PersistentVolumeSource{
+						HostPath: &corev1.HostPathVolumeSource{Path: "/var/lib/k3k/" + namespace},
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, volume)
+			Expect(err).To(Not(HaveOccurred()))
+
+			DeferCleanup(k8sClient.Delete, context.Background(), volume)
+
+			return volume.Name
+		}
+
+		serverClaimVolume := func(virtualCluster *v1alpha1.Cluster, ordinal int) func() string {
+			return func() string {
+				var claim corev1.PersistentVolumeClaim
+
+				key := client.ObjectKey{Name: server.PVCName(virtualCluster.Name, ordinal), Namespace: namespace}
+				if err := k8sClient.Get(ctx, key, &claim); err != nil {
+					return ""
+				}
+
+				return claim.Spec.VolumeName
+			}
+		}
+
+		It("will bind the named volumes to the servers", func() {
+			volumeNames := []string{createVolume(), createVolume()}
+
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					Servers: ptr.To[int32](2),
+					Persistence: v1alpha1.PersistenceConfig{
+						Type:        v1alpha1.StaticPersistenceMode,
+						VolumeNames: volumeNames,
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			for ordinal, volumeName := range volumeNames {
+				Eventually(serverClaimVolume(virtualCluster, ordinal)).
+					WithTimeout(time.Second * 30).
+					WithPolling(time.Second).
+					Should(Equal(volumeName))
+			}
+
+			var statefulSet appsv1.StatefulSet
+
+			key := client.ObjectKey{
+				Name:      k3kcontroller.SafeConcatNameWithPrefix(virtualCluster.Name, "server"),
+				Namespace: namespace,
+			}
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, key, &statefulSet)
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Succeed())
+
+			Expect(statefulSet.Spec.VolumeClaimTemplates).To(HaveLen(1))
+			Expect(statefulSet.Spec.VolumeClaimTemplates[0].Spec.StorageClassName).To(Equal(ptr.To("")))
+		})
+
+		It("will be pending when not enough volumes are selected", func() {
+			volumeName := createVolume()
+
+			virtualCluster := &v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "cluster-",
+					Namespace:    namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					Servers: ptr.To[int32](2),
+					Persistence: v1alpha1.PersistenceConfig{
+						Type: v1alpha1.StaticPersistenceMode,
+						VolumeSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"namespace": namespace},
+						},
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, virtualCluster)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Eventually(func() string {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualCluster), virtualCluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				condition := meta.FindStatusCondition(virtualCluster.Status.Conditions, cluster.ConditionReady)
+				if condition == nil {
+					return ""
+				}
+
+				return condition.Reason
+			}).
+				WithTimeout(time.Second * 30).
+				WithPolling(time.Second).
+				Should(Equal(cluster.ReasonVolumesUnavailable))
+
+			Expect(virtualCluster.Status.Phase).To(Equal(v1alpha1.ClusterPending))
+			Expect(serverClaimVolume(virtualCluster, 0)()).To(Equal(volumeName))
+			Expect(serverClaimVolume(virtualCluster, 1)()).To(BeEmpty())
+		})
+	})
+})
@@ -25,21 +25,62 @@ var Backoff = wait.Backoff{
 	Jitter:   0.1,
 }
 
-// Image returns the rancher/k3s image tagged with the specified Version.
-// If Version is empty it will use with the same k8s version of the host cluster,
-// stored in the Status object. It will return the latest version as last fallback.
+// Image returns the rancher/k3s image of the servers, tagged with their ServerVersion.
+// It will return the latest version as last fallback.
 func K3SImage(cluster *v1alpha1.Cluster, k3SImage string) string {
-	image := k3SImage
+	return imageWithVersion(k3SImage, ServerVersion(cluster))
+}
 
-	imageVersion := "latest"
+// K3SAgentImage returns the rancher/k3s image of the agents, tagged with their AgentVersion.
+func K3SAgentImage(cluster *v1alpha1.Cluster, k3SImage string) string {
+	return imageWithVersion(k3SImage, AgentVersion(cluster))
+}
 
+func imageWithVersion(image, version string) string {
+	if version == "" {
+		version = "latest"
+	}
+
+	return image + ":" + version
+}
+
+// K3SVersion returns the requested K3s version of the cluster. If Version is empty it will use
+// the same k8s version of the host cluster, stored in the Status object.
+func K3SVersion(cluster *v1alpha1.Cluster) string {
 	if cluster.Spec.Version != "" {
-		imageVersion = cluster.Spec.Version
-	} else if cluster.Status.HostVersion != "" {
-		imageVersion = cluster.Status.HostVersion
+		return cluster.Spec.Version
+	}
+
+	return cluster.Status.HostVersion
+}
+
+// ServerVersion returns the version of the servers, the target version of an upgrade in progress
+func ServerVersion(cluster *v1alpha1.Cluster) string {
+	if cluster.Status.TargetVersion != "" {
+		return cluster.Status.TargetVersion
+	}
+
+	return K3SVersion(cluster)
+}
+
+// AgentVersion returns the version of the agents, that are upgraded once all the servers run the target version
+func AgentVersion(cluster *v1alpha1.Cluster) string {
+	if cluster.Status.CurrentVersion != "" {
+		return cluster.Status.CurrentVersion
 	}
 
-	return image + ":" + imageVersion
+	return K3SVersion(cluster)
+}
+
+// Suspended returns true if the cluster is suspended, or sleeping after being idle
+func Suspended(cluster *v1alpha1.Cluster) bool {
+	return cluster.Spec.Suspend || cluster.Status.Sleeping
+}
+
+// ExternalDatastore returns true if the cluster stores its data in an external datastore, or in SQLite,
+// instead of the embedded etcd
+func ExternalDatastore(cluster *v1alpha1.Cluster) bool {
+	return cluster.Spec.Persistence.Type == v1alpha1.ExternalPersistenceMode
 }
 
 // SafeConcatNameWithPrefix runs the SafeConcatName with extra prefix.
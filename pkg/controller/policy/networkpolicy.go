@@ -11,11 +11,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 
+	"github.com/rancher/k3k/k3k-kubelet/translate"
 	"github.com/rancher/k3k/pkg/apis/k3k.io/v1alpha1"
 	k3kcontroller "github.com/rancher/k3k/pkg/controller"
+	"github.com/rancher/k3k/pkg/controller/cluster/activity"
 )
 
-func (c *VirtualClusterPolicyReconciler) reconcileNetworkPolicy(ctx context.Context, namespace string, policy *v1alpha1.VirtualClusterPolicy) error {
+func (c *VirtualClusterPolicyReconciler) reconcileNetworkPolicy(ctx context.Context, namespace *v1.Namespace, policy *v1alpha1.VirtualClusterPolicy) error {
 	log := ctrl.LoggerFrom(ctx)
 	log.Info("reconciling NetworkPolicy")
 
@@ -40,6 +42,16 @@ func (c *VirtualClusterPolicyReconciler) reconcileNetworkPolicy(ctx context.Cont
 
 	networkPolicy := networkPolicy(namespace, policy, cidrList)
 
+	// the servers send their audit events to the activity tracker of the controller
+	if c.ActivityURL != "" {
+		activityRule, err := activity.EgressRule(c.ActivityURL, c.ControllerNamespace)
+		if err != nil {
+			return err
+		}
+
+		networkPolicy.Spec.Egress = append(networkPolicy.Spec.Egress, activityRule)
+	}
+
 	if err := ctrl.SetControllerReference(policy, networkPolicy, c.Scheme); err != nil {
 		return err
 	}
@@ -59,8 +71,10 @@ func (c *VirtualClusterPolicyReconciler) reconcileNetworkPolicy(ctx context.Cont
 	return err
 }
 
-func networkPolicy(namespaceName string, policy *v1alpha1.VirtualClusterPolicy, cidrList []string) *networkingv1.NetworkPolicy {
-	return &networkingv1.NetworkPolicy{
+func networkPolicy(namespace *v1.Namespace, policy *v1alpha1.VirtualClusterPolicy, cidrList []string) *networkingv1.NetworkPolicy {
+	namespaceName := namespace.Name
+
+	netpol := &networkingv1.NetworkPolicy{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "NetworkPolicy",
 			APIVersion: "networking.k8s.io/v1",
@@ -114,4 +128,36 @@ func networkPolicy(namespaceName string, policy *v1alpha1.VirtualClusterPolicy,
 			},
 		},
 	}
+
+	netpol.Spec.Egress[0].To = append(netpol.Spec.Egress[0].To, hostNamespacesPeers(namespace)...)
+
+	return netpol
+}
+
+// hostNamespacesPeers returns the peers allowing the traffic between the namespace of the virtual clusters
+// and the dedicated host namespaces of their virtual namespaces
+func hostNamespacesPeers(namespace *v1.Namespace) []networkingv1.NetworkPolicyPeer {
+	clustersNamespace := namespace.Name
+
+	var peers []networkingv1.NetworkPolicyPeer
+
+	if parent := namespace.Labels[translate.ClusterNamespaceLabel]; parent != "" {
+		clustersNamespace = parent
+
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"kubernetes.io/metadata.name": parent,
+				},
+			},
+		})
+	}
+
+	return append(peers, networkingv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				translate.ClusterNamespaceLabel: clustersNamespace,
+			},
+		},
+	})
 }
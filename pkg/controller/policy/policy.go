@@ -35,14 +35,19 @@ type VirtualClusterPolicyReconciler struct {
 	Client      client.Client
 	Scheme      *runtime.Scheme
 	ClusterCIDR string
+	ActivityURL string
+	// ControllerNamespace is the namespace of the controller, reached by the clusters sending their activity
+	ControllerNamespace string
 }
 
 // Add the controller to manage the Virtual Cluster policies
-func Add(mgr manager.Manager, clusterCIDR string, maxConcurrentReconciles int) error {
+func Add(mgr manager.Manager, clusterCIDR, activityURL, controllerNamespace string, maxConcurrentReconciles int) error {
 	reconciler := VirtualClusterPolicyReconciler{
-		Client:      mgr.GetClient(),
-		Scheme:      mgr.GetScheme(),
-		ClusterCIDR: clusterCIDR,
+		Client:              mgr.GetClient(),
+		Scheme:              mgr.GetScheme(),
+		ClusterCIDR:         clusterCIDR,
+		ActivityURL:         activityURL,
+		ControllerNamespace: controllerNamespace,
 	}
 
 	return ctrl.NewControllerManagedBy(mgr).
@@ -186,8 +191,10 @@ func nodeEventHandler(r *VirtualClusterPolicyReconciler) handler.Funcs {
 // clusterEventHandler will enqueue a reconciliation of the VCP associated to the Namespace when a Cluster changes.
 func clusterEventHandler(r *VirtualClusterPolicyReconciler) handler.Funcs {
 	type clusterSubSpec struct {
-		PriorityClass string
-		NodeSelector  map[string]string
+		PriorityClass    string
+		NodeSelector     map[string]string
+		RuntimeClassName *string
+		NodeTaints       []v1.Taint
 	}
 
 	return handler.Funcs{
@@ -227,13 +234,17 @@ func clusterEventHandler(r *VirtualClusterPolicyReconciler) handler.Funcs {
 			}
 
 			clusterSubSpecOld := clusterSubSpec{
-				PriorityClass: oldCluster.Spec.PriorityClass,
-				NodeSelector:  oldCluster.Spec.NodeSelector,
+				PriorityClass:    oldCluster.Spec.PriorityClass,
+				NodeSelector:     oldCluster.Spec.NodeSelector,
+				RuntimeClassName: oldCluster.Spec.RuntimeClassName,
+				NodeTaints:       oldCluster.Spec.NodeTaints,
 			}
 
 			clusterSubSpecNew := clusterSubSpec{
-				PriorityClass: newCluster.Spec.PriorityClass,
-				NodeSelector:  newCluster.Spec.NodeSelector,
+				PriorityClass:    newCluster.Spec.PriorityClass,
+				NodeSelector:     newCluster.Spec.NodeSelector,
+				RuntimeClassName: newCluster.Spec.RuntimeClassName,
+				NodeTaints:       newCluster.Spec.NodeTaints,
 			}
 
 			if !reflect.DeepEqual(clusterSubSpecOld, clusterSubSpecNew) {
@@ -312,7 +323,7 @@ func (c *VirtualClusterPolicyReconciler) reconcileMatchingNamespaces(ctx context
 
 		orig := ns.DeepCopy()
 
-		if err := c.reconcileNetworkPolicy(ctx, ns.Name, policy); err != nil {
+		if err := c.reconcileNetworkPolicy(ctx, &ns, policy); err != nil {
 			return err
 		}
 
@@ -453,6 +464,8 @@ func (c *VirtualClusterPolicyReconciler) reconcileClusters(ctx context.Context,
 
 		cluster.Spec.PriorityClass = policy.Spec.DefaultPriorityClass
 		cluster.Spec.NodeSelector = policy.Spec.DefaultNodeSelector
+		cluster.Spec.RuntimeClassName = policy.Spec.RuntimeClassName
+		cluster.Spec.NodeTaints = policy.Spec.DefaultNodeTaints
 
 		if !reflect.DeepEqual(orig, cluster) {
 			// continue updating also the other clusters even if an error occurred
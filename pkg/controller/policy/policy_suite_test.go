@@ -54,7 +54,7 @@ var _ = BeforeSuite(func() {
 	ctrl.SetLogger(zapr.NewLogger(zap.NewNop()))
 
 	ctx, cancel = context.WithCancel(context.Background())
-	err = policy.Add(mgr, "", 50)
+	err = policy.Add(mgr, "", "", "", 50)
 	Expect(err).NotTo(HaveOccurred())
 
 	go func() {
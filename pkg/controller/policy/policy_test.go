@@ -341,6 +341,41 @@ var _ = Describe("VirtualClusterPolicy Controller", Label("controller"), Label("
 					Should(BeTrue())
 			})
 
+			It("should update Cluster's RuntimeClassName", func() {
+				policy := newPolicy(v1alpha1.VirtualClusterPolicySpec{
+					RuntimeClassName: ptr.To("gvisor"),
+				})
+
+				bindPolicyToNamespace(namespace, policy)
+
+				cluster := &v1alpha1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{
+						GenerateName: "cluster-",
+						Namespace:    namespace.Name,
+					},
+					Spec: v1alpha1.ClusterSpec{
+						Mode:             v1alpha1.SharedClusterMode,
+						Servers:          ptr.To[int32](1),
+						Agents:           ptr.To[int32](0),
+						RuntimeClassName: ptr.To("runc"),
+					},
+				}
+
+				err := k8sClient.Create(ctx, cluster)
+				Expect(err).To(Not(HaveOccurred()))
+
+				// wait a bit
+				Eventually(func() string {
+					key := types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace}
+					err = k8sClient.Get(ctx, key, cluster)
+					Expect(err).To(Not(HaveOccurred()))
+					return ptr.Deref(cluster.Spec.RuntimeClassName, "")
+				}).
+					WithTimeout(time.Second * 10).
+					WithPolling(time.Second).
+					Should(Equal("gvisor"))
+			})
+
 			It("should update Cluster's NodeSelector", func() {
 				policy := newPolicy(v1alpha1.VirtualClusterPolicySpec{
 					DefaultNodeSelector: map[string]string{"label-1": "value-1"},
@@ -12,10 +12,22 @@ import (
 
 type Logger struct {
 	*zap.SugaredLogger
+
+	level zap.AtomicLevel
 }
 
 func New(debug bool) *Logger {
-	return &Logger{newZappLogger(debug).Sugar()}
+	lvl := zap.NewAtomicLevelAt(zap.InfoLevel)
+	if debug {
+		lvl = zap.NewAtomicLevelAt(zap.DebugLevel)
+	}
+
+	return &Logger{SugaredLogger: newZappLogger(lvl).Sugar(), level: lvl}
+}
+
+// SetLevel changes the level of the logger, and of all the loggers derived from it
+func (l *Logger) SetLevel(level zapcore.Level) {
+	l.level.SetLevel(level)
 }
 
 func (l *Logger) WithError(err error) log.Logger {
@@ -35,16 +47,11 @@ func (l *Logger) Named(name string) *Logger {
 	return l
 }
 
-func newZappLogger(debug bool) *zap.Logger {
+func newZappLogger(lvl zap.AtomicLevel) *zap.Logger {
 	encCfg := zap.NewProductionEncoderConfig()
 	encCfg.TimeKey = "timestamp"
 	encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
 
-	lvl := zap.NewAtomicLevelAt(zap.InfoLevel)
-	if debug {
-		lvl = zap.NewAtomicLevelAt(zap.DebugLevel)
-	}
-
 	encoder := zapcore.NewJSONEncoder(encCfg)
 	core := zapcore.NewCore(&ctrlruntimezap.KubeAwareEncoder{Encoder: encoder}, zapcore.AddSync(os.Stderr), lvl)
 
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/rancher/k3k/pkg/backup"
+)
+
+type snapshotConfig struct {
+	name         string
+	etcdEndpoint string
+	etcdTLSDir   string
+	secretsFile  string
+	retention    int
+	storage      storageConfig
+}
+
+type restoreConfig struct {
+	name         string
+	restoreDir   string
+	regenerateCA bool
+	storage      storageConfig
+}
+
+// storageConfig is the storage of the snapshots, a directory or an S3-compatible bucket
+type storageConfig struct {
+	dir string
+	s3  backup.S3Config
+}
+
+// newSnapshotCmd returns the command saving the etcd snapshots of a virtual cluster, run by the jobs of the ClusterBackups,
+// and fetching the snapshot restored by the servers
+func newSnapshotCmd() *cobra.Command {
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "etcd snapshots of a virtual cluster",
+	}
+
+	var cfg snapshotConfig
+
+	saveCmd := &cobra.Command{
+		Use:   "save",
+		Short: "Save an etcd snapshot of a virtual cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return saveSnapshot(cfg)
+		},
+	}
+
+	saveCmd.Flags().StringVar(&cfg.name, "name", "", "Name of the snapshot")
+	saveCmd.Flags().StringVar(&cfg.etcdEndpoint, "etcd-endpoint", "", "Endpoint of the etcd of the virtual cluster")
+	saveCmd.Flags().StringVar(&cfg.etcdTLSDir, "etcd-tls-dir", "", "Directory with the etcd client certificate (tls.crt and tls.key) and CA (ca.crt)")
+	saveCmd.Flags().StringVar(&cfg.secretsFile, "secrets-file", "", "File with the Secrets of the virtual cluster stored with the snapshot")
+	saveCmd.Flags().IntVar(&cfg.retention, "retention", 5, "Number of snapshots kept in the storage")
+	storageFlags(saveCmd, &cfg.storage)
+
+	var restoreCfg restoreConfig
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Fetch the etcd snapshot restored by a server of a virtual cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fetchSnapshot(restoreCfg)
+		},
+	}
+
+	restoreCmd.Flags().StringVar(&restoreCfg.name, "name", "", "Name of the snapshot")
+	restoreCmd.Flags().StringVar(&restoreCfg.restoreDir, "restore-dir", "", "Directory where the snapshot and the token of the cluster are written")
+	restoreCmd.Flags().BoolVar(&restoreCfg.regenerateCA, "regenerate-ca", false, "Generate new CAs, rotated by the server after the restore")
+	storageFlags(restoreCmd, &restoreCfg.storage)
+
+	snapshotCmd.AddCommand(saveCmd, restoreCmd)
+
+	return snapshotCmd
+}
+
+func storageFlags(cmd *cobra.Command, cfg *storageConfig) {
+	cmd.Flags().StringVar(&cfg.dir, "dir", "", "Directory where the snapshots are stored")
+	cmd.Flags().StringVar(&cfg.s3.Endpoint, "s3-endpoint", "", "S3 endpoint where the snapshots are stored")
+	cmd.Flags().StringVar(&cfg.s3.Bucket, "s3-bucket", "", "S3 bucket")
+	cmd.Flags().StringVar(&cfg.s3.Folder, "s3-folder", "", "S3 folder")
+	cmd.Flags().StringVar(&cfg.s3.Region, "s3-region", "", "S3 region")
+	cmd.Flags().StringVar(&cfg.s3.AccessKeyID, "s3-access-key-id", "", "S3 access key ID")
+	cmd.Flags().StringVar(&cfg.s3.SecretAccessKey, "s3-secret-access-key", "", "S3 secret access key")
+	cmd.Flags().BoolVar(&cfg.s3.Insecure, "s3-insecure", false, "Use HTTP to connect to the S3 endpoint")
+	cmd.Flags().BoolVar(&cfg.s3.SkipSSLVerify, "s3-skip-ssl-verify", false, "Skip the verification of the certificate of the S3 endpoint")
+}
+
+// store returns the store of the snapshots, exactly one of the directory or the S3 endpoint must be set
+func (cfg storageConfig) store(ctx context.Context) (backup.Store, error) {
+	if (cfg.dir == "") == (cfg.s3.Endpoint == "") {
+		return nil, errors.New("exactly one of dir or s3 endpoint is required")
+	}
+
+	if cfg.s3.Endpoint != "" {
+		return backup.NewS3Store(ctx, cfg.s3)
+	}
+
+	return &backup.VolumeStore{Dir: cfg.dir}, nil
+}
+
+func saveSnapshot(cfg snapshotConfig) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.name == "" || cfg.etcdEndpoint == "" {
+		return errors.New("name and etcd endpoint are required")
+	}
+
+	store, err := cfg.storage.store(ctx)
+	if err != nil {
+		return err
+	}
+
+	secrets, err := os.ReadFile(cfg.secretsFile)
+	if err != nil {
+		return fmt.Errorf("unable to read secrets: %w", err)
+	}
+
+	tlsConfig, err := etcdClientTLS(cfg.etcdTLSDir)
+	if err != nil {
+		return err
+	}
+
+	etcdClient, err := clientv3.New(clientv3.Config{
+		Endpoints: []string{cfg.etcdEndpoint},
+		TLS:       tlsConfig,
+	})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = etcdClient.Close()
+	}()
+
+	logger.Infow("saving etcd snapshot", "name", cfg.name)
+
+	if err := backup.Save(ctx, etcdClient, store, cfg.name, secrets); err != nil {
+		return err
+	}
+
+	snapshots, err := backup.Prune(ctx, store, cfg.retention)
+	if err != nil {
+		return err
+	}
+
+	logger.Infow("etcd snapshot saved", "name", cfg.name, "snapshots", snapshots)
+
+	return nil
+}
+
+func fetchSnapshot(cfg restoreConfig) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.name == "" || cfg.restoreDir == "" {
+		return errors.New("name and restore dir are required")
+	}
+
+	store, err := cfg.storage.store(ctx)
+	if err != nil {
+		return err
+	}
+
+	fetched, err := backup.Fetch(ctx, store, cfg.name, cfg.restoreDir)
+	if err != nil {
+		return err
+	}
+
+	if !fetched {
+		logger.Infow("etcd snapshot already fetched", "name", cfg.name)
+		return nil
+	}
+
+	logger.Infow("etcd snapshot fetched", "name", cfg.name, "dir", cfg.restoreDir)
+
+	if cfg.regenerateCA {
+		if err := backup.GenerateCAs(filepath.Join(cfg.restoreDir, backup.RestoreCADir)); err != nil {
+			return fmt.Errorf("unable to generate the CAs of the cluster: %w", err)
+		}
+
+		logger.Infow("CAs generated", "dir", filepath.Join(cfg.restoreDir, backup.RestoreCADir))
+	}
+
+	return nil
+}
+
+// etcdClientTLS returns the TLS config of the etcd client, from the client certificate and CA in the directory
+func etcdClientTLS(dir string) (*tls.Config, error) {
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load etcd client certificate: %w", err)
+	}
+
+	ca, err := os.ReadFile(filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read etcd CA: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("invalid etcd CA")
+	}
+
+	return &tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{clientCert},
+	}, nil
+}
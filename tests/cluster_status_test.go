@@ -94,6 +94,22 @@ var _ = When("a cluster's status is tracked", Label("e2e"), func() {
 				WithTimeout(time.Minute * 3).
 				WithPolling(time.Second * 5).
 				Should(Succeed())
+
+			// the components of the ready cluster are healthy
+			for _, conditionType := range []string{
+				cluster.ConditionDatastoreReady,
+				cluster.ConditionServersReady,
+				cluster.ConditionAgentsReady,
+				cluster.ConditionBootstrapReady,
+				cluster.ConditionKubeconfigReady,
+				cluster.ConditionPolicyCompliant,
+				cluster.ConditionSyncHealthy,
+			} {
+				Expect(meta.IsStatusConditionTrue(clusterObj.Status.Conditions, conditionType)).To(BeTrue(), conditionType)
+			}
+
+			Expect(clusterObj.Status.ReadyServers).To(Equal(int32(1)))
+			Expect(clusterObj.Status.Endpoint).To(HavePrefix("https://"))
 		})
 	})
 
@@ -126,6 +142,11 @@ var _ = When("a cluster's status is tracked", Label("e2e"), func() {
 				g.Expect(cond.Status).To(Equal(metav1.ConditionFalse))
 				g.Expect(cond.Reason).To(Equal(cluster.ReasonValidationFailed))
 				g.Expect(cond.Message).To(ContainSubstring(`mode "virtual" is not allowed by the policy`))
+
+				policyCond := meta.FindStatusCondition(clusterObj.Status.Conditions, cluster.ConditionPolicyCompliant)
+				g.Expect(policyCond).NotTo(BeNil())
+				g.Expect(policyCond.Status).To(Equal(metav1.ConditionFalse))
+				g.Expect(policyCond.Reason).To(Equal(cluster.ReasonPolicyViolation))
 			}).
 				WithPolling(time.Second * 2).
 				WithTimeout(time.Second * 20).
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const wakeUpRetryInterval = 5 * time.Second
+
+type wakeProxyConfig struct {
+	url   string
+	port  int
+	token string
+	ca    string
+}
+
+// newWakeProxyCmd returns the command replacing the servers of a sleeping virtual cluster behind its Service.
+// Every connection is closed, and wakes the cluster up through the activity tracker of the controller.
+func newWakeProxyCmd() *cobra.Command {
+	var cfg wakeProxyConfig
+
+	cmd := &cobra.Command{
+		Use:   "wake-proxy",
+		Short: "Wake a sleeping virtual cluster up on the first connection to its API server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg.token = os.Getenv("TOKEN")
+			cfg.ca = os.Getenv("CA_CERT")
+
+			return runWakeProxy(cfg)
+		},
+	}
+
+	cmd.Flags().StringVar(&cfg.url, "url", "", "Wake up endpoint of the activity tracker of the cluster")
+	cmd.Flags().IntVar(&cfg.port, "port", 6443, "Port of the API server of the cluster")
+
+	return cmd
+}
+
+func runWakeProxy(cfg wakeProxyConfig) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.url == "" || cfg.token == "" || cfg.ca == "" {
+		return errors.New("url, token and CA certificate are required")
+	}
+
+	// the activity tracker is only trusted with the CA of the controller
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(cfg.ca)) {
+		return errors.New("invalid CA certificate")
+	}
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12},
+		},
+	}
+
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(cfg.port))
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		_ = listener.Close()
+	}()
+
+	connections := make(chan struct{}, 1)
+
+	go wakeUp(ctx, client, cfg, connections)
+
+	logger.Infow("waiting for connections", "port", cfg.port)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		_ = conn.Close()
+
+		// the cluster is already being woken up if a request is pending
+		select {
+		case connections <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// wakeUp sends a wake up request for each connection, retrying until it succeeds
+func wakeUp(ctx context.Context, client *http.Client, cfg wakeProxyConfig, connections <-chan struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-connections:
+		}
+
+		for {
+			err := sendWakeUp(ctx, client, cfg)
+			if err == nil {
+				break
+			}
+
+			logger.Errorw("failed to wake the cluster up", zap.Error(err))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wakeUpRetryInterval):
+			}
+		}
+	}
+}
+
+func sendWakeUp(ctx context.Context, client *http.Client, cfg wakeProxyConfig) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+cfg.token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	logger.Info("cluster woken up")
+
+	return nil
+}